@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+func (c *client) ListAccounts(ctx context.Context) ([]Account, error) {
+	return paginate[Account](ctx, c, "accounts", nil)
+}
+
+func (c *client) GetAccount(ctx context.Context, accountId int) (*Account, error) {
+	var account Account
+	if err := c.call(ctx, http.MethodGet, apiPath("accounts", accountId), nil, nil, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (c *client) ListAccountMembers(ctx context.Context, accountId int) ([]AccountMember, error) {
+	return paginate[AccountMember](ctx, c, apiPath("accounts", accountId, "members"), nil)
+}
+
+func (c *client) AccountEmailPreferences(ctx context.Context, accountId int) (UserPreference, error) {
+	return c.notificationPreferences(ctx, accountId, "accounts", emailPreferences)
+}
+
+func (c *client) AccountInAppPreferences(ctx context.Context, accountId int) (UserPreference, error) {
+	return c.notificationPreferences(ctx, accountId, "accounts", inAppPreferences)
+}
+
+// UpdateAccountEmailNotifications replaces the account-wide email defaults applied to all of its projects
+func (c *client) UpdateAccountEmailNotifications(ctx context.Context, accountId, prefId int, body io.Reader) error {
+	_, err := c.updateNotifications(ctx, accountId, "accounts", prefId, emailPreferences, body)
+	return err
+}
+
+func (c *client) UpdateAccountInAppNotifications(ctx context.Context, accountId, prefId int, body io.Reader) error {
+	_, err := c.updateNotifications(ctx, accountId, "accounts", prefId, inAppPreferences, body)
+	return err
+}
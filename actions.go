@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// actions emits github actions workflow annotations for findings and writes the rows of
+// a run as a job summary, a nil actions does nothing
+type actions struct {
+	out         io.Writer
+	summaryPath string
+	rows        report
+}
+
+// newActions returns actions when running in a github actions workflow
+func newActions(out io.Writer) *actions {
+	if os.Getenv("GITHUB_ACTIONS") != "true" {
+		return nil
+	}
+	return &actions{out: out, summaryPath: os.Getenv("GITHUB_STEP_SUMMARY")}
+}
+
+// escapeData escapes workflow command messages
+var escapeData = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+
+// escapeProperty escapes workflow command properties like title
+var escapeProperty = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+
+func (a *actions) annotate(level, title, message string) {
+	fmt.Fprintf(a.out, "::%s title=%s::%s\n", level, escapeProperty.Replace(title), escapeData.Replace(message))
+}
+
+// observe collects row for the job summary, warning about objects with every notification enabled
+func (a *actions) observe(row reportRow) {
+	if a == nil {
+		return
+	}
+	a.rows.add(row)
+	total := booleans(row.Email, row.InApp)
+	if n := row.EmailEnabled + row.InAppEnabled; total > 0 && n >= total {
+		name, object := row.Project, "project"
+		if row.Workspace != "" {
+			name, object = row.Project+"/"+row.Workspace, "workspace"
+		}
+		a.annotate("warning", "Noisy "+object, fmt.Sprintf("%s %s has all %d notifications enabled", object, name, n))
+	}
+}
+
+// finish annotates failures and changes and writes the job summary
+func (a *actions) finish(sum summaryReport, failures []error) error {
+	if a == nil {
+		return nil
+	}
+	for _, err := range failures {
+		a.annotate("error", "Failed", err.Error())
+	}
+	if sum.touched() {
+		a.annotate("notice", "Preferences changed", fmt.Sprintf("changed preferences of %d projects and %d workspaces", sum.Projects, sum.Workspaces))
+	}
+	if a.summaryPath == "" {
+		return nil
+	}
+	f, err := os.OpenFile(a.summaryPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("while writing job summary: %w", err)
+	}
+	defer f.Close()
+	a.writeSummary(f, sum, len(failures))
+	return nil
+}
+
+// cell escapes text for a markdown table cell
+var cell = strings.NewReplacer("|", "\\|", "\n", " ")
+
+func (a *actions) writeSummary(w io.Writer, sum summaryReport, failures int) {
+	fmt.Fprintf(w, "## Zube notification preferences\n\n")
+	if rows := a.rows.sorted(); len(rows) > 0 {
+		fmt.Fprintf(w, "| Project | Workspace | Subscription | Email frequency | Email enabled | In-app enabled |\n")
+		fmt.Fprintf(w, "|---|---|---|---|--:|--:|\n")
+		for _, row := range rows {
+			fmt.Fprintf(w, "| %s | %s | %s | %s | %d | %d |\n", cell.Replace(row.Project), cell.Replace(row.Workspace),
+				row.SubscriptionLevel, cell.Replace(row.EmailFrequency), row.EmailEnabled, row.InAppEnabled)
+		}
+		fmt.Fprintln(w)
+	}
+	if sum.touched() {
+		fmt.Fprintf(w, "Changed preferences of %d projects and %d workspaces.\n\n", sum.Projects, sum.Workspaces)
+	}
+	fmt.Fprintf(w, "%d api calls in %s, %d failures.\n", sum.APICalls, sum.Elapsed, failures)
+}
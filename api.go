@@ -59,6 +59,8 @@ type Workspace struct {
 	UpdatedAt         time.Time `json:"updated_at"`
 	ArchiveMergedPrs  bool      `json:"archive_merged_prs"`
 	UseCategoryLabels bool      `json:"use_category_labels"`
+	Archived          bool      `json:"archived"`
+	ArchivedAt        time.Time `json:"archived_at"`
 }
 
 type UserSetting struct {
@@ -70,3 +72,142 @@ type UserSetting struct {
 }
 
 type UserPreference map[string]interface{}
+
+type Card struct {
+	ID             int        `json:"id"`
+	AccountID      int        `json:"account_id"`
+	ProjectID      int        `json:"project_id"`
+	WorkspaceID    int        `json:"workspace_id"`
+	SprintID       int        `json:"sprint_id"`
+	EpicID         int        `json:"epic_id"`
+	Number         int        `json:"number"`
+	Title          string     `json:"title"`
+	Body           string     `json:"body"`
+	State          string     `json:"state"`
+	Status         string     `json:"status"`
+	CategoryName   string     `json:"category_name"`
+	Priority       int        `json:"priority"`
+	Points         float64    `json:"points"`
+	Rank           string     `json:"rank"`
+	AssigneeIDs    []int      `json:"assignee_ids"`
+	LabelIDs       []int      `json:"label_ids"`
+	MilestoneIDs   []int      `json:"milestone_ids"`
+	CommentsCount  int        `json:"comments_count"`
+	UpvotesCount   int        `json:"upvotes_count"`
+	CreatorID      int        `json:"creator_id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	ClosedAt       *time.Time `json:"closed_at"`
+	LastMovedAt    *time.Time `json:"last_moved_at"`
+	GithubIssueURL string     `json:"github_issue_url"`
+}
+
+type Comment struct {
+	ID        int       `json:"id"`
+	CardID    int       `json:"card_id"`
+	CreatorID int       `json:"creator_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Label struct {
+	ID          int       `json:"id"`
+	ProjectID   int       `json:"project_id"`
+	Name        string    `json:"name"`
+	Color       string    `json:"color"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type Epic struct {
+	ID          int        `json:"id"`
+	ProjectID   int        `json:"project_id"`
+	Number      int        `json:"number"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	State       string     `json:"state"`
+	Status      string     `json:"status"`
+	Color       string     `json:"color"`
+	CardsCount  int        `json:"cards_count"`
+	DueOn       *time.Time `json:"due_on"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type Sprint struct {
+	ID          int        `json:"id"`
+	WorkspaceID int        `json:"workspace_id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	State       string     `json:"state"`
+	StartDate   *time.Time `json:"start_date"`
+	EndDate     *time.Time `json:"end_date"`
+	ClosedAt    *time.Time `json:"closed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type Milestone struct {
+	ID          int        `json:"id"`
+	ProjectID   int        `json:"project_id"`
+	SourceID    int        `json:"source_id"`
+	Number      int        `json:"number"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	State       string     `json:"state"`
+	DueOn       *time.Time `json:"due_on"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type Account struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Slug        string    `json:"slug"`
+	Description string    `json:"description"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type User struct {
+	ID           int       `json:"id"`
+	Username     string    `json:"username"`
+	Name         string    `json:"name"`
+	Email        string    `json:"email"`
+	GithubUserID int       `json:"github_user_id"`
+	AvatarPath   string    `json:"avatar_path"`
+	TimeZone     string    `json:"time_zone"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	// Preferences holds the user's default notification settings, only present for the current user
+	Preferences UserPreference `json:"preferences,omitempty"`
+}
+
+type AccountMember struct {
+	ID        int       `json:"id"`
+	AccountID int       `json:"account_id"`
+	UserID    int       `json:"user_id"`
+	Role      string    `json:"role"`
+	User      User      `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Notification struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`
+	AccountID   int       `json:"account_id"`
+	ProjectID   int       `json:"project_id"`
+	WorkspaceID int       `json:"workspace_id"`
+	CardID      int       `json:"card_id"`
+	ActorID     int       `json:"actor_id"`
+	Event       string    `json:"event"`
+	Title       string    `json:"title"`
+	Body        string    `json:"body"`
+	URL         string    `json:"url"`
+	Read        bool      `json:"read"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
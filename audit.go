@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// auditEntry records one preference mutation sent to the api
+type auditEntry struct {
+	Time time.Time `json:"time"`
+	// Run identifies the invocation that made the change
+	Run      string             `json:"run"`
+	ClientID string             `json:"client_id"`
+	Method   string             `json:"method"`
+	Object   string             `json:"object"`
+	ObjectID int                `json:"object_id"`
+	PrefType string             `json:"pref_type"`
+	Changes  []preferenceChange `json:"changes,omitempty"`
+	Status   int                `json:"status"`
+	Error    string             `json:"error,omitempty"`
+}
+
+// auditLog appends a json line per mutation to a file
+type auditLog struct {
+	mu   sync.Mutex
+	path string
+	run  string
+	f    *os.File
+}
+
+func openAuditLog(path string) (*auditLog, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("while opening audit log: %w", err)
+	}
+	return &auditLog{path: path, run: time.Now().UTC().Format(time.RFC3339Nano), f: f}, nil
+}
+
+// record writes e, a may be nil
+func (a *auditLog) record(e auditEntry) error {
+	if a == nil {
+		return nil
+	}
+	e.Run = a.run
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("while writing audit log: %w", err)
+	}
+	return nil
+}
+
+func (a *auditLog) Close() error {
+	if a == nil {
+		return nil
+	}
+	return a.f.Close()
+}
+
+// recordAudit logs a mutation of the object identified by objectId, joining a failure to log with err
+func (s *sweep) recordAudit(method, object string, objectId int, prefType string, changes []preferenceChange, status int, err error) error {
+	e := auditEntry{
+		Time:     time.Now().UTC(),
+		ClientID: s.client.clientId,
+		Method:   method,
+		Object:   object,
+		ObjectID: objectId,
+		PrefType: prefType,
+		Changes:  changes,
+		Status:   status,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	} else {
+		s.summary.record(object, objectId, prefType, changes)
+	}
+	return errors.Join(err, s.auditLog.record(e))
+}
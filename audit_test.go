@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/graphaelli/zube-notifications/zubetest"
+)
+
+// readAudit returns the entries of the audit log at path
+func readAudit(t *testing.T, path string) []auditEntry {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var entries []auditEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e auditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestAuditRecordsChanges(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	p := srv.AddProject("proj-a")
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	s := newTestSweep(srv)
+	var err error
+	if s.auditLog, err = openAuditLog(path); err != nil {
+		t.Fatal(err)
+	}
+	defer s.auditLog.Close()
+	current, err := s.client.notificationPreferences(ctx, p.ID, "projects", emailPreferences)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.update(ctx, "projects", p.ID, emailPreferences, current, copyPreferences(current)); err != nil {
+		t.Fatal(err)
+	}
+	if entries := readAudit(t, path); len(entries) > 0 {
+		t.Fatalf("audited a write without changes: %+v", entries)
+	}
+
+	current, err = s.client.notificationPreferences(ctx, p.ID, "projects", emailPreferences)
+	if err != nil {
+		t.Fatal(err)
+	}
+	desired := copyPreferences(current)
+	desired["card_moved"] = false
+	if err := s.update(ctx, "projects", p.ID, emailPreferences, current, desired); err != nil {
+		t.Fatal(err)
+	}
+	entries := readAudit(t, path)
+	if len(entries) != 1 {
+		t.Fatalf("%d audit entries, want 1", len(entries))
+	}
+	if e := entries[0]; e.ObjectID != p.ID || e.PrefType != emailPreferences || len(e.Changes) != 1 ||
+		e.Changes[0].Key != "card_moved" || e.Changes[0].Before != true || e.Changes[0].After != false {
+		t.Errorf("audit entry %+v", e)
+	}
+}
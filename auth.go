@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// maxClockSkew is how far the local clock may drift from zube's before refresh tokens are at risk
+const maxClockSkew = 30 * time.Second
+
+var authCommand = &command{
+	name:        "auth",
+	usage:       "auth doctor - check each step of authentication, reporting which one fails and why",
+	keyOptional: true,
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		if len(args) != 1 || args[0] != "doctor" {
+			return errors.New("auth requires doctor")
+		}
+		return s.authDoctor(ctx, os.Stdout)
+	},
+}
+
+// authDoctor walks through loading the key, the clock, signing a refresh token, exchanging it
+// for an access token and using that, stopping at the first step that fails
+func (s *sweep) authDoctor(ctx context.Context, w io.Writer) error {
+	c := s.client
+	step := func(name, status, format string, args ...interface{}) {
+		fmt.Fprintf(w, "%-9s%-6s%s\n", name, status, fmt.Sprintf(format, args...))
+	}
+	fail := func(name string, err error, hints ...string) error {
+		step(name, "FAIL", "%v", err)
+		for _, h := range hints {
+			fmt.Fprintf(w, "%15s%s\n", "", h)
+		}
+		return fmt.Errorf("auth doctor: %s failed: %w", name, err)
+	}
+
+	step("client", "ok", "%s, api %s", c.clientId, c.apiBaseUrl)
+	if s.keyErr != nil {
+		return fail("key", s.keyErr,
+			"the private key must be the pem zube generated for this client id,",
+			"rsa, ecdsa or ed25519, set ZUBE_KEY_PASSPHRASE for encrypted keys")
+	}
+	signer := c.signers[0]
+	step("key", "ok", "%v signing %s", signer, signer.Algorithm())
+
+	skew, err := s.clockSkew(ctx)
+	switch {
+	case err != nil:
+		step("clock", "warn", "couldn't read zube's time: %v", err)
+	case skew > maxClockSkew || skew < -maxClockSkew:
+		direction := "behind"
+		if skew > 0 {
+			direction = "ahead of"
+		}
+		step("clock", "warn", "local clock is %s %s zube's, refresh tokens may be rejected as not yet valid or expired",
+			skew.Abs().Round(time.Second), direction)
+	default:
+		step("clock", "ok", "%s skew", skew.Round(time.Second))
+	}
+
+	now := time.Now()
+	refreshToken, err := c.refreshToken(ctx, signer, now, now.Add(c.accessDuration))
+	if err != nil {
+		return fail("sign", err)
+	}
+	step("sign", "ok", "refresh token issued by %s valid for %s", c.clientId, c.accessDuration)
+
+	req, err := c.newRequest(ctx, http.MethodPost, "users/tokens", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+refreshToken)
+	rsp, err := c.doRequest(req)
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+			return fail("exchange", err,
+				"zube rejected the refresh token: check the client id matches the key,",
+				"that the key wasn't revoked and the clock above")
+		}
+		return fail("exchange", err)
+	}
+	defer closeBody(rsp)
+	var exchanged struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := decodeResponse(rsp, "access token", &exchanged); err != nil {
+		return fail("exchange", err)
+	}
+	if exchanged.AccessToken == "" {
+		return fail("exchange", errors.New("zube returned no access token"))
+	}
+	step("exchange", "ok", "access token received")
+
+	req, err = c.newRequest(ctx, http.MethodGet, apiPath("users", "me"), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+exchanged.AccessToken)
+	rsp, err = c.doRequest(req)
+	if err != nil {
+		return fail("api", err, "the access token was issued but isn't accepted, the client may lack api access")
+	}
+	defer closeBody(rsp)
+	var user User
+	if err := decodeResponse(rsp, "current user", &user); err != nil {
+		return fail("api", err)
+	}
+	step("api", "ok", "authenticated as %s (%s)", user.Name, user.Username)
+	return nil
+}
+
+// clockSkew compares the local clock to the Date header of an unauthenticated api response,
+// positive when the local clock is ahead
+func (s *sweep) clockSkew(ctx context.Context) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.client.apiBaseUrl, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", userAgent())
+	start := time.Now()
+	rsp, err := s.client.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	closeBody(rsp)
+	date, err := http.ParseTime(rsp.Header.Get("Date"))
+	if err != nil {
+		return 0, fmt.Errorf("no usable Date header: %w", err)
+	}
+	// the server's time is best matched to the middle of the round trip
+	local := start.Add(time.Since(start) / 2)
+	return local.Sub(date), nil
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/term"
+)
+
+// cardOp changes a single card, describing the change for output
+type cardOp func(ctx context.Context, card Card) (string, error)
+
+// selectCards returns the cards named by id in args, read from stdin, or matching the query flags
+func (s *sweep) selectCards(ctx context.Context, fs *flag.FlagSet, query func(context.Context) (cardQuery, error), stdin io.Reader) ([]Card, error) {
+	filtered := false
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "project", "workspace", "state", "status", "search", "since", "assignee":
+			filtered = true
+		}
+	})
+	if filtered {
+		if fs.NArg() > 0 {
+			return nil, errors.New("card ids and filters cannot be combined")
+		}
+		q, err := query(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return s.cards(ctx, q)
+	}
+	ids := fs.Args()
+	if len(ids) == 0 {
+		if f, ok := stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+			return nil, errors.New("requires card ids as arguments, on stdin, or filters")
+		}
+		scanner := bufio.NewScanner(stdin)
+		scanner.Split(bufio.ScanWords)
+		for scanner.Scan() {
+			ids = append(ids, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("while reading card ids: %w", err)
+		}
+	}
+	cards := make([]Card, 0, len(ids))
+	for _, v := range ids {
+		id, err := strconv.Atoi(strings.TrimPrefix(v, "#"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid card id %q", v)
+		}
+		// the rest is fetched by the worker when an op needs it
+		cards = append(cards, Card{ID: id})
+	}
+	return cards, nil
+}
+
+// bulk applies op to every card with at most s.concurrency in flight, collecting per-card errors
+func (s *sweep) bulk(ctx context.Context, cards []Card, verb string, op cardOp) error {
+	var (
+		mu   sync.Mutex
+		errs []error
+		done int
+	)
+	var g errgroup.Group
+	g.SetLimit(s.concurrency)
+	for _, c := range cards {
+		if ctx.Err() != nil {
+			break
+		}
+		card := c
+		g.Go(func() error {
+			if card.ProjectID == 0 {
+				fetched, err := s.client.GetCard(ctx, card.ID)
+				if err != nil {
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("card %d: %w", card.ID, err))
+					mu.Unlock()
+					return nil
+				}
+				card = *fetched
+			}
+			change, err := op(ctx, card)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("card #%d (%d): %w", card.Number, card.ID, err))
+				return nil
+			}
+			done++
+			s.outcome.succeed()
+			if s.dryRun {
+				s.printf("would %s card #%d (%d) %s\n", change, card.Number, card.ID, card.Title)
+			} else {
+				s.printf("%s card #%d (%d) %s\n", change, card.Number, card.ID, card.Title)
+			}
+			return nil
+		})
+	}
+	g.Wait()
+	if ctx.Err() != nil {
+		errs = append(errs, ctx.Err())
+	}
+	if s.dryRun {
+		s.printf("dry run, would have %s %d of %d cards\n", verb, done, len(cards))
+	} else {
+		s.printf("%s %d of %d cards\n", verb, done, len(cards))
+	}
+	return errors.Join(errs...)
+}
+
+func (s *sweep) bulkCards(ctx context.Context, name string, args []string) error {
+	fs := flag.NewFlagSet("cards "+name, flag.ExitOnError)
+	query := s.cardQueryFlags(fs)
+	var (
+		op    cardOp
+		verb  string
+		check func() error
+	)
+	switch name {
+	case "move":
+		column := fs.String("to-column", "", "name of the board column to move cards to")
+		check = func() error {
+			if *column == "" {
+				return errors.New("cards move requires -to-column")
+			}
+			return nil
+		}
+		op = func(ctx context.Context, card Card) (string, error) {
+			if !s.dryRun {
+				if _, err := s.client.MoveCard(ctx, card.ID, CardDestination{Type: "category", Name: *column, WorkspaceID: card.WorkspaceID}); err != nil {
+					return "", err
+				}
+			}
+			return "move to " + *column, nil
+		}
+		verb = "moved"
+	case "label":
+		var add, remove listFlags
+		fs.Var(&add, "add", "label name to add, may be repeated")
+		fs.Var(&remove, "remove", "label name to remove, may be repeated")
+		labels := &projectLabels{s: s, ids: make(map[int]map[string]int)}
+		check = func() error {
+			if len(add)+len(remove) == 0 {
+				return errors.New("cards label requires -add or -remove")
+			}
+			return nil
+		}
+		op = func(ctx context.Context, card Card) (string, error) {
+			ids, err := labels.resolve(ctx, card.ProjectID, add, remove)
+			if err != nil {
+				return "", err
+			}
+			set := make(map[int]bool)
+			for _, id := range card.LabelIDs {
+				set[id] = true
+			}
+			for _, id := range ids.add {
+				set[id] = true
+			}
+			for _, id := range ids.remove {
+				delete(set, id)
+			}
+			labelIds := make([]int, 0, len(set))
+			for id := range set {
+				labelIds = append(labelIds, id)
+			}
+			if !s.dryRun {
+				if _, err := s.client.SetCardLabels(ctx, card.ID, labelIds); err != nil {
+					return "", err
+				}
+			}
+			var change []string
+			if len(add) > 0 {
+				change = append(change, "add labels "+add.String())
+			}
+			if len(remove) > 0 {
+				change = append(change, "remove labels "+remove.String())
+			}
+			return strings.Join(change, " and ") + " on", nil
+		}
+		verb = "labeled"
+	case "archive":
+		op = func(ctx context.Context, card Card) (string, error) {
+			if !s.dryRun {
+				if _, err := s.client.MoveCard(ctx, card.ID, CardDestination{Type: "archive"}); err != nil {
+					return "", err
+				}
+			}
+			return "archive", nil
+		}
+		verb = "archived"
+	}
+	fs.Parse(args)
+	if check != nil {
+		if err := check(); err != nil {
+			return err
+		}
+	}
+	cards, err := s.selectCards(ctx, fs, query, os.Stdin)
+	if err != nil {
+		return err
+	}
+	return s.bulk(ctx, cards, verb, op)
+}
+
+// projectLabels caches label name to id lookups per project for concurrent ops
+type projectLabels struct {
+	s   *sweep
+	mu  sync.Mutex
+	ids map[int]map[string]int
+}
+
+type labelChange struct {
+	add, remove []int
+}
+
+func (l *projectLabels) resolve(ctx context.Context, projectId int, add, remove []string) (labelChange, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	byName, ok := l.ids[projectId]
+	if !ok {
+		labels, err := l.s.client.ListLabels(ctx, projectId)
+		if err != nil {
+			return labelChange{}, err
+		}
+		byName = make(map[string]int, len(labels))
+		for _, label := range labels {
+			byName[strings.ToLower(label.Name)] = label.ID
+		}
+		l.ids[projectId] = byName
+	}
+	lookup := func(names []string) ([]int, error) {
+		ids := make([]int, 0, len(names))
+		for _, name := range names {
+			id, ok := byName[strings.ToLower(name)]
+			if !ok {
+				return nil, fmt.Errorf("no label %q in project %d", name, projectId)
+			}
+			ids = append(ids, id)
+		}
+		return ids, nil
+	}
+	var (
+		c   labelChange
+		err error
+	)
+	if c.add, err = lookup(add); err != nil {
+		return c, err
+	}
+	c.remove, err = lookup(remove)
+	return c, err
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cacheEntry is a cached GET response
+type cacheEntry struct {
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+	// Expires is when the entry must be revalidated, from Cache-Control max-age
+	Expires time.Time `json:"expires"`
+}
+
+// httpCache stores GET responses for revalidation with If-None-Match and If-Modified-Since
+type httpCache interface {
+	get(key string) (*cacheEntry, bool)
+	set(key string, entry *cacheEntry)
+	delete(key string)
+}
+
+// CacheOption caches GET responses in cache, see newMemoryCache and newDiskCache
+func CacheOption(cache httpCache) option {
+	return func(c *client) {
+		c.cache = cache
+	}
+}
+
+type memoryCache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{entries: make(map[string]*cacheEntry)}
+}
+
+func (m *memoryCache) get(key string) (*cacheEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	return e, ok
+}
+
+func (m *memoryCache) set(key string, entry *cacheEntry) {
+	m.mu.Lock()
+	m.entries[key] = entry
+	m.mu.Unlock()
+}
+
+func (m *memoryCache) delete(key string) {
+	m.mu.Lock()
+	delete(m.entries, key)
+	m.mu.Unlock()
+}
+
+// diskCache stores one file per response in dir, surviving between runs
+type diskCache struct {
+	dir string
+}
+
+func newDiskCache(dir string) (*diskCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &diskCache{dir: dir}, nil
+}
+
+func (d *diskCache) file(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (d *diskCache) get(key string) (*cacheEntry, bool) {
+	b, err := os.ReadFile(d.file(key))
+	if err != nil {
+		return nil, false
+	}
+	var e cacheEntry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, false
+	}
+	return &e, true
+}
+
+func (d *diskCache) set(key string, entry *cacheEntry) {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	// write atomically so concurrent runs never read partial entries
+	tmp, err := os.CreateTemp(d.dir, ".entry-*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		return
+	}
+	os.Rename(tmp.Name(), d.file(key))
+}
+
+func (d *diskCache) delete(key string) {
+	os.Remove(d.file(key))
+}
+
+// defaultHTTPCacheDir is where -cache disk stores responses
+func defaultHTTPCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "zube", "http")
+}
+
+// maxAge returns the Cache-Control max-age of a response
+func maxAge(header http.Header) time.Duration {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.TrimSpace(directive)
+		if directive == "no-cache" || directive == "no-store" {
+			return 0
+		}
+		if v, ok := strings.CutPrefix(directive, "max-age="); ok {
+			if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+				return time.Duration(secs) * time.Second
+			}
+		}
+	}
+	return 0
+}
+
+// cacheMiddleware answers GETs from cache while fresh, revalidates stale entries and
+// drops entries of objects changed by other methods. Keys include clientId so accounts
+// sharing a cache never see each other's responses.
+func cacheMiddleware(cache httpCache, clientId string) Middleware {
+	key := func(method string, u string) string {
+		return clientId + " " + method + " " + u
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Method != http.MethodGet {
+				rsp, err := next.RoundTrip(req)
+				if err == nil && rsp.StatusCode/100 == 2 {
+					// writes like PUT .../user_email_preferences/2 change GET .../user_email_preferences
+					u := *req.URL
+					u.RawQuery = ""
+					cache.delete(key(http.MethodGet, u.String()))
+					u.Path = path.Dir(u.Path)
+					cache.delete(key(http.MethodGet, u.String()))
+				}
+				return rsp, err
+			}
+
+			k := key(req.Method, req.URL.String())
+			entry, cached := cache.get(k)
+			if cached && time.Now().Before(entry.Expires) {
+				return cachedResponse(req, entry), nil
+			}
+			if cached {
+				req = req.Clone(req.Context())
+				if etag := entry.Header.Get("ETag"); etag != "" {
+					req.Header.Set("If-None-Match", etag)
+				}
+				if lm := entry.Header.Get("Last-Modified"); lm != "" {
+					req.Header.Set("If-Modified-Since", lm)
+				}
+			}
+			rsp, err := next.RoundTrip(req)
+			if err != nil {
+				return rsp, err
+			}
+			if cached && rsp.StatusCode == http.StatusNotModified {
+				closeBody(rsp)
+				entry.Expires = time.Now().Add(maxAge(rsp.Header))
+				cache.set(k, entry)
+				return cachedResponse(req, entry), nil
+			}
+			if rsp.StatusCode != http.StatusOK || (rsp.Header.Get("ETag") == "" && rsp.Header.Get("Last-Modified") == "" && maxAge(rsp.Header) == 0) {
+				return rsp, nil
+			}
+			body, err := io.ReadAll(rsp.Body)
+			rsp.Body.Close()
+			rsp.Body = io.NopCloser(bytes.NewReader(body))
+			if err != nil {
+				return rsp, err
+			}
+			cache.set(k, &cacheEntry{Header: rsp.Header.Clone(), Body: body, Expires: time.Now().Add(maxAge(rsp.Header))})
+			return rsp, nil
+		})
+	}
+}
+
+func cachedResponse(req *http.Request, entry *cacheEntry) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        entry.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       req,
+	}
+}
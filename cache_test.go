@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/graphaelli/zube-notifications/zubetest"
+)
+
+// recordStatus records the status of responses to requests whose path ends with suffix
+func recordStatus(suffix string, statuses *[]int) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			rsp, err := next.RoundTrip(req)
+			if err == nil && strings.HasSuffix(req.URL.Path, suffix) {
+				*statuses = append(*statuses, rsp.StatusCode)
+			}
+			return rsp, err
+		})
+	}
+}
+
+func TestCacheRevalidates(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	srv.AddProject("proj-a")
+	ctx := context.Background()
+	var statuses []int
+	c := newTestClient(srv, CacheOption(newMemoryCache()), MiddlewareOption(recordStatus("/projects", &statuses)))
+	listProjects := func(want int) {
+		t.Helper()
+		projects, err := c.ListProjects(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(projects) != want {
+			t.Errorf("listed %d projects, want %d", len(projects), want)
+		}
+	}
+
+	listProjects(1)
+	listProjects(1)
+	srv.AddProject("proj-b")
+	listProjects(2)
+	if want := []int{200, 304, 200}; !slices.Equal(statuses, want) {
+		t.Errorf("responses %v, want %v", statuses, want)
+	}
+}
+
+func TestDiskCacheSurvivesRuns(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	srv.AddProject("proj-a")
+	dir := t.TempDir()
+	var statuses []int
+	for i := 0; i < 2; i++ {
+		cache, err := newDiskCache(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := newTestClient(srv, CacheOption(cache), MiddlewareOption(recordStatus("/projects", &statuses)))
+		if projects, err := c.ListProjects(context.Background()); err != nil || len(projects) != 1 {
+			t.Fatalf("listed %v: %v", projects, err)
+		}
+	}
+	if want := []int{200, 304}; !slices.Equal(statuses, want) {
+		t.Errorf("responses %v, want %v", statuses, want)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// CardFilter narrows ListCards, zero values are ignored
+type CardFilter struct {
+	ProjectID   int
+	WorkspaceID int
+	SprintID    int
+	EpicID      int
+	State       string
+	// Status is the card's board location, e.g. "triage", "backlog" or "in_progress"
+	Status string
+	Search string
+	// RecentlyUpdated orders cards most recently updated first, for callers that stop at a point in time
+	RecentlyUpdated bool
+}
+
+func (f CardFilter) values() url.Values {
+	q := url.Values{}
+	for k, v := range map[string]int{
+		"where[project_id]":   f.ProjectID,
+		"where[workspace_id]": f.WorkspaceID,
+		"where[sprint_id]":    f.SprintID,
+		"where[epic_id]":      f.EpicID,
+	} {
+		if v != 0 {
+			q.Set(k, strconv.Itoa(v))
+		}
+	}
+	if f.State != "" {
+		q.Set("where[state]", f.State)
+	}
+	if f.Status != "" {
+		q.Set("where[status]", f.Status)
+	}
+	if f.Search != "" {
+		q.Set("search", f.Search)
+	}
+	if f.RecentlyUpdated {
+		q.Set("order[by]", "updated_at")
+		q.Set("order[direction]", "desc")
+	}
+	return q
+}
+
+// CardParams are the writable card fields sent by CreateCard and UpdateCard
+type CardParams struct {
+	ProjectID    int      `json:"project_id,omitempty"`
+	WorkspaceID  int      `json:"workspace_id,omitempty"`
+	SprintID     int      `json:"sprint_id,omitempty"`
+	EpicID       int      `json:"epic_id,omitempty"`
+	Title        string   `json:"title,omitempty"`
+	Body         string   `json:"body,omitempty"`
+	State        string   `json:"state,omitempty"`
+	Priority     int      `json:"priority,omitempty"`
+	Points       *float64 `json:"points,omitempty"`
+	AssigneeIDs  []int    `json:"assignee_ids,omitempty"`
+	LabelIDs     []int    `json:"label_ids,omitempty"`
+	MilestoneIDs []int    `json:"milestone_ids,omitempty"`
+}
+
+// CardDestination is where MoveCard places a card
+type CardDestination struct {
+	// Type is the kind of destination, e.g. "category", "triage" or "archive"
+	Type        string `json:"type"`
+	Name        string `json:"name,omitempty"`
+	Position    int    `json:"position"`
+	WorkspaceID int    `json:"workspace_id,omitempty"`
+}
+
+func (c *client) ListCards(ctx context.Context, filter CardFilter) ([]Card, error) {
+	return paginate[Card](ctx, c, "cards", filter.values())
+}
+
+// CardsIter iterates over the cards matching filter, a page at a time
+func (c *client) CardsIter(ctx context.Context, filter CardFilter) *Iterator[Card] {
+	return iterate[Card](ctx, c, "cards", filter.values())
+}
+
+func (c *client) GetCard(ctx context.Context, cardId int) (*Card, error) {
+	var card Card
+	if err := c.call(ctx, http.MethodGet, apiPath("cards", cardId), nil, nil, &card); err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+func (c *client) CreateCard(ctx context.Context, params CardParams) (*Card, error) {
+	var card Card
+	if err := c.call(ctx, http.MethodPost, "cards", nil, params, &card); err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+func (c *client) UpdateCard(ctx context.Context, cardId int, params CardParams) (*Card, error) {
+	var card Card
+	if err := c.call(ctx, http.MethodPut, apiPath("cards", cardId), nil, params, &card); err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+// SetCardAssignees replaces a card's assignees, unlike UpdateCard an empty list unassigns everyone
+func (c *client) SetCardAssignees(ctx context.Context, cardId int, assigneeIds []int) (*Card, error) {
+	var card Card
+	body := struct {
+		AssigneeIDs []int `json:"assignee_ids"`
+	}{assigneeIds}
+	if err := c.call(ctx, http.MethodPut, apiPath("cards", cardId), nil, body, &card); err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+// SetCardLabels replaces a card's labels, unlike UpdateCard an empty list removes every label
+func (c *client) SetCardLabels(ctx context.Context, cardId int, labelIds []int) (*Card, error) {
+	var card Card
+	body := struct {
+		LabelIDs []int `json:"label_ids"`
+	}{labelIds}
+	if err := c.call(ctx, http.MethodPut, apiPath("cards", cardId), nil, body, &card); err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+func (c *client) MoveCard(ctx context.Context, cardId int, destination CardDestination) (*Card, error) {
+	var card Card
+	body := struct {
+		Destination CardDestination `json:"destination"`
+	}{destination}
+	if err := c.call(ctx, http.MethodPut, apiPath("cards", cardId, "move"), nil, body, &card); err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
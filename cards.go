@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// listFlags collects a repeated flag, comma separated values are split
+type listFlags []string
+
+func (l *listFlags) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlags) Set(s string) error {
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// resolveLabels maps label names to the project's label ids
+func (s *sweep) resolveLabels(ctx context.Context, projectId int, names []string) ([]int, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	labels, err := s.client.ListLabels(ctx, projectId)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, l := range labels {
+			if strings.EqualFold(l.Name, name) {
+				ids = append(ids, l.ID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no label %q", name)
+		}
+	}
+	return ids, nil
+}
+
+// resolveUsers maps "me", user ids and usernames of the account's members to user ids
+func (s *sweep) resolveUsers(ctx context.Context, accountId int, names []string) ([]int, error) {
+	var members []AccountMember
+	ids := make([]int, 0, len(names))
+	for _, name := range names {
+		if name == "me" {
+			user, err := s.client.CurrentUser(ctx)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, user.ID)
+			continue
+		}
+		if id, err := strconv.Atoi(name); err == nil {
+			ids = append(ids, id)
+			continue
+		}
+		if accountId == 0 {
+			return nil, fmt.Errorf("resolving username %q requires a project", name)
+		}
+		if members == nil {
+			var err error
+			if members, err = s.client.ListAccountMembers(ctx, accountId); err != nil {
+				return nil, err
+			}
+		}
+		found := false
+		for _, m := range members {
+			if strings.EqualFold(m.User.Username, name) {
+				ids = append(ids, m.UserID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no account member %q", name)
+		}
+	}
+	return ids, nil
+}
+
+var cardCommand = &command{
+	name:  "card",
+	usage: "card create -project <p> -title <t> | assign <card> <user>... - create and assign individual cards",
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		if len(args) == 0 {
+			return errors.New("card requires create or assign")
+		}
+		switch args[0] {
+		case "create":
+			return s.createCard(ctx, args[1:])
+		case "assign":
+			return s.assignCard(ctx, args[1:])
+		}
+		return fmt.Errorf("unknown card command %q", args[0])
+	},
+}
+
+func (s *sweep) createCard(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("card create", flag.ExitOnError)
+	projectName := fs.String("project", "", "project id, name or slug")
+	workspaceName := fs.String("workspace", "", "workspace id, name or slug, the card goes to triage when unset")
+	title := fs.String("title", "", "card title")
+	body := fs.String("body", "", "card body, markdown")
+	var labels, assignees listFlags
+	fs.Var(&labels, "label", "label name, may be repeated")
+	fs.Var(&assignees, "assignee", `"me", a user id or username, may be repeated`)
+	fs.Parse(args)
+	if *projectName == "" || *title == "" {
+		return errors.New("card create requires -project and -title")
+	}
+	project, err := s.resolveProject(ctx, *projectName)
+	if err != nil {
+		return err
+	}
+	params := CardParams{ProjectID: project.ID, Title: *title, Body: *body}
+	if *workspaceName != "" {
+		workspace, err := s.resolveWorkspace(ctx, strconv.Itoa(project.ID), *workspaceName)
+		if err != nil {
+			return err
+		}
+		params.WorkspaceID = workspace.ID
+	}
+	if params.LabelIDs, err = s.resolveLabels(ctx, project.ID, labels); err != nil {
+		return err
+	}
+	if params.AssigneeIDs, err = s.resolveUsers(ctx, project.AccountID, assignees); err != nil {
+		return err
+	}
+	if s.dryRun {
+		s.printf("would create card %q in project %s\n", params.Title, project.Name)
+		return nil
+	}
+	card, err := s.client.CreateCard(ctx, params)
+	if err != nil {
+		return fmt.Errorf("while creating card: %w", err)
+	}
+	s.printf("created card #%d (%d) %s\n", card.Number, card.ID, card.Title)
+	return nil
+}
+
+func (s *sweep) assignCard(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("card assign", flag.ExitOnError)
+	remove := fs.Bool("remove", false, "unassign the users instead")
+	fs.Parse(args)
+	if fs.NArg() < 2 {
+		return errors.New("card assign requires a card id and at least one user")
+	}
+	cardId, err := strconv.Atoi(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid card id %q", fs.Arg(0))
+	}
+	card, err := s.client.GetCard(ctx, cardId)
+	if err != nil {
+		return err
+	}
+	users, err := s.resolveUsers(ctx, card.AccountID, fs.Args()[1:])
+	if err != nil {
+		return err
+	}
+	assigned := make(map[int]bool)
+	for _, id := range card.AssigneeIDs {
+		assigned[id] = true
+	}
+	for _, id := range users {
+		assigned[id] = !*remove
+	}
+	ids := []int{}
+	for id, ok := range assigned {
+		if ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	if s.dryRun {
+		s.printf("would set card #%d assignees to %v\n", card.Number, ids)
+		return nil
+	}
+	if _, err := s.client.SetCardAssignees(ctx, card.ID, ids); err != nil {
+		return fmt.Errorf("while assigning card %d: %w", card.ID, err)
+	}
+	s.printf("card #%d assignees: %v\n", card.Number, ids)
+	return nil
+}
+
+var cardsCommand = &command{
+	name:  "cards",
+	usage: "cards list | move -to-column <c> | label -add <l> | archive [filters] [card ids] - list and change cards in bulk",
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		if len(args) == 0 {
+			return errors.New("cards requires list, move, label or archive")
+		}
+		switch args[0] {
+		case "list":
+			return s.listCards(ctx, args[1:])
+		case "move", "label", "archive":
+			return s.bulkCards(ctx, args[0], args[1:])
+		}
+		return fmt.Errorf("unknown cards command %q", args[0])
+	},
+}
+
+// cardQuery selects cards, filtering server-side where the api supports it
+type cardQuery struct {
+	filter    CardFilter
+	assignees map[int]bool
+	since     time.Time
+}
+
+func (q cardQuery) match(card Card) bool {
+	if !q.since.IsZero() && card.UpdatedAt.Before(q.since) {
+		return false
+	}
+	if len(q.assignees) == 0 {
+		return true
+	}
+	for _, id := range card.AssigneeIDs {
+		if q.assignees[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// cardQueryFlags registers the card selection flags on fs, call build after parsing
+func (s *sweep) cardQueryFlags(fs *flag.FlagSet) func(ctx context.Context) (cardQuery, error) {
+	projectName := fs.String("project", "", "project id, name or slug")
+	workspaceName := fs.String("workspace", "", "workspace id, name, slug or project/workspace")
+	state := fs.String("state", "", "card state, open or closed")
+	status := fs.String("status", "", `board location, e.g. "triage", "backlog" or "in_progress"`)
+	search := fs.String("search", "", "full text search")
+	since := fs.String("since", "", "only cards updated after this RFC 3339 time, duration or number of days like 7d")
+	var assignees listFlags
+	fs.Var(&assignees, "assignee", `"me", a user id or username, may be repeated`)
+	return func(ctx context.Context) (cardQuery, error) {
+		q := cardQuery{filter: CardFilter{State: *state, Status: *status, Search: *search}}
+		var accountId int
+		if *projectName != "" {
+			project, err := s.resolveProject(ctx, *projectName)
+			if err != nil {
+				return q, err
+			}
+			q.filter.ProjectID, accountId = project.ID, project.AccountID
+		}
+		if *workspaceName != "" {
+			workspace, err := s.resolveWorkspace(ctx, *projectName, *workspaceName)
+			if err != nil {
+				return q, err
+			}
+			q.filter.WorkspaceID = workspace.ID
+			if accountId == 0 {
+				project, err := s.client.GetProject(ctx, workspace.ProjectID)
+				if err != nil {
+					return q, err
+				}
+				accountId = project.AccountID
+			}
+		}
+		if len(assignees) > 0 {
+			ids, err := s.resolveUsers(ctx, accountId, assignees)
+			if err != nil {
+				return q, err
+			}
+			q.assignees = make(map[int]bool, len(ids))
+			for _, id := range ids {
+				q.assignees[id] = true
+			}
+		}
+		if *since != "" {
+			t, err := parseSince(*since, time.Now())
+			if err != nil {
+				return q, err
+			}
+			q.since = t
+		}
+		return q, nil
+	}
+}
+
+// cards fetches every card matching q
+func (s *sweep) cards(ctx context.Context, q cardQuery) ([]Card, error) {
+	all, err := s.client.ListCards(ctx, q.filter)
+	if err != nil {
+		return nil, err
+	}
+	cards := all[:0]
+	for _, card := range all {
+		if q.match(card) {
+			cards = append(cards, card)
+		}
+	}
+	return cards, nil
+}
+
+func (s *sweep) listCards(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("cards list", flag.ExitOnError)
+	query := s.cardQueryFlags(fs)
+	format := fs.String("format", s.format, "output format: text or json")
+	fs.Parse(args)
+	q, err := query(ctx)
+	if err != nil {
+		return err
+	}
+	cards, err := s.cards(ctx, q)
+	if err != nil {
+		return err
+	}
+	switch *format {
+	case "", textFormat, tableFormat:
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNUMBER\tSTATE\tSTATUS\tUPDATED\tTITLE")
+		for _, c := range cards {
+			fmt.Fprintf(tw, "%d\t#%d\t%s\t%s\t%s\t%s\n", c.ID, c.Number, c.State, c.Status, c.UpdatedAt.Format(time.DateOnly), c.Title)
+		}
+		return tw.Flush()
+	case jsonFormat:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(cards)
+	}
+	return fmt.Errorf("unsupported -format %q for cards list", *format)
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"sync"
+	"text/tabwriter"
+
+	"golang.org/x/sync/errgroup"
+	"gopkg.in/yaml.v3"
+)
+
+// checkPolicy is a set of rules live preferences must satisfy, for example:
+//
+//	rules:
+//	  - name: quiet production
+//	    workspace: prod-*
+//	    email: {"*": false}
+//	  - name: no firehose
+//	    project: "*"
+//	    not_subscription_level: [everything]
+type checkPolicy struct {
+	Rules []checkRule `yaml:"rules" json:"rules"`
+}
+
+// checkRule constrains objects matching its patterns like a profileRule:
+// without a workspace pattern it applies to project preferences, with one to matching workspaces.
+// Email and InApp keys must have the given values, "*" meaning every boolean key.
+type checkRule struct {
+	Name                 string          `yaml:"name" json:"name"`
+	Project              string          `yaml:"project" json:"project"`
+	Workspace            string          `yaml:"workspace" json:"workspace"`
+	Email                map[string]bool `yaml:"email" json:"email"`
+	InApp                map[string]bool `yaml:"in_app" json:"in_app"`
+	SubscriptionLevel    string          `yaml:"subscription_level" json:"subscription_level"`
+	NotSubscriptionLevel []string        `yaml:"not_subscription_level" json:"not_subscription_level"`
+}
+
+func (r checkRule) matches(project Project, workspace *Workspace) bool {
+	if !matchName(r.Project, project.Name, project.Slug) {
+		return false
+	}
+	if workspace == nil {
+		return r.Workspace == ""
+	}
+	return r.Workspace != "" && matchName(r.Workspace, workspace.Name, workspace.Slug)
+}
+
+// violation is a rule an object doesn't satisfy
+type violation struct {
+	Rule   string `json:"rule"`
+	Object string `json:"object"`
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Detail string `json:"detail"`
+}
+
+func loadCheckPolicy(file string) (*checkPolicy, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var p checkPolicy
+	if err := yaml.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("while decoding policy %s: %w", file, err)
+	}
+	for i, r := range p.Rules {
+		if r.Name == "" {
+			p.Rules[i].Name = fmt.Sprintf("rule %d", i+1)
+		}
+		for _, pattern := range []string{r.Project, r.Workspace} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("policy %s: invalid pattern %q: %w", file, pattern, err)
+			}
+		}
+	}
+	return &p, nil
+}
+
+var checkCommand = &command{
+	name:  "check",
+	usage: "check -policy <rules.yaml> - report preferences violating policy rules, exiting 4 if any do",
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		fs := flag.NewFlagSet("check", flag.ExitOnError)
+		policyFile := fs.String("policy", "", "path to yaml rules every matching project and workspace must satisfy")
+		fs.Parse(args)
+		if *policyFile == "" {
+			return errors.New("check requires -policy")
+		}
+		p, err := loadCheckPolicy(*policyFile)
+		if err != nil {
+			return err
+		}
+		projects, err := s.targetProjects(ctx)
+		if err != nil {
+			return err
+		}
+		violations, err := s.check(ctx, p, projects)
+		if rerr := renderViolations(s.reportWriter(), s.format, violations); rerr != nil {
+			err = errors.Join(err, rerr)
+		}
+		// violations exit with their own status rather than as failures
+		s.outcome.violated(len(violations))
+		return err
+	},
+}
+
+// check evaluates p against every project and workspace, continuing past failures
+func (s *sweep) check(ctx context.Context, p *checkPolicy, projects []Project) ([]violation, error) {
+	var (
+		mu         sync.Mutex
+		violations []violation
+		errs       []error
+	)
+	evaluate := func(project Project, workspace *Workspace) {
+		object, id, name := "projects", project.ID, project.Name
+		if workspace != nil {
+			object, id, name = "workspaces", workspace.ID, project.Name+"/"+workspace.Name
+		}
+		var found []violation
+		err := s.checkObject(ctx, p, project, workspace, func(rule, detail string) {
+			found = append(found, violation{Rule: rule, Object: object, ID: id, Name: name, Detail: detail})
+		})
+		mu.Lock()
+		defer mu.Unlock()
+		violations = append(violations, found...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s (%d): %w", object[:len(object)-1], name, id, err))
+		} else {
+			s.outcome.succeed()
+		}
+	}
+	var g errgroup.Group
+	g.SetLimit(s.concurrency)
+	for _, pr := range projects {
+		project := pr
+		g.Go(func() error {
+			if s.group.hasWholeProject(project) {
+				evaluate(project, nil)
+			}
+			workspaces, err := s.client.ListWorkspaces(ctx, project.ID)
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("project %s (%d) workspaces: %w", project.Name, project.ID, err))
+				mu.Unlock()
+				return nil
+			}
+			for _, w := range s.activeWorkspaces(project, workspaces) {
+				workspace := w
+				evaluate(project, &workspace)
+			}
+			return nil
+		})
+	}
+	g.Wait()
+	sort.Slice(violations, func(i, j int) bool {
+		if violations[i].Name != violations[j].Name {
+			return violations[i].Name < violations[j].Name
+		}
+		return violations[i].Rule < violations[j].Rule
+	})
+	return violations, errors.Join(errs...)
+}
+
+// checkObject reports every rule the object violates, fetching only the preferences rules constrain
+func (s *sweep) checkObject(ctx context.Context, p *checkPolicy, project Project, workspace *Workspace, violate func(rule, detail string)) error {
+	object, objectId := "projects", project.ID
+	if workspace != nil {
+		object, objectId = "workspaces", workspace.ID
+	}
+	prefs := make(map[string]UserPreference)
+	preferences := func(prefType string) (UserPreference, error) {
+		if cached, ok := prefs[prefType]; ok {
+			return cached, nil
+		}
+		live, err := s.client.notificationPreferences(ctx, objectId, object, prefType)
+		if err != nil {
+			return nil, err
+		}
+		prefs[prefType] = live
+		return live, nil
+	}
+	var settings *UserSetting
+	for _, r := range p.Rules {
+		if !r.matches(project, workspace) {
+			continue
+		}
+		for _, pt := range []struct {
+			prefType string
+			keys     map[string]bool
+		}{{emailPreferences, r.Email}, {inAppPreferences, r.InApp}} {
+			if len(pt.keys) == 0 {
+				continue
+			}
+			live, err := preferences(pt.prefType)
+			if err != nil {
+				return err
+			}
+			for _, k := range sortedKeys(live) {
+				b, isBool := live[k].(bool)
+				want, ok := pt.keys[k]
+				if !ok {
+					want, ok = pt.keys["*"]
+				}
+				if isBool && ok && b != want {
+					violate(r.Name, fmt.Sprintf("%s %s is %t, must be %t", pt.prefType, k, b, want))
+				}
+			}
+		}
+		if r.SubscriptionLevel == "" && len(r.NotSubscriptionLevel) == 0 {
+			continue
+		}
+		if settings == nil {
+			var err error
+			if settings, err = s.client.userSettings(ctx, objectId, object, false); err != nil {
+				return err
+			}
+		}
+		if r.SubscriptionLevel != "" && settings.SubscriptionLevel != r.SubscriptionLevel {
+			violate(r.Name, fmt.Sprintf("subscription_level is %s, must be %s", settings.SubscriptionLevel, r.SubscriptionLevel))
+		}
+		if slices.Contains(r.NotSubscriptionLevel, settings.SubscriptionLevel) {
+			violate(r.Name, fmt.Sprintf("subscription_level must not be %s", settings.SubscriptionLevel))
+		}
+	}
+	return nil
+}
+
+func renderViolations(w io.Writer, format string, violations []violation) error {
+	if format == jsonFormat {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if violations == nil {
+			violations = []violation{}
+		}
+		return enc.Encode(violations)
+	}
+	if len(violations) == 0 {
+		fmt.Fprintln(w, "no policy violations")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "RULE\tOBJECT\tNAME\tVIOLATION")
+	for _, v := range violations {
+		fmt.Fprintf(tw, "%s\t%s %d\t%s\t%s\n", v.Rule, v.Object[:len(v.Object)-1], v.ID, v.Name, v.Detail)
+	}
+	return tw.Flush()
+}
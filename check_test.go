@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/graphaelli/zube-notifications/zubetest"
+)
+
+const testCheckPolicy = `rules:
+  - name: quiet production
+    workspace: prod-*
+    email: {card_moved: false}
+  - name: no firehose
+    project: "*"
+    not_subscription_level: [everything]
+`
+
+func TestCheckViolations(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	p := srv.AddProject("proj-a")
+	srv.AddWorkspace(p.ID, "dev")
+	prod := srv.AddWorkspace(p.ID, "prod-web")
+	policy := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(policy, []byte(testCheckPolicy), 0600); err != nil {
+		t.Fatal(err)
+	}
+	check := func() ([]violation, *outcome) {
+		t.Helper()
+		var report bytes.Buffer
+		s := newTestSweep(srv)
+		s.format = jsonFormat
+		s.reportOut = &report
+		if err := checkCommand.run(context.Background(), s, []string{"-policy", policy}); err != nil {
+			t.Fatal(err)
+		}
+		var violations []violation
+		if err := json.Unmarshal(report.Bytes(), &violations); err != nil {
+			t.Fatalf("decoding %q: %v", report.String(), err)
+		}
+		return violations, s.outcome
+	}
+
+	violations, o := check()
+	if len(violations) != 1 || violations[0].Rule != "quiet production" || violations[0].ID != prod.ID {
+		t.Errorf("violations %+v, want quiet production by %s", violations, prod.Name)
+	}
+	if got := o.exitCode(nil); got != exitViolations {
+		t.Errorf("exit code %d, want %d", got, exitViolations)
+	}
+	if writes := srv.Writes(); len(writes) > 0 {
+		t.Errorf("check wrote %v", writes)
+	}
+
+	srv.SetPreference("workspaces", prod.ID, zubetest.EmailPreferences, "card_moved", false)
+	violations, o = check()
+	if len(violations) != 0 {
+		t.Errorf("violations %+v after fixing them", violations)
+	}
+	if got := o.exitCode(nil); got != exitOK {
+		t.Errorf("exit code %d, want %d", got, exitOK)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"io"
+)
+
+// API is the part of the Zube api used to inspect and change notification preferences.
+// It is implemented by the client returned from NewClient, tests can substitute fakes
+// or point the client at a zubetest.Server.
+type API interface {
+	ListProjects(ctx context.Context) ([]Project, error)
+	GetProject(ctx context.Context, projectId int) (*Project, error)
+	ListWorkspaces(ctx context.Context, projectId int) ([]Workspace, error)
+	ListSources(ctx context.Context, projectId int) ([]Sources, error)
+
+	ProjectEmailPreferences(ctx context.Context, projectId int) (UserPreference, error)
+	ProjectInAppPreferences(ctx context.Context, projectId int) (UserPreference, error)
+	WorkspaceEmailPreferences(ctx context.Context, workspaceId int) (UserPreference, error)
+	WorkspaceInAppPreferences(ctx context.Context, workspaceId int) (UserPreference, error)
+
+	ProjectUserSettings(ctx context.Context, projectId int) (*UserSetting, error)
+	ProjectTriageUserSettings(ctx context.Context, projectId int) (*UserSetting, error)
+	WorkspaceUserSettings(ctx context.Context, workspaceId int) (*UserSetting, error)
+
+	SetProjectSubscriptionLevel(ctx context.Context, projectId, settingId int, level string) error
+	SetProjectTriageSubscriptionLevel(ctx context.Context, projectId, settingId int, level string) error
+	SetWorkspaceSubscriptionLevel(ctx context.Context, workspaceId, settingId int, level string) error
+
+	DisableProjectEmailNotifications(ctx context.Context, projectId, prefId int, body io.Reader) error
+	DisableProjectInAppNotifications(ctx context.Context, projectId, prefId int, body io.Reader) error
+	DisableWorkspaceEmailNotifications(ctx context.Context, workspaceId, prefId int, body io.Reader) error
+	DisableWorkspaceInAppNotifications(ctx context.Context, workspaceId, prefId int, body io.Reader) error
+	EnableProjectEmailNotifications(ctx context.Context, projectId, prefId int, body io.Reader) error
+	EnableProjectInAppNotifications(ctx context.Context, projectId, prefId int, body io.Reader) error
+	EnableWorkspaceEmailNotifications(ctx context.Context, workspaceId, prefId int, body io.Reader) error
+	EnableWorkspaceInAppNotifications(ctx context.Context, workspaceId, prefId int, body io.Reader) error
+	ResetProjectEmailNotifications(ctx context.Context, projectId, prefId int) error
+	ResetProjectInAppNotifications(ctx context.Context, projectId, prefId int) error
+	ResetWorkspaceEmailNotifications(ctx context.Context, workspaceId, prefId int) error
+	ResetWorkspaceInAppNotifications(ctx context.Context, workspaceId, prefId int) error
+
+	CurrentUser(ctx context.Context) (*User, error)
+}
+
+var _ API = (*client)(nil)
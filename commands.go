@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// command is a subcommand of the cli, run after global flags are parsed
+type command struct {
+	name  string
+	usage string
+	// offline commands don't call the api, they run without loading a private key
+	offline bool
+	// keyOptional commands run when the private key or signer can't be loaded, with the error in sweep.keyErr
+	keyOptional bool
+	run         func(ctx context.Context, s *sweep, args []string) error
+}
+
+// defaultCommand reports on, and optionally updates, preferences for every project
+var defaultCommand = &command{
+	name:  "sweep",
+	usage: "report on all projects and workspaces, applying -E/-I/-enable-* changes",
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		projects, err := s.targetProjects(ctx)
+		if err != nil {
+			return err
+		}
+		err = s.run(ctx, projects)
+		if s.report != nil {
+			// render what was collected, even when some projects failed
+			if rerr := s.report.render(s.reportWriter(), s.format); rerr != nil {
+				return errors.Join(err, rerr)
+			}
+		}
+		return err
+	},
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		defaultCommand,
+		snapshotCommand,
+		restoreCommand,
+		applyCommand,
+		planCommand,
+		whoamiCommand,
+		watchCommand,
+		inboxCommand,
+		forwardCommand,
+		digestCommand,
+		keyringCommand,
+		undoCommand,
+		tuiCommand,
+		statsCommand,
+		exportPolicyCommand,
+		cardCommand,
+		cardsCommand,
+		serveCommand,
+		dndCommand,
+		muteCommand,
+		unmuteCommand,
+		subscribeCommand,
+		unsubscribeCommand,
+		stateCommand,
+		versionCommand,
+		schemaCommand,
+		checkCommand,
+		authCommand,
+		reportCommand,
+	}
+}
+
+func lookupCommand(args []string) (*command, []string) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c, args[1:]
+		}
+	}
+	return nil, args
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] [command] [args]\n\ncommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(flag.CommandLine.Output(), "  %-10s %s\n", c.name, c.usage)
+	}
+	fmt.Fprintf(flag.CommandLine.Output(), "\nflags:\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(flag.CommandLine.Output(), "\nexit status:\n"+
+		"  %d  success, no preferences changed\n"+
+		"  %d  failure, nothing could be done\n"+
+		"  %d  partial failure, some projects, workspaces or accounts failed\n"+
+		"  %d  success, preferences changed, or would change with -dry-run\n"+
+		"  %d  check found policy violations\n",
+		exitOK, exitFatal, exitPartial, exitChanged, exitViolations)
+}
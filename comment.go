@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"net/http"
+)
+
+func (c *client) ListCardComments(ctx context.Context, cardId int) ([]Comment, error) {
+	return paginate[Comment](ctx, c, apiPath("cards", cardId, "comments"), nil)
+}
+
+// CommentsIter iterates over the comments of a card, a page at a time
+func (c *client) CommentsIter(ctx context.Context, cardId int) *Iterator[Comment] {
+	return iterate[Comment](ctx, c, apiPath("cards", cardId, "comments"), nil)
+}
+
+func (c *client) CreateComment(ctx context.Context, cardId int, body string) (*Comment, error) {
+	var comment Comment
+	params := map[string]string{"body": body}
+	if err := c.call(ctx, http.MethodPost, apiPath("cards", cardId, "comments"), nil, params, &comment); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+func (c *client) UpdateComment(ctx context.Context, cardId, commentId int, body string) (*Comment, error) {
+	var comment Comment
+	params := map[string]string{"body": body}
+	if err := c.call(ctx, http.MethodPut, apiPath("cards", cardId, "comments", commentId), nil, params, &comment); err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
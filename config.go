@@ -0,0 +1,231 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// config is read from ~/.config/zube-notifications/config.yaml, for example:
+//
+//	client_id: abc123
+//	key: ~/.zube/zube_api_key.pem
+//	concurrency: 8
+//	profiles:
+//	  staging:
+//	    client_id: def456
+//	    key: ~/.zube/staging.pem
+//	    api_url: https://staging.zube.io/api/
+//	accounts:
+//	  work:
+//	    client_id: ghi789
+//	    key: ~/.zube/work.pem
+//	preferences:
+//	  vacation:
+//	    rules:
+//	      - email: {"*": false}
+//	groups:
+//	  frontend: [proj-a/web, proj-b/ui, design]
+//	defaults:
+//	  oncall-project: everything
+//	  archive-*: mute-all
+//	  "*": vacation
+//
+// Flags and environment variables take precedence over the profile selected with -profile,
+// which takes precedence over the top level settings.
+type config struct {
+	settings    `yaml:",inline"`
+	Profiles    map[string]settings `yaml:"profiles"`
+	Accounts    map[string]settings `yaml:"accounts"`
+	Preferences map[string]*profile `yaml:"preferences"`
+	// Groups name sets of <project>/<workspace> references, bare projects include every workspace and the project itself
+	Groups map[string][]string `yaml:"groups"`
+	// Defaults maps project slugs, names or patterns to the preference profile apply -defaults uses
+	Defaults map[string]string `yaml:"defaults"`
+}
+
+// settings are the connection and runtime defaults a config profile may set
+type settings struct {
+	ClientID    string `yaml:"client_id"`
+	Key         string `yaml:"key"`
+	APIURL      string `yaml:"api_url"`
+	Concurrency int    `yaml:"concurrency"`
+}
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "zube-notifications", "config.yaml")
+}
+
+// loadConfig reads the config at path, a missing file is an empty config unless required
+func loadConfig(path string, required bool) (*config, error) {
+	cfg := &config{}
+	if path == "" {
+		return cfg, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return cfg, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("while decoding config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// resolve merges the named profile over the top level settings
+func (c *config) resolve(name string) (settings, error) {
+	s := c.settings
+	if name == "" {
+		return s, nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return s, fmt.Errorf("no profile %q in config", name)
+	}
+	if p.ClientID != "" {
+		s.ClientID = p.ClientID
+	}
+	if p.Key != "" {
+		s.Key = p.Key
+	}
+	if p.APIURL != "" {
+		s.APIURL = p.APIURL
+	}
+	if p.Concurrency > 0 {
+		s.Concurrency = p.Concurrency
+	}
+	return s, nil
+}
+
+// expandHome replaces a leading ~/ with the user's home directory
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
+
+// credentials identify a zube client to run commands as
+type credentials struct {
+	// name is the config account or -users user name, empty for flag or profile credentials
+	name string
+	// kind is what name refers to, account unless set
+	kind     string
+	clientId string
+	keyFiles []string
+	apiURL   string
+}
+
+// heading is the report section title of the credentials
+func (c credentials) heading() string {
+	if c.kind == "" {
+		return "account " + c.name
+	}
+	return c.kind + " " + c.name
+}
+
+// label prefixes err with the account or user name, if any
+func (c credentials) label(err error) error {
+	if c.name == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %w", c.heading(), err)
+}
+
+// credentials resolves -account: empty uses base, "all" every configured account in name order,
+// anything else the named account. Accounts without an api_url use base's.
+func (c *config) credentials(account string, base credentials) ([]credentials, error) {
+	if account == "" {
+		return []credentials{base}, nil
+	}
+	names := []string{account}
+	if account == "all" {
+		names = names[:0]
+		for name := range c.Accounts {
+			names = append(names, name)
+		}
+		if len(names) == 0 {
+			return nil, errors.New("-account all requires accounts in the config file")
+		}
+		sort.Strings(names)
+	}
+	creds := make([]credentials, 0, len(names))
+	for _, name := range names {
+		a, ok := c.Accounts[name]
+		if !ok {
+			return nil, fmt.Errorf("no account %q in config", name)
+		}
+		cred := credentials{name: name, clientId: a.ClientID, keyFiles: strings.Split(a.Key, ","), apiURL: a.APIURL}
+		if cred.apiURL == "" {
+			cred.apiURL = base.apiURL
+		}
+		creds = append(creds, cred)
+	}
+	return creds, nil
+}
+
+// userCredentials reads the credentials of the users an admin acts for with -users, in name order.
+// path is either a directory of <client id>.pem keys, or a yaml file mapping user names to
+// client_id, key and optionally api_url, relative key paths being relative to the file.
+func userCredentials(path string, base credentials) ([]credentials, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	var creds []credentials
+	if info.IsDir() {
+		keys, err := filepath.Glob(filepath.Join(path, "*.pem"))
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			id := strings.TrimSuffix(filepath.Base(key), ".pem")
+			creds = append(creds, credentials{name: id, kind: "user", clientId: id, keyFiles: []string{key}, apiURL: base.apiURL})
+		}
+	} else {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var users map[string]settings
+		if err := yaml.Unmarshal(b, &users); err != nil {
+			return nil, fmt.Errorf("while decoding users %s: %w", path, err)
+		}
+		for name, u := range users {
+			if u.ClientID == "" || u.Key == "" {
+				return nil, fmt.Errorf("user %s in %s requires client_id and key", name, path)
+			}
+			cred := credentials{name: name, kind: "user", clientId: u.ClientID, apiURL: u.APIURL}
+			for _, key := range strings.Split(u.Key, ",") {
+				if !filepath.IsAbs(key) && !strings.HasPrefix(key, "~/") {
+					key = filepath.Join(filepath.Dir(path), key)
+				}
+				cred.keyFiles = append(cred.keyFiles, key)
+			}
+			if cred.apiURL == "" {
+				cred.apiURL = base.apiURL
+			}
+			creds = append(creds, cred)
+		}
+		sort.Slice(creds, func(i, j int) bool { return creds[i].name < creds[j].name })
+	}
+	if len(creds) == 0 {
+		return nil, fmt.Errorf("no users in %s", path)
+	}
+	return creds, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`client_id: prod-id
+key: ~/prod.pem
+concurrency: 4
+profiles:
+  staging:
+    client_id: staging-id
+    api_url: https://staging.example.com/api/
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadConfig(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := cfg.resolve("staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := settings{ClientID: "staging-id", Key: "~/prod.pem", APIURL: "https://staging.example.com/api/", Concurrency: 4}
+	if got != want {
+		t.Errorf("staging settings %+v, want %+v", got, want)
+	}
+	if got, _ := cfg.resolve(""); got.ClientID != "prod-id" || got.APIURL != "" {
+		t.Errorf("top level settings %+v", got)
+	}
+	if _, err := cfg.resolve("production"); err == nil {
+		t.Error("expected an error for an unknown profile")
+	}
+
+	if cfg, err := loadConfig(filepath.Join(t.TempDir(), "missing.yaml"), false); err != nil || cfg.ClientID != "" {
+		t.Errorf("missing optional config: %+v, %v", cfg, err)
+	}
+	if _, err := loadConfig(filepath.Join(t.TempDir(), "missing.yaml"), true); err == nil {
+		t.Error("expected an error for a missing required config")
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+)
+
+// builtinProfiles are preference profiles available without defining them in the config file
+var builtinProfiles = map[string]func() *profile{
+	"everything": func() *profile { return allProfile("everything", true) },
+	"mute-all":   func() *profile { return allProfile("mute-all", false) },
+}
+
+// allProfile sets every email and in-app preference of projects and workspaces to enabled
+func allProfile(name string, enabled bool) *profile {
+	all := func() map[string]bool { return map[string]bool{"*": enabled} }
+	return &profile{Name: name, Rules: []profileRule{
+		{Email: all(), InApp: all()},
+		{Workspace: "*", Email: all(), InApp: all()},
+	}}
+}
+
+// validateDefaults checks the project patterns of the defaults
+func (c *config) validateDefaults() error {
+	for pattern := range c.Defaults {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("defaults: invalid pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// defaultProfile returns the name of the profile the config's defaults map project to.
+// An exact name or slug wins over patterns, and longer patterns over shorter ones.
+func (c *config) defaultProfile(project Project) (string, bool) {
+	if name, ok := c.Defaults[project.Slug]; ok {
+		return name, true
+	}
+	if name, ok := c.Defaults[project.Name]; ok {
+		return name, true
+	}
+	best := ""
+	for pattern := range c.Defaults {
+		if !matchName(pattern, project.Name, project.Slug) {
+			continue
+		}
+		if best == "" || len(pattern) > len(best) || (len(pattern) == len(best) && pattern < best) {
+			best = pattern
+		}
+	}
+	if best == "" {
+		return "", false
+	}
+	return c.Defaults[best], true
+}
+
+// applyDefaults reconciles each project, and its workspaces, toward the profile the config's
+// defaults map it to, leaving projects without one alone
+func (s *sweep) applyDefaults(ctx context.Context, projects []Project, workspaceFilter string) error {
+	if s.config == nil || len(s.config.Defaults) == 0 {
+		return errors.New("apply -defaults requires defaults in the config file")
+	}
+	if err := s.config.validateDefaults(); err != nil {
+		return err
+	}
+	byProfile := make(map[string][]Project)
+	for _, p := range sortedByName(projects, func(p Project) string { return p.Name }) {
+		name, ok := s.config.defaultProfile(p)
+		if !ok {
+			s.narrate("%s: no default profile, skipped\n", p.Name)
+			continue
+		}
+		byProfile[name] = append(byProfile[name], p)
+	}
+	names := make([]string, 0, len(byProfile))
+	for name := range byProfile {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	// load every profile before changing anything, a typo shouldn't leave a half applied policy
+	profiles := make(map[string]*profile, len(names))
+	for _, name := range names {
+		p, err := s.loadProfile(name)
+		if err != nil {
+			return fmt.Errorf("default profile %s: %w", name, err)
+		}
+		if err := s.validateProfile(ctx, p, byProfile[name]); err != nil {
+			return err
+		}
+		profiles[name] = p
+	}
+	var errs []error
+	for _, name := range names {
+		s.narrate("\n*** applying %s to %d projects\n", name, len(byProfile[name]))
+		if err := s.applyProfile(ctx, profiles[name], byProfile[name], workspaceFilter); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
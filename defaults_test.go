@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/graphaelli/zube-notifications/zubetest"
+)
+
+func TestDefaultProfile(t *testing.T) {
+	cfg := &config{Defaults: map[string]string{
+		"web-app":    "slug",
+		"Mobile App": "name",
+		"*":          "everything",
+		"web-*":      "web",
+		"api-*":      "api-prefix",
+		"*-svc":      "svc-suffix",
+	}}
+	for _, tc := range []struct {
+		name, slug string
+		want       string
+	}{
+		{"Web App", "web-app", "slug"},
+		{"Mobile App", "mobile-app", "name"},
+		{"Web Site", "web-site", "web"},
+		{"Docs", "docs", "everything"},
+		// equally long patterns fall back to the lexically smallest
+		{"Api Svc", "api-svc", "svc-suffix"},
+	} {
+		got, ok := cfg.defaultProfile(Project{Name: tc.name, Slug: tc.slug})
+		if !ok || got != tc.want {
+			t.Errorf("%s: got %q %v, want %q", tc.slug, got, ok, tc.want)
+		}
+	}
+
+	cfg = &config{Defaults: map[string]string{"web-*": "web"}}
+	if got, ok := cfg.defaultProfile(Project{Name: "Docs", Slug: "docs"}); ok {
+		t.Errorf("docs mapped to %q", got)
+	}
+}
+
+func TestApplyDefaults(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	oncall := srv.AddProject("oncall")
+	archive := srv.AddProject("archive-2023")
+	old := srv.AddWorkspace(archive.ID, "old")
+	srv.AddProject("other")
+
+	s := newTestSweep(srv)
+	s.config = &config{Defaults: map[string]string{"oncall": "everything", "archive-*": "mute-all"}}
+	run := func() {
+		t.Helper()
+		if err := applyCommand.run(context.Background(), s, []string{"-defaults"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run()
+	for object, id := range map[string]int{"projects": archive.ID, "workspaces": old.ID} {
+		if email := srv.Preferences(object, id, zubetest.EmailPreferences); email["card_moved"] != false {
+			t.Errorf("%s %d not muted: %v", object, id, email)
+		}
+	}
+	if email := srv.Preferences("projects", oncall.ID, zubetest.EmailPreferences); email["card_moved"] != true {
+		t.Errorf("oncall changed: %v", email)
+	}
+	// oncall already has everything enabled and other has no default
+	if puts := countWrites(srv, http.MethodPut); puts != 4 {
+		t.Errorf("%d writes, want both preference types of the archive project and workspace", puts)
+	}
+
+	// a second run finds everything reconciled
+	run()
+	if puts := countWrites(srv, http.MethodPut); puts != 4 {
+		t.Errorf("%d writes after reconciling again, want none", puts-4)
+	}
+}
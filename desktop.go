@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// toastScript shows a windows toast with the title and body passed in the environment
+const toastScript = `$ErrorActionPreference = 'Stop'
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$t = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$x = $t.GetElementsByTagName('text')
+$x.Item(0).AppendChild($t.CreateTextNode($env:ZUBE_TITLE)) > $null
+$x.Item(1).AppendChild($t.CreateTextNode($env:ZUBE_BODY)) > $null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('zube-notifications').Show([Windows.UI.Notifications.ToastNotification]::new($t))`
+
+// desktopSink shows notifications and webhook events as native desktop notifications
+type desktopSink struct {
+	// projects, when set, restricts notifications to these project ids
+	projects map[int]bool
+	goos     string
+}
+
+// newDesktopSink checks the platform's notifier is available. projects is a comma separated list of ids,
+// or of names and slugs when resolve is set.
+func newDesktopSink(ctx context.Context, projects string, resolve func(context.Context, string) (int, error)) (*desktopSink, error) {
+	d := &desktopSink{goos: runtime.GOOS}
+	for ref := range splitKeys(projects) {
+		n, err := strconv.Atoi(ref)
+		if err != nil && resolve != nil {
+			n, err = resolve(ctx, ref)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid -desktop-projects project %q: %w", ref, err)
+		}
+		if d.projects == nil {
+			d.projects = make(map[int]bool)
+		}
+		d.projects[n] = true
+	}
+	name, _ := d.command("", "")
+	if _, err := exec.LookPath(name); err != nil {
+		return nil, fmt.Errorf("desktop notifications on %s require %s: %w", d.goos, name, err)
+	}
+	return d, nil
+}
+
+// command returns the notifier invocation for the platform
+func (d *desktopSink) command(title, body string) (string, []string) {
+	switch d.goos {
+	case "darwin":
+		if _, err := exec.LookPath("terminal-notifier"); err == nil {
+			return "terminal-notifier", []string{"-title", title, "-message", body, "-group", "zube-notifications"}
+		}
+		return "osascript", []string{"-e", "on run argv", "-e", "display notification (item 2 of argv) with title (item 1 of argv)", "-e", "end run", title, body}
+	case "windows":
+		return "powershell", []string{"-NoProfile", "-NonInteractive", "-Command", toastScript}
+	}
+	return "notify-send", []string{"--app-name=zube-notifications", title, body}
+}
+
+func (d *desktopSink) show(ctx context.Context, projectId int, title, body string) error {
+	if d.projects != nil && !d.projects[projectId] {
+		return nil
+	}
+	name, args := d.command(title, body)
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), "ZUBE_TITLE="+title, "ZUBE_BODY="+body)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s: %w: %s", name, err, msg)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+func (d *desktopSink) Send(ctx context.Context, n Notification) error {
+	return d.show(ctx, n.ProjectID, n.Title, n.Body)
+}
+
+func (d *desktopSink) Handle(ctx context.Context, e Event) error {
+	title, body := "Zube: "+e.Type, ""
+	if e.Card != nil {
+		title = fmt.Sprintf("Zube: #%d %s", e.Card.Number, e.Card.Title)
+		body = e.Type
+	}
+	switch {
+	case e.Type == eventCardMoved:
+		body = fmt.Sprintf("moved %s → %s", e.From, e.To)
+	case e.Comment != nil:
+		body = e.Comment.Body
+	}
+	return d.show(ctx, e.ProjectID, title, body)
+}
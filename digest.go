@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// digest summarizes notification activity over a time window
+type digest struct {
+	Since    time.Time     `json:"since"`
+	Until    time.Time     `json:"until"`
+	Total    int           `json:"total"`
+	Projects []digestGroup `json:"projects"`
+}
+
+// digestGroup counts notifications for a project or workspace by event type
+type digestGroup struct {
+	Name       string         `json:"name"`
+	Total      int            `json:"total"`
+	Events     map[string]int `json:"events"`
+	Workspaces []digestGroup  `json:"workspaces,omitempty"`
+}
+
+func (g *digestGroup) add(event string) {
+	g.Total++
+	if g.Events == nil {
+		g.Events = make(map[string]int)
+	}
+	g.Events[event]++
+}
+
+// buildDigest groups notifications created in [since, until) by project, workspace and event
+func buildDigest(notifications []Notification, projectNames, workspaceNames map[int]string, since, until time.Time) *digest {
+	d := &digest{Since: since, Until: until}
+	projects := make(map[int]*digestGroup)
+	workspaces := make(map[int]map[int]*digestGroup)
+	for _, n := range notifications {
+		if n.CreatedAt.Before(since) || !n.CreatedAt.Before(until) {
+			continue
+		}
+		d.Total++
+		p, ok := projects[n.ProjectID]
+		if !ok {
+			p = &digestGroup{Name: nameOr(projectNames, n.ProjectID, "project")}
+			projects[n.ProjectID] = p
+			workspaces[n.ProjectID] = make(map[int]*digestGroup)
+		}
+		p.add(n.Event)
+		if n.WorkspaceID == 0 {
+			continue
+		}
+		w, ok := workspaces[n.ProjectID][n.WorkspaceID]
+		if !ok {
+			w = &digestGroup{Name: nameOr(workspaceNames, n.WorkspaceID, "workspace")}
+			workspaces[n.ProjectID][n.WorkspaceID] = w
+		}
+		w.add(n.Event)
+	}
+	for id, p := range projects {
+		for _, w := range workspaces[id] {
+			p.Workspaces = append(p.Workspaces, *w)
+		}
+		sortDigestGroups(p.Workspaces)
+		d.Projects = append(d.Projects, *p)
+	}
+	sortDigestGroups(d.Projects)
+	return d
+}
+
+func nameOr(names map[int]string, id int, kind string) string {
+	if name, ok := names[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("%s %d", kind, id)
+}
+
+// sortDigestGroups orders the busiest groups first
+func sortDigestGroups(groups []digestGroup) {
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Total != groups[j].Total {
+			return groups[i].Total > groups[j].Total
+		}
+		return groups[i].Name < groups[j].Name
+	})
+}
+
+func writeEventCounts(w io.Writer, indent string, events map[string]int) {
+	keys := make([]string, 0, len(events))
+	for k := range events {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if events[keys[i]] != events[keys[j]] {
+			return events[keys[i]] > events[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s%-30s %d\n", indent, k, events[k])
+	}
+}
+
+func (d *digest) writeText(w io.Writer) {
+	fmt.Fprintf(w, "zube notifications %s - %s: %d total\n",
+		d.Since.Local().Format("2006-01-02 15:04"), d.Until.Local().Format("2006-01-02 15:04"), d.Total)
+	for _, p := range d.Projects {
+		fmt.Fprintf(w, "\n%s: %d\n", p.Name, p.Total)
+		writeEventCounts(w, "    ", p.Events)
+		for _, ws := range p.Workspaces {
+			fmt.Fprintf(w, "  %s: %d\n", ws.Name, ws.Total)
+			writeEventCounts(w, "      ", ws.Events)
+		}
+	}
+}
+
+// smtpConfig describes how to email a digest
+type smtpConfig struct {
+	addr     string
+	from     string
+	to       []string
+	username string
+	password string
+}
+
+func (c smtpConfig) send(subject string, body []byte) error {
+	msg := new(bytes.Buffer)
+	fmt.Fprintf(msg, "From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n",
+		c.from, strings.Join(c.to, ", "), subject)
+	msg.Write(bytes.ReplaceAll(body, []byte("\n"), []byte("\r\n")))
+	var auth smtp.Auth
+	if c.username != "" {
+		host, _, err := net.SplitHostPort(c.addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", c.username, c.password, host)
+	}
+	return smtp.SendMail(c.addr, auth, c.from, c.to, msg.Bytes())
+}
+
+var digestCommand = &command{
+	name:  "digest",
+	usage: "digest [-since 24h] [-unread] [-smtp host:port -to addr] - summarize notification activity, as json with the global -format json",
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		fs := flag.NewFlagSet("digest", flag.ExitOnError)
+		since := fs.Duration("since", 24*time.Hour, "window of notification activity to summarize")
+		unread := fs.Bool("unread", false, "only summarize unread notifications")
+		smtpAddr := fs.String("smtp", "", "smtp server host:port to email the digest through instead of printing it")
+		from := fs.String("from", os.Getenv("ZUBE_DIGEST_FROM"), "digest email sender")
+		to := fs.String("to", os.Getenv("ZUBE_DIGEST_TO"), "comma separated digest email recipients")
+		smtpUser := fs.String("smtp-user", os.Getenv("ZUBE_SMTP_USER"), "smtp username, password is read from ZUBE_SMTP_PASSWORD")
+		fs.Parse(args)
+
+		until := time.Now()
+		notifications, err := s.client.ListNotificationsSince(ctx, *unread, until.Add(-*since))
+		if err != nil {
+			return err
+		}
+		projects, err := s.client.ListProjects(ctx)
+		if err != nil {
+			return err
+		}
+		projectNames := make(map[int]string, len(projects))
+		for _, p := range projects {
+			projectNames[p.ID] = p.Name
+		}
+		workspaces, err := s.client.ListAllWorkspaces(ctx)
+		if err != nil {
+			return err
+		}
+		workspaceNames := make(map[int]string, len(workspaces))
+		for _, w := range workspaces {
+			workspaceNames[w.ID] = w.Name
+		}
+		d := buildDigest(notifications, projectNames, workspaceNames, until.Add(-*since), until)
+
+		out := new(bytes.Buffer)
+		switch s.format {
+		case "", textFormat:
+			d.writeText(out)
+		case jsonFormat:
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(d); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("digest supports -format text or json, not %q", s.format)
+		}
+		if *smtpAddr == "" {
+			_, err := out.WriteTo(s.reportWriter())
+			return err
+		}
+		if *from == "" || *to == "" {
+			return errors.New("emailing a digest requires -from and -to")
+		}
+		cfg := smtpConfig{
+			addr:     *smtpAddr,
+			from:     *from,
+			to:       strings.Split(*to, ","),
+			username: *smtpUser,
+			password: os.Getenv("ZUBE_SMTP_PASSWORD"),
+		}
+		subject := fmt.Sprintf("Zube digest: %d notifications", d.Total)
+		if s.dryRun {
+			s.printf("[dry-run] email %q to %s\n", subject, *to)
+			return nil
+		}
+		return cfg.send(subject, out.Bytes())
+	},
+}
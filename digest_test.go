@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/graphaelli/zube-notifications/zubetest"
+)
+
+func TestDigest(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	srv.AddNotification(zubetest.Notification{Event: "card_moved", Title: "one"})
+	srv.AddNotification(zubetest.Notification{Event: "card_moved", Title: "two"})
+	srv.AddNotification(zubetest.Notification{Event: "card_commented", Title: "three"})
+	ctx := context.Background()
+	digest := func(format string, args ...string) (report, out string) {
+		t.Helper()
+		var reportOut, narration bytes.Buffer
+		s := newTestSweep(srv)
+		s.out = &narration
+		s.reportOut = &reportOut
+		s.format = format
+		s.dryRun = true
+		if err := digestCommand.run(ctx, s, args); err != nil {
+			t.Fatal(err)
+		}
+		return reportOut.String(), narration.String()
+	}
+
+	if report, _ := digest(""); !strings.Contains(report, ": 3 total") {
+		t.Errorf("text digest %q", report)
+	}
+	report, _ := digest(jsonFormat)
+	var d struct{ Total int }
+	if err := json.Unmarshal([]byte(report), &d); err != nil || d.Total != 3 {
+		t.Errorf("json digest %q: %v", report, err)
+	}
+	if err := digestCommand.run(ctx, &sweep{client: newTestClient(srv), format: "csv"}, nil); err == nil {
+		t.Error("digest accepted -format csv")
+	}
+
+	report, out := digest("", "-smtp", "localhost:25", "-from", "zube@example.com", "-to", "me@example.com")
+	if report != "" || !strings.Contains(out, `[dry-run] email "Zube digest: 3 notifications" to me@example.com`) {
+		t.Errorf("dry run printed %q, reported %q", out, report)
+	}
+}
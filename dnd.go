@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// dndBucket holds do not disturb state by client id
+const dndBucket = "dnd"
+
+// dndState is persisted while do not disturb is on, so any later invocation can restore it
+type dndState struct {
+	ExpiresAt time.Time `json:"expires_at"`
+	Snapshot  *snapshot `json:"snapshot"`
+}
+
+// loadDND returns nil when do not disturb is off
+func (s *sweep) loadDND() (*dndState, error) {
+	if s.store == nil {
+		return nil, nil
+	}
+	var st dndState
+	if ok, err := s.store.Get(dndBucket, s.client.clientId, &st); !ok || err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+func (s *sweep) saveDND(st *dndState) error {
+	if s.store == nil {
+		return errors.New("dnd requires -state-db")
+	}
+	return s.store.Put(dndBucket, s.client.clientId, st)
+}
+
+// endDND restores the snapshot taken when do not disturb started, keeping the state for a retry on failure
+func (s *sweep) endDND(ctx context.Context, st *dndState) error {
+	if err := s.restore(ctx, st.Snapshot); err != nil {
+		return fmt.Errorf("while ending do not disturb, will retry on the next run: %w", err)
+	}
+	if s.dryRun {
+		return nil
+	}
+	return s.store.Delete(dndBucket, s.client.clientId)
+}
+
+// expireDND ends an expired do not disturb window, called before every command that uses the api.
+// Read-only runs leave the window to the next run that may write.
+func (s *sweep) expireDND(ctx context.Context) error {
+	st, err := s.loadDND()
+	if err != nil || st == nil || time.Now().Before(st.ExpiresAt) {
+		return err
+	}
+	if s.client.readOnly {
+		slog.Info("do not disturb expired, preferences are restored by the next run without -read-only", "expired", st.ExpiresAt.Local().Format(time.Kitchen))
+		return nil
+	}
+	slog.Info("do not disturb expired, restoring preferences", "expired", st.ExpiresAt.Local().Format(time.Kitchen))
+	return s.endDND(ctx, st)
+}
+
+var dndCommand = &command{
+	name:  "dnd",
+	usage: "dnd -for 2h [-wait] | -off | -status - disable all notifications, restoring them when the window ends",
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		fs := flag.NewFlagSet("dnd", flag.ExitOnError)
+		duration := fs.Duration("for", 0, "how long to disable notifications, extends an active window")
+		wait := fs.Bool("wait", false, "keep running and restore preferences when the window ends, otherwise the next run restores them")
+		off := fs.Bool("off", false, "end do not disturb now")
+		status := fs.Bool("status", false, "print whether do not disturb is on")
+		fs.Parse(args)
+		st, err := s.loadDND()
+		if err != nil {
+			return err
+		}
+		switch {
+		case *status:
+			if st == nil {
+				s.printf("do not disturb is off\n")
+			} else {
+				s.printf("do not disturb until %s\n", st.ExpiresAt.Local().Format(time.RFC1123))
+			}
+			return nil
+		case *off:
+			if st == nil {
+				return errors.New("do not disturb is not on")
+			}
+			s.showChanges = true
+			return s.endDND(ctx, st)
+		case *duration <= 0:
+			return errors.New("dnd requires -for, -off or -status")
+		}
+
+		if st != nil {
+			// preferences are already disabled, snapshotting again would lose the originals
+			st.ExpiresAt = time.Now().Add(*duration)
+			if !s.dryRun {
+				if err := s.saveDND(st); err != nil {
+					return err
+				}
+			}
+			s.printf("do not disturb extended until %s\n", st.ExpiresAt.Local().Format(time.RFC1123))
+		} else {
+			snap, err := s.snapshot(ctx)
+			if err != nil {
+				return fmt.Errorf("while snapshotting preferences: %w", err)
+			}
+			st = &dndState{ExpiresAt: time.Now().Add(*duration), Snapshot: snap}
+			// persist before changing anything, so an interrupted run can still be restored
+			if !s.dryRun {
+				if err := s.saveDND(st); err != nil {
+					return err
+				}
+			}
+			projects, err := s.targetProjects(ctx)
+			if err != nil {
+				return err
+			}
+			s.email, s.inApp = disableAction, disableAction
+			s.skipUnchanged = true
+			if err := s.run(ctx, projects); err != nil {
+				return err
+			}
+			s.printf("do not disturb until %s\n", st.ExpiresAt.Local().Format(time.RFC1123))
+		}
+		if !*wait {
+			return nil
+		}
+		timer := time.NewTimer(time.Until(st.ExpiresAt))
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			slog.Info("stopped waiting, the next run restores preferences once do not disturb expires")
+			return nil
+		case <-timer.C:
+		}
+		s.showChanges = true
+		return s.endDND(ctx, st)
+	},
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/graphaelli/zube-notifications/store"
+	"github.com/graphaelli/zube-notifications/zubetest"
+)
+
+func TestDNDExpiry(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	p := srv.AddProject("proj-a")
+	w := srv.AddWorkspace(p.ID, "web")
+	state := store.New(filepath.Join(t.TempDir(), "state.db"))
+	ctx := context.Background()
+	newSweep := func(options ...option) *sweep {
+		s := newTestSweep(srv, options...)
+		s.store = state
+		s.scopes = map[string]bool{accountScope: true, projectScope: true, workspaceScope: true}
+		return s
+	}
+	objects := map[string]int{"accounts": zubetest.CurrentAccount.ID, "projects": p.ID, "workspaces": w.ID}
+	expect := func(enabled bool) {
+		t.Helper()
+		for object, id := range objects {
+			for _, prefType := range []string{zubetest.EmailPreferences, zubetest.InAppPreferences} {
+				if prefs := srv.Preferences(object, id, prefType); prefs["card_moved"] != enabled {
+					t.Errorf("%s %d %s card_moved is %v, want %t", object, id, prefType, prefs["card_moved"], enabled)
+				}
+			}
+		}
+	}
+
+	if err := dndCommand.run(ctx, newSweep(), []string{"-for", "1h"}); err != nil {
+		t.Fatal(err)
+	}
+	expect(false)
+
+	s := newSweep()
+	st, err := s.loadDND()
+	if err != nil || st == nil {
+		t.Fatalf("do not disturb state %v: %v", st, err)
+	}
+	st.ExpiresAt = time.Now().Add(-time.Minute)
+	if err := s.saveDND(st); err != nil {
+		t.Fatal(err)
+	}
+
+	// read-only runs leave the expired window alone
+	writes := len(srv.Writes())
+	if err := newSweep(ReadOnlyOption(true)).expireDND(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(srv.Writes()); got != writes {
+		t.Errorf("read-only run wrote %v", srv.Writes()[writes:])
+	}
+	if st, _ := s.loadDND(); st == nil {
+		t.Fatal("read-only run ended do not disturb")
+	}
+
+	if err := newSweep().expireDND(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expect(true)
+	if st, _ := s.loadDND(); st != nil {
+		t.Error("do not disturb still on after restoring")
+	}
+}
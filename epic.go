@@ -0,0 +1,9 @@
+package main
+
+import (
+	"context"
+)
+
+func (c *client) ListEpics(ctx context.Context, projectId int) ([]Epic, error) {
+	return paginate[Epic](ctx, c, apiPath("projects", projectId, "epics"), nil)
+}
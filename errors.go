@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// ErrNotFound is matched by errors.Is for 404 responses and for objects zube returns no data for,
+// like the preferences of a project the user was only just added to
+var ErrNotFound = errors.New("not found")
+
+// ErrReadOnly is returned for requests that would change anything through a client created with ReadOnlyOption
+var ErrReadOnly = errors.New("client is read-only")
+
+// ErrConflict is matched by errors.Is for 409 and 412 responses, zube refusing a write
+// because the object changed since it was read
+var ErrConflict = errors.New("changed since read")
+
+// APIError is a non-2xx response from the Zube api.
+// Use errors.As to inspect the status, e.g. to tell missing permissions from missing objects.
+type APIError struct {
+	StatusCode int
+	// Code is the machine readable error code from the response body, if any
+	Code      string
+	Message   string
+	RequestID string
+	Method    string
+	URL       string
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Code != "" {
+		msg += " (" + e.Code + ")"
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.RequestID != "" {
+		msg += " [request " + e.RequestID + "]"
+	}
+	return msg
+}
+
+// Is lets errors.Is match 401 responses against errUnauthorized, 404 responses against ErrNotFound
+// and 409 or 412 responses against ErrConflict
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case errUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict || e.StatusCode == http.StatusPreconditionFailed
+	}
+	return false
+}
+
+// hasStatus reports whether err is an APIError with status code
+func hasStatus(err error, code int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == code
+}
+
+// newAPIError reads and closes the body of a failed response
+func newAPIError(rsp *http.Response) *APIError {
+	defer closeBody(rsp)
+	e := &APIError{
+		StatusCode: rsp.StatusCode,
+		RequestID:  rsp.Header.Get("X-Request-Id"),
+	}
+	if rsp.Request != nil {
+		e.Method = rsp.Request.Method
+		e.URL = rsp.Request.URL.String()
+	}
+	b, _ := io.ReadAll(io.LimitReader(rsp.Body, 64<<10))
+	var body struct {
+		Code    interface{} `json:"code"`
+		Error   interface{} `json:"error"`
+		Message string      `json:"message"`
+	}
+	if err := json.Unmarshal(b, &body); err != nil {
+		// error pages from proxies and load balancers are often html
+		e.Message = snippet(b)
+		return e
+	}
+	if body.Code != nil {
+		e.Code = fmt.Sprint(body.Code)
+	}
+	e.Message = body.Message
+	if e.Message == "" && body.Error != nil {
+		e.Message = fmt.Sprint(body.Error)
+	}
+	return e
+}
+
+// maxSnippet bounds the length of response bodies quoted in errors
+const maxSnippet = 200
+
+var (
+	htmlTitle = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	htmlTag   = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// snippet summarizes a response body for an error message: the title of html pages,
+// otherwise the text with tags removed and whitespace collapsed, truncated to maxSnippet
+func snippet(b []byte) string {
+	text := string(b)
+	if m := htmlTitle.FindStringSubmatch(text); m != nil {
+		text = m[1]
+	} else {
+		text = htmlTag.ReplaceAllString(text, " ")
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if r := []rune(text); len(r) > maxSnippet {
+		text = string(r[:maxSnippet]) + "..."
+	}
+	return text
+}
+
+// decodeResponse decodes the json body of rsp into out, quoting the body when it isn't json
+func decodeResponse(rsp *http.Response, what string, out interface{}) error {
+	b, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return fmt.Errorf("while reading %s response: %w", what, err)
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return decodeError(rsp, what, err, b)
+	}
+	return nil
+}
+
+// decodeError describes a response that couldn't be decoded, quoting the start of its body
+func decodeError(rsp *http.Response, what string, err error, body []byte) error {
+	return fmt.Errorf("while decoding %s response (%d, %s): %w: %q", what, rsp.StatusCode, rsp.Header.Get("Content-Type"), err, snippet(body))
+}
+
+// headBuffer keeps the first limit bytes written to it, for quoting streamed bodies in errors
+type headBuffer struct {
+	limit int
+	b     []byte
+}
+
+func (h *headBuffer) Write(p []byte) (int, error) {
+	if n := h.limit - len(h.b); n > 0 {
+		h.b = append(h.b, p[:min(n, len(p))]...)
+	}
+	return len(p), nil
+}
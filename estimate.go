@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// estimateWindow is the span of recent activity notification volume is estimated from
+const estimateWindow = 30 * 24 * time.Hour
+
+// estimateCardLimit bounds the cards, and so comment lookups, an estimate reads per workspace,
+// busier workspaces are estimated from their most recently updated cards
+const estimateCardLimit = 200
+
+// activity counts the recent events of a workspace by the preference key that notifies about them
+type activity map[string]int
+
+// workspaceActivity counts card and comment events in the workspace since the given time.
+// Cards are read most recently updated first until one wasn't updated since, up to estimateCardLimit,
+// comments are only fetched for cards that have any.
+func (s *sweep) workspaceActivity(ctx context.Context, workspaceId int, since time.Time) (activity, error) {
+	var cards []Card
+	it := s.client.CardsIter(ctx, CardFilter{WorkspaceID: workspaceId, RecentlyUpdated: true})
+	for len(cards) < estimateCardLimit && it.Next() {
+		card := it.Item()
+		if !card.UpdatedAt.After(since) {
+			break
+		}
+		cards = append(cards, card)
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	a := make(activity)
+	recent := func(t *time.Time) bool { return t != nil && t.After(since) }
+	var (
+		mu                   sync.Mutex
+		commented, mentioned int
+	)
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(s.concurrency)
+	for _, card := range cards {
+		if recent(&card.CreatedAt) {
+			a["triage_card_opened"]++
+			if len(card.AssigneeIDs) > 0 {
+				a["card_assigned"]++
+			}
+		}
+		if recent(card.LastMovedAt) {
+			a["card_moved"]++
+		}
+		if recent(card.ClosedAt) {
+			a["card_closed"]++
+		}
+		if card.CommentsCount == 0 {
+			continue
+		}
+		cardId := card.ID
+		g.Go(func() error {
+			comments, err := s.client.ListCardComments(gctx, cardId)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, comment := range comments {
+				if !recent(&comment.CreatedAt) {
+					continue
+				}
+				commented++
+				if strings.Contains(comment.Body, "@") {
+					mentioned++
+				}
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	if commented > 0 {
+		a["card_commented"] += commented
+	}
+	if mentioned > 0 {
+		a["card_mentioned"] += mentioned
+	}
+	return a, nil
+}
+
+// weekly estimates notifications per week from a, counting each event once per preference set it's enabled in.
+// It's an upper bound: zube doesn't notify about your own actions or, below everything, cards you don't follow.
+func (a activity) weekly(prefs ...UserPreference) float64 {
+	var events int
+	for _, p := range prefs {
+		for k, n := range a {
+			if b, _ := p[k].(bool); b {
+				events += n
+			}
+		}
+	}
+	perWeek := float64(events) * float64(7*24*time.Hour) / float64(estimateWindow)
+	return math.Round(perWeek*10) / 10
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"sync"
+)
+
+// exit codes of the cli
+const (
+	exitOK         = 0 // success, nothing changed
+	exitFatal      = 1 // nothing could be done
+	exitPartial    = 2 // some projects, workspaces or accounts failed
+	exitChanged    = 3 // success, preferences changed or, with -dry-run, would change
+	exitViolations = 4 // check found policy violations, without failures
+)
+
+// outcome tallies what commands did across accounts to pick the exit code
+type outcome struct {
+	mu         sync.Mutex
+	changes    int
+	succeeded  int
+	violations int
+}
+
+// changed records a preference change, o may be nil
+func (o *outcome) changed() {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	o.changes++
+	o.succeeded++
+	o.mu.Unlock()
+}
+
+// violated records n policy violations found by check, o may be nil
+func (o *outcome) violated(n int) {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	o.violations += n
+	o.mu.Unlock()
+}
+
+// succeed records a project, workspace or write that completed without errors, o may be nil
+func (o *outcome) succeed() {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	o.succeeded++
+	o.mu.Unlock()
+}
+
+// exitCode is exitFatal or exitPartial when err is set, depending on whether anything succeeded,
+// then exitViolations when check found violations
+func (o *outcome) exitCode(err error) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	switch {
+	case err != nil && o.succeeded > 0:
+		return exitPartial
+	case err != nil:
+		return exitFatal
+	case o.violations > 0:
+		return exitViolations
+	case o.changes > 0:
+		return exitChanged
+	}
+	return exitOK
+}
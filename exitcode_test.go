@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/graphaelli/zube-notifications/zubetest"
+)
+
+func TestSweepPartialFailure(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	p := srv.AddProject("proj-a")
+	srv.AddWorkspace(p.ID, "web")
+	broken := srv.AddWorkspace(p.ID, "broken")
+
+	failing := func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if strings.Contains(req.URL.Path, apiPath("workspaces", broken.ID)) {
+				return &http.Response{StatusCode: http.StatusInternalServerError, Status: "500 Internal Server Error",
+					Header: http.Header{}, Body: io.NopCloser(strings.NewReader("{}")), Request: req}, nil
+			}
+			return next.RoundTrip(req)
+		})
+	}
+	s := newTestSweep(srv, MiddlewareOption(failing))
+	err := defaultCommand.run(context.Background(), s, nil)
+	if err == nil {
+		t.Fatal("expected the broken workspace to fail")
+	}
+	if got := s.outcome.exitCode(err); got != exitPartial {
+		t.Errorf("exit code %d, want %d", got, exitPartial)
+	}
+	if got := (&outcome{}).exitCode(err); got != exitFatal {
+		t.Errorf("exit code without successes %d, want %d", got, exitFatal)
+	}
+}
+
+func TestExitCodeViolations(t *testing.T) {
+	o := &outcome{}
+	o.violated(2)
+	if got := o.exitCode(nil); got != exitViolations {
+		t.Errorf("exit code %d, want %d", got, exitViolations)
+	}
+	o.succeed()
+	if got := o.exitCode(errors.New("failed")); got != exitPartial {
+		t.Errorf("exit code with failures %d, want %d", got, exitPartial)
+	}
+}
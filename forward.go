@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/graphaelli/zube-notifications/store"
+)
+
+// notificationSink delivers a notification somewhere outside of Zube
+type notificationSink interface {
+	Send(ctx context.Context, n Notification) error
+}
+
+const defaultSlackTemplate = `*{{.Title}}*{{if .Body}}
+{{.Body}}{{end}}{{if .URL}}
+<{{.URL}}|open in zube>{{end}}`
+
+// slackSink posts notifications to a slack incoming webhook
+type slackSink struct {
+	webhookURL string
+	tmpl       *template.Template
+	httpClient *http.Client
+}
+
+func newSlackSink(webhookURL, tmpl string, httpClient *http.Client) (*slackSink, error) {
+	if tmpl == "" {
+		tmpl = defaultSlackTemplate
+	}
+	t, err := template.New("slack").Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid slack template: %w", err)
+	}
+	return &slackSink{webhookURL: webhookURL, tmpl: t, httpClient: httpClient}, nil
+}
+
+func (s *slackSink) Send(ctx context.Context, n Notification) error {
+	text := new(bytes.Buffer)
+	if err := s.tmpl.Execute(text, n); err != nil {
+		return err
+	}
+	return s.post(ctx, text.String())
+}
+
+// post sends text as a slack message
+func (s *slackSink) post(ctx context.Context, text string) error {
+	payload, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent())
+	rsp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer closeBody(rsp)
+	if rsp.StatusCode/100 != 2 {
+		return fmt.Errorf("slack webhook responded %s", rsp.Status)
+	}
+	return nil
+}
+
+// forwardStateLimit bounds the number of delivered notification ids remembered
+const forwardStateLimit = 5000
+
+// forwardBucket holds delivered notification ids by client id
+const forwardBucket = "forward"
+
+// forwardState remembers delivered notifications so restarts don't deliver them again
+type forwardState struct {
+	store     *store.Store
+	key       string
+	Delivered map[int]time.Time `json:"delivered"`
+	// Sent holds the sinks that accepted a notification some other sink failed, so retries skip them
+	Sent map[int][]string `json:"sent,omitempty"`
+}
+
+// loadForwardState reads the state of clientId, a nil store keeps it in memory only
+func loadForwardState(st *store.Store, clientId string) (*forwardState, error) {
+	state := &forwardState{store: st, key: clientId}
+	if st != nil {
+		if _, err := st.Get(forwardBucket, clientId, state); err != nil {
+			return nil, err
+		}
+	}
+	if state.Delivered == nil {
+		state.Delivered = make(map[int]time.Time)
+	}
+	if state.Sent == nil {
+		state.Sent = make(map[int][]string)
+	}
+	return state, nil
+}
+
+// sinkKey names sink in the forward state, forward configures at most one sink of each kind
+func sinkKey(sink notificationSink) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", sink), "*main.")
+}
+
+func (st *forwardState) save() error {
+	if st.store == nil {
+		return nil
+	}
+	if len(st.Delivered) > forwardStateLimit {
+		ids := make([]int, 0, len(st.Delivered))
+		for id := range st.Delivered {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return st.Delivered[ids[i]].After(st.Delivered[ids[j]]) })
+		for _, id := range ids[forwardStateLimit:] {
+			delete(st.Delivered, id)
+		}
+	}
+	return st.store.Put(forwardBucket, st.key, st)
+}
+
+// forwarder delivers new notifications from the feed to every sink
+type forwarder struct {
+	client *client
+	sinks  []notificationSink
+	state  *forwardState
+	dryRun bool
+	// out receives the dry run report
+	out io.Writer
+}
+
+// poll delivers undelivered notifications, oldest first
+func (f *forwarder) poll(ctx context.Context) error {
+	notifications, err := f.client.ListNotifications(ctx, true)
+	if err != nil {
+		return err
+	}
+	sort.Slice(notifications, func(i, j int) bool { return notifications[i].CreatedAt.Before(notifications[j].CreatedAt) })
+	var errs []error
+	for _, n := range notifications {
+		if _, done := f.state.Delivered[n.ID]; done {
+			continue
+		}
+		if f.dryRun {
+			fmt.Fprintf(f.out, "[dry-run] forward notification %d: %s\n", n.ID, n.Title)
+			continue
+		}
+		var sinkErrs []error
+		for _, sink := range f.sinks {
+			key := sinkKey(sink)
+			if slices.Contains(f.state.Sent[n.ID], key) {
+				continue
+			}
+			if err := sink.Send(ctx, n); err != nil {
+				sinkErrs = append(sinkErrs, err)
+				continue
+			}
+			f.state.Sent[n.ID] = append(f.state.Sent[n.ID], key)
+		}
+		if len(sinkErrs) > 0 {
+			// retried on the next poll, by the failed sinks only
+			errs = append(errs, fmt.Errorf("notification %d: %w", n.ID, errors.Join(sinkErrs...)))
+			continue
+		}
+		delete(f.state.Sent, n.ID)
+		f.state.Delivered[n.ID] = time.Now()
+	}
+	if err := f.state.save(); err != nil {
+		errs = append(errs, fmt.Errorf("while saving forward state: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+var forwardCommand = &command{
+	name:  "forward",
+	usage: "forward [-slack-webhook <url>] [-webhook <url>] [-hook event=cmd] [-desktop] [-interval 1m] - forward new in-app notifications",
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		fs := flag.NewFlagSet("forward", flag.ExitOnError)
+		interval := fs.Duration("interval", time.Minute, "time between polls of the notification feed")
+		once := fs.Bool("once", false, "poll once and exit")
+		slackWebhook := fs.String("slack-webhook", os.Getenv("ZUBE_SLACK_WEBHOOK"), "slack incoming webhook url, defaults to ZUBE_SLACK_WEBHOOK")
+		slackTemplate := fs.String("slack-template", "", "text/template for slack messages, fields of Notification are available")
+		webhookURL := fs.String("webhook", "", "url to post notifications to as json")
+		webhookSecret := fs.String("webhook-secret", os.Getenv("ZUBE_WEBHOOK_SECRET"), "secret for the "+signatureHeader+" HMAC-SHA256 header, defaults to ZUBE_WEBHOOK_SECRET")
+		webhookHeaders := headerFlags{}
+		fs.Var(webhookHeaders, "webhook-header", `extra "Name: value" header for webhook requests, repeatable`)
+		metricsAddr := fs.String("metrics-addr", "", "address to serve prometheus /metrics on, e.g. :9090")
+		hooks := hookFlags{}
+		fs.Var(hooks, "hook", `"event=command" run with the event json on stdin, "*" matches every event, repeatable`)
+		hookTimeout := fs.Duration("hook-timeout", 30*time.Second, "time a hook command may run before it is killed")
+		hookConcurrency := fs.Int("hook-concurrency", 4, "maximum hook commands running at once")
+		desktop := fs.Bool("desktop", false, "show native desktop notifications")
+		desktopProjects := fs.String("desktop-projects", "", "comma separated project ids, names or slugs to show desktop notifications for, all by default")
+		fs.Parse(args)
+
+		f := &forwarder{client: s.client, dryRun: s.dryRun, out: s.writer()}
+		if *slackWebhook != "" {
+			sink, err := newSlackSink(*slackWebhook, *slackTemplate, s.client.transport.externalClient())
+			if err != nil {
+				return err
+			}
+			f.sinks = append(f.sinks, sink)
+		}
+		if *webhookURL != "" {
+			f.sinks = append(f.sinks, &webhookSink{
+				url:        *webhookURL,
+				header:     http.Header(webhookHeaders),
+				secret:     []byte(*webhookSecret),
+				httpClient: s.client.transport.externalClient(),
+			})
+		}
+		if len(hooks) > 0 {
+			f.sinks = append(f.sinks, newShellHook(hooks, *hookTimeout, *hookConcurrency))
+		}
+		if *desktop {
+			d, err := newDesktopSink(ctx, *desktopProjects, s.client.ResolveProject)
+			if err != nil {
+				return err
+			}
+			f.sinks = append(f.sinks, d)
+		}
+		if len(f.sinks) == 0 {
+			return errors.New("forward requires at least one sink, -slack-webhook, -webhook, -hook or -desktop")
+		}
+		var err error
+		if f.state, err = loadForwardState(s.store, s.client.clientId); err != nil {
+			return err
+		}
+		if *once {
+			return f.poll(ctx)
+		}
+		if err := s.serveMetrics(ctx, *metricsAddr); err != nil {
+			return err
+		}
+		slog.Info("forwarding notifications", "sinks", len(f.sinks), "interval", *interval)
+		return s.watch(ctx, *interval, f.poll)
+	},
+}
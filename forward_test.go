@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/graphaelli/zube-notifications/zubetest"
+)
+
+func TestForward(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	n := srv.AddNotification(zubetest.Notification{Event: "card_moved", Title: "moved"})
+	var signatures []string
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(signatureHeader) != "sha256="+sign([]byte("shh"), body) {
+			t.Errorf("signature %q", r.Header.Get(signatureHeader))
+		}
+		signatures = append(signatures, r.Header.Get(signatureHeader))
+	}))
+	defer hook.Close()
+	t.Setenv("ZUBE_WEBHOOK_SECRET", "shh")
+	ctx := context.Background()
+	forward := func(dryRun bool) string {
+		t.Helper()
+		var out bytes.Buffer
+		s := newTestSweep(srv)
+		s.out = &out
+		s.dryRun = dryRun
+		if err := forwardCommand.run(ctx, s, []string{"-once", "-webhook", hook.URL}); err != nil {
+			t.Fatal(err)
+		}
+		return out.String()
+	}
+
+	if out := forward(true); !strings.Contains(out, "[dry-run] forward notification") || !strings.Contains(out, n.Title) {
+		t.Errorf("dry run printed %q", out)
+	}
+	if len(signatures) != 0 {
+		t.Fatalf("dry run delivered %d notifications", len(signatures))
+	}
+	forward(false)
+	if len(signatures) != 1 {
+		t.Errorf("delivered %d notifications, want 1", len(signatures))
+	}
+}
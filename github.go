@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// githubClient changes the watch settings of the GitHub repositories synced to Zube projects
+type githubClient struct {
+	token      string
+	baseURL    string
+	httpClient *http.Client
+}
+
+func newGitHubClient(token, baseURL string, httpClient *http.Client) *githubClient {
+	return &githubClient{token: token, baseURL: strings.TrimSuffix(baseURL, "/"), httpClient: httpClient}
+}
+
+// repoSubscription is how the authenticated user watches a repository
+type repoSubscription struct {
+	Subscribed bool `json:"subscribed"`
+	Ignored    bool `json:"ignored"`
+}
+
+func (s *repoSubscription) String() string {
+	switch {
+	case s == nil:
+		return "participating"
+	case s.Ignored:
+		return "ignoring"
+	case s.Subscribed:
+		return "watching"
+	}
+	return "participating"
+}
+
+func (g *githubClient) do(ctx context.Context, method, repo string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, g.baseURL+"/repos/"+repo+"/subscription", body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+g.token)
+	req.Header.Set("User-Agent", userAgent())
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	rsp, err := g.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer closeBody(rsp)
+	if rsp.StatusCode/100 != 2 {
+		return newAPIError(rsp)
+	}
+	if out == nil {
+		return nil
+	}
+	return decodeResponse(rsp, "github subscription", out)
+}
+
+// subscription returns how repo is watched, nil when the user only gets participating notifications
+func (g *githubClient) subscription(ctx context.Context, repo string) (*repoSubscription, error) {
+	var sub repoSubscription
+	if err := g.do(ctx, http.MethodGet, repo, nil, &sub); errors.Is(err, ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// setSubscription watches or ignores repo, a nil sub reverts to participating notifications
+func (g *githubClient) setSubscription(ctx context.Context, repo string, sub *repoSubscription) error {
+	if sub == nil {
+		return g.do(ctx, http.MethodDelete, repo, nil, nil)
+	}
+	return g.do(ctx, http.MethodPut, repo, sub, nil)
+}
+
+// githubSubscription maps a zube subscription level to a repository subscription:
+// mute ignores the repository, everything watches it, anything else is participating
+func githubSubscription(level string) *repoSubscription {
+	switch level {
+	case "mute":
+		return &repoSubscription{Ignored: true}
+	case "everything":
+		return &repoSubscription{Subscribed: true}
+	}
+	return nil
+}
+
+// subscribeGitHub applies level to the repositories synced to the project
+func (s *sweep) subscribeGitHub(ctx context.Context, g *githubClient, projectId int, level string) error {
+	sources, err := s.client.ListSources(ctx, projectId)
+	if err != nil {
+		return err
+	}
+	if len(sources) == 0 {
+		s.printf("\tno github repositories synced\n")
+		return nil
+	}
+	desired := githubSubscription(level)
+	var errs []error
+	for _, source := range sources {
+		current, err := g.subscription(ctx, source.FullName)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("github %s: %w", source.FullName, err))
+			continue
+		}
+		if current.String() == desired.String() {
+			s.printf("\tgithub %s: already %s\n", source.FullName, current)
+			continue
+		}
+		prefix := ""
+		if s.dryRun {
+			prefix = "[dry-run] "
+		}
+		s.printf("\t%sgithub %s: %s -> %s\n", prefix, source.FullName, current, desired)
+		if s.dryRun {
+			continue
+		}
+		if s.client.readOnly {
+			errs = append(errs, fmt.Errorf("github %s: %w", source.FullName, ErrReadOnly))
+			continue
+		}
+		if err := g.setSubscription(ctx, source.FullName, desired); err != nil {
+			errs = append(errs, fmt.Errorf("github %s: %w", source.FullName, err))
+		}
+	}
+	return errors.Join(errs...)
+}
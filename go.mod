@@ -1,5 +1,22 @@
 module github.com/graphaelli/zube-notifications
 
-go 1.13
+go 1.21
 
-require github.com/dgrijalva/jwt-go v3.2.0+incompatible
+require (
+	github.com/golang-jwt/jwt/v5 v5.3.1
+	github.com/youmark/pkcs8 v0.0.0-20240726163527-a2c0da244d78
+	github.com/zalando/go-keyring v0.2.3
+	go.etcd.io/bbolt v1.3.10
+	golang.org/x/crypto v0.23.0
+	golang.org/x/sync v0.7.0
+	golang.org/x/term v0.20.0
+	golang.org/x/time v0.5.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/alessio/shellescape v1.4.1 // indirect
+	github.com/danieljoos/wincred v1.2.0 // indirect
+	github.com/godbus/dbus/v5 v5.1.0 // indirect
+	golang.org/x/sys v0.20.0 // indirect
+)
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// workspaceGroup is a set of workspaces named in the config's groups, targeted with -group
+type workspaceGroup struct {
+	name    string
+	members []groupMember
+}
+
+// groupMember references <project>/<workspace>, each a name, slug or pattern.
+// A bare <project> includes all of its workspaces and its own preferences.
+type groupMember struct {
+	project, workspace string
+}
+
+// group resolves comma separated group names into one group of all their members
+func (c *config) group(names string) (*workspaceGroup, error) {
+	g := &workspaceGroup{name: names}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		refs, ok := c.Groups[name]
+		if !ok {
+			return nil, fmt.Errorf("no group %q in config", name)
+		}
+		for _, ref := range refs {
+			project, workspace, _ := strings.Cut(ref, "/")
+			if project == "" {
+				return nil, fmt.Errorf("group %s: %q must be <project>[/<workspace>]", name, ref)
+			}
+			for _, pattern := range []string{project, workspace} {
+				if _, err := path.Match(pattern, ""); err != nil {
+					return nil, fmt.Errorf("group %s: invalid pattern %q: %w", name, ref, err)
+				}
+			}
+			g.members = append(g.members, groupMember{project: project, workspace: workspace})
+		}
+	}
+	return g, nil
+}
+
+// hasProject reports whether any member is in project, a nil group has every project
+func (g *workspaceGroup) hasProject(project Project) bool {
+	if g == nil {
+		return true
+	}
+	for _, m := range g.members {
+		if matchName(m.project, project.Name, project.Slug) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasWholeProject reports whether a bare <project> member includes all of project, making its own
+// preferences part of the group, a nil group has every project
+func (g *workspaceGroup) hasWholeProject(project Project) bool {
+	if g == nil {
+		return true
+	}
+	for _, m := range g.members {
+		if m.workspace == "" && matchName(m.project, project.Name, project.Slug) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasWorkspace reports whether a member matches workspace of project, a nil group has every workspace
+func (g *workspaceGroup) hasWorkspace(project Project, workspace Workspace) bool {
+	if g == nil {
+		return true
+	}
+	for _, m := range g.members {
+		if matchName(m.project, project.Name, project.Slug) && matchName(m.workspace, workspace.Name, workspace.Slug) {
+			return true
+		}
+	}
+	return false
+}
+
+// targetProjects lists the projects commands act on, only those of -group when set
+func (s *sweep) targetProjects(ctx context.Context) ([]Project, error) {
+	projects, err := s.client.ListProjects(ctx)
+	if err != nil || s.group == nil {
+		return projects, err
+	}
+	var targets []Project
+	for _, p := range projects {
+		if s.group.hasProject(p) {
+			targets = append(targets, p)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("group %s matches none of the projects", s.group.name)
+	}
+	return targets, nil
+}
+
+// groupWorkspaces drops the workspaces of project outside -group
+func (s *sweep) groupWorkspaces(project Project, workspaces []Workspace) []Workspace {
+	if s.group == nil {
+		return workspaces
+	}
+	var members []Workspace
+	for _, w := range workspaces {
+		if s.group.hasWorkspace(project, w) {
+			members = append(members, w)
+		}
+	}
+	return members
+}
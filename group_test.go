@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/graphaelli/zube-notifications/zubetest"
+)
+
+func TestGroupTargetsWorkspaces(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	a := srv.AddProject("proj-a")
+	web := srv.AddWorkspace(a.ID, "web")
+	api := srv.AddWorkspace(a.ID, "api")
+	b := srv.AddProject("proj-b")
+	other := srv.AddWorkspace(b.ID, "web")
+
+	cfg := &config{Groups: map[string][]string{"frontend": {"proj-a/web"}}}
+	g, err := cfg.group("frontend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestSweep(srv)
+	s.group = g
+	s.email = disableAction
+	if err := defaultCommand.run(context.Background(), s, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if email := srv.Preferences("workspaces", web.ID, zubetest.EmailPreferences); email["card_moved"] != false {
+		t.Errorf("group workspace not changed: %v", email)
+	}
+	for _, untouched := range []struct {
+		object string
+		id     int
+	}{
+		{"projects", a.ID},
+		{"workspaces", api.ID},
+		{"projects", b.ID},
+		{"workspaces", other.ID},
+	} {
+		if email := srv.Preferences(untouched.object, untouched.id, zubetest.EmailPreferences); email["card_moved"] != true {
+			t.Errorf("%s %d outside the group changed: %v", untouched.object, untouched.id, email)
+		}
+	}
+}
+
+func TestGroupWholeProject(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	a := srv.AddProject("proj-a")
+	web := srv.AddWorkspace(a.ID, "web")
+
+	cfg := &config{Groups: map[string][]string{"all-a": {"proj-*"}}}
+	g, err := cfg.group("all-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestSweep(srv)
+	s.group = g
+	s.email = disableAction
+	if err := defaultCommand.run(context.Background(), s, nil); err != nil {
+		t.Fatal(err)
+	}
+	for object, id := range map[string]int{"projects": a.ID, "workspaces": web.ID} {
+		if email := srv.Preferences(object, id, zubetest.EmailPreferences); email["card_moved"] != false {
+			t.Errorf("%s %d not changed: %v", object, id, email)
+		}
+	}
+}
+
+func TestGroupErrors(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	srv.AddProject("proj-a")
+	cfg := &config{Groups: map[string][]string{
+		"empty":   {},
+		"missing": {"proj-z"},
+		"bad":     {"/web"},
+	}}
+
+	if _, err := cfg.group("nope"); err == nil {
+		t.Error("expected an error for an unknown group")
+	}
+	if _, err := cfg.group("bad"); err == nil {
+		t.Error("expected an error for a member without a project")
+	}
+	for _, name := range []string{"empty", "missing"} {
+		g, err := cfg.group(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := newTestSweep(srv)
+		s.group = g
+		if _, err := s.targetProjects(context.Background()); err == nil {
+			t.Errorf("expected group %s to match no projects", name)
+		}
+	}
+}
+
+// partialGroupSweep returns a sweep of srv limited to the web workspace of proj-a
+func partialGroupSweep(t *testing.T, srv *zubetest.Server) *sweep {
+	t.Helper()
+	cfg := &config{Groups: map[string][]string{"frontend": {"proj-a/web"}}}
+	g, err := cfg.group("frontend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestSweep(srv)
+	s.group = g
+	return s
+}
+
+func TestGroupCheck(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	a := srv.AddProject("proj-a")
+	srv.AddWorkspace(a.ID, "web")
+	srv.AddWorkspace(a.ID, "api")
+
+	s := partialGroupSweep(t, srv)
+	projects, err := s.targetProjects(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := &checkPolicy{Rules: []checkRule{
+		{Name: "quiet projects", Project: "*", Email: map[string]bool{"*": false}},
+		{Name: "quiet workspaces", Project: "*", Workspace: "*", Email: map[string]bool{"*": false}},
+	}}
+	violations, err := s.check(context.Background(), policy, projects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) == 0 {
+		t.Fatal("expected violations for the web workspace")
+	}
+	for _, v := range violations {
+		if v.Name != "proj-a/web" {
+			t.Errorf("violation outside the group: %+v", v)
+		}
+	}
+}
+
+func TestGroupSnapshot(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	a := srv.AddProject("proj-a")
+	srv.AddWorkspace(a.ID, "web")
+	srv.AddWorkspace(a.ID, "api")
+
+	snap, err := partialGroupSweep(t, srv).snapshot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.Projects) != 1 {
+		t.Fatalf("%d projects, want 1", len(snap.Projects))
+	}
+	ps := snap.Projects[0]
+	if ps.Email != nil || ps.InApp != nil || ps.SubscriptionLevel != "" || ps.TriageSubscriptionLevel != "" {
+		t.Errorf("captured preferences of a project the group only partly covers: %+v", ps)
+	}
+	if len(ps.Workspaces) != 1 || ps.Workspaces[0].Name != "web" || ps.Workspaces[0].Email == nil {
+		t.Errorf("workspaces %+v, want web", ps.Workspaces)
+	}
+}
+
+func TestGroupOnboarding(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	a := srv.AddProject("proj-a")
+	ctx := context.Background()
+
+	s := partialGroupSweep(t, srv)
+	onboard := s.onboarding(allProfile("mute-all", false), func(context.Context) error { return nil })
+	// the first run only remembers existing workspaces
+	if err := onboard(ctx); err != nil {
+		t.Fatal(err)
+	}
+	web := srv.AddWorkspace(a.ID, "web")
+	api := srv.AddWorkspace(a.ID, "api")
+	if err := onboard(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if email := srv.Preferences("workspaces", web.ID, zubetest.EmailPreferences); email["card_moved"] != false {
+		t.Errorf("web not onboarded: %v", email)
+	}
+	if email := srv.Preferences("workspaces", api.ID, zubetest.EmailPreferences); email["card_moved"] != true {
+		t.Errorf("api outside the group onboarded: %v", email)
+	}
+	if email := srv.Preferences("projects", a.ID, zubetest.EmailPreferences); email["card_moved"] != true {
+		t.Errorf("project preferences changed: %v", email)
+	}
+}
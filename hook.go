@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// hookFlags collects repeated "event=command" flags, the event "*" matches every event
+type hookFlags map[string][]string
+
+func (h hookFlags) String() string {
+	var parts []string
+	for event, commands := range h {
+		for _, c := range commands {
+			parts = append(parts, event+"="+c)
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+func (h hookFlags) Set(s string) error {
+	event, command, ok := strings.Cut(s, "=")
+	if !ok || strings.TrimSpace(event) == "" || strings.TrimSpace(command) == "" {
+		return fmt.Errorf(`expected "event=command", got %q`, s)
+	}
+	event = strings.TrimSpace(event)
+	h[event] = append(h[event], command)
+	return nil
+}
+
+// shellHook runs shell commands for events with the event json on stdin.
+// It is both an eventHandler for serve and a notificationSink for forward.
+type shellHook struct {
+	hooks   hookFlags
+	timeout time.Duration
+	// slots bounds the number of commands running at once
+	slots chan struct{}
+}
+
+func newShellHook(hooks hookFlags, timeout time.Duration, concurrency int) *shellHook {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &shellHook{hooks: hooks, timeout: timeout, slots: make(chan struct{}, concurrency)}
+}
+
+func (h *shellHook) Handle(ctx context.Context, e Event) error {
+	env := []string{
+		"ZUBE_EVENT=" + e.Type,
+		"ZUBE_EVENT_ID=" + e.ID,
+		"ZUBE_PROJECT=" + strconv.Itoa(e.ProjectID),
+		"ZUBE_WORKSPACE=" + strconv.Itoa(e.WorkspaceID),
+	}
+	if e.Card != nil {
+		env = append(env, "ZUBE_CARD_ID="+strconv.Itoa(e.Card.ID))
+	}
+	return h.run(ctx, e.Type, e.Raw, env)
+}
+
+func (h *shellHook) Send(ctx context.Context, n Notification) error {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	return h.run(ctx, n.Event, payload, []string{
+		"ZUBE_EVENT=" + n.Event,
+		"ZUBE_NOTIFICATION_ID=" + strconv.Itoa(n.ID),
+		"ZUBE_PROJECT=" + strconv.Itoa(n.ProjectID),
+		"ZUBE_WORKSPACE=" + strconv.Itoa(n.WorkspaceID),
+		"ZUBE_CARD_ID=" + strconv.Itoa(n.CardID),
+	})
+}
+
+// run executes the commands configured for event and for "*", in that order
+func (h *shellHook) run(ctx context.Context, event string, payload []byte, env []string) error {
+	var errs []error
+	for _, command := range append(append([]string(nil), h.hooks[event]...), h.hooks["*"]...) {
+		if err := h.exec(ctx, command, payload, env); err != nil {
+			errs = append(errs, fmt.Errorf("hook %q for %s: %w", command, event, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *shellHook) exec(ctx context.Context, command string, payload []byte, env []string) error {
+	select {
+	case h.slots <- struct{}{}:
+		defer func() { <-h.slots }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(os.Environ(), env...)
+	// don't wait on children of a killed shell still holding its output open
+	cmd.WaitDelay = time.Second
+	out, err := cmd.CombinedOutput()
+	slog.Debug("ran hook", "command", command, "output", string(out), "err", err)
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", h.timeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ListNotifications fetches the user's in-app notification feed, newest first
+func (c *client) ListNotifications(ctx context.Context, unreadOnly bool) ([]Notification, error) {
+	return paginate[Notification](ctx, c, "notifications", notificationsQuery(unreadOnly))
+}
+
+// NotificationsIter iterates over the user's in-app notification feed, a page at a time
+func (c *client) NotificationsIter(ctx context.Context, unreadOnly bool) *Iterator[Notification] {
+	return iterate[Notification](ctx, c, "notifications", notificationsQuery(unreadOnly))
+}
+
+// ListNotificationsSince fetches the notifications created after since, newest first,
+// reading pages only until one is older
+func (c *client) ListNotificationsSince(ctx context.Context, unreadOnly bool, since time.Time) ([]Notification, error) {
+	q := notificationsQuery(unreadOnly)
+	q.Set("order[by]", "created_at")
+	q.Set("order[direction]", "desc")
+	var notifications []Notification
+	it := iterate[Notification](ctx, c, "notifications", q)
+	for it.Next() {
+		n := it.Item()
+		if !n.CreatedAt.After(since) {
+			break
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, it.Err()
+}
+
+func notificationsQuery(unreadOnly bool) url.Values {
+	q := url.Values{}
+	if unreadOnly {
+		q.Set("where[read]", "false")
+	}
+	return q
+}
+
+func (c *client) MarkNotificationRead(ctx context.Context, notificationId int) error {
+	return c.call(ctx, http.MethodPut, apiPath("notifications", notificationId), nil, map[string]bool{"read": true}, nil)
+}
+
+func (c *client) MarkAllNotificationsRead(ctx context.Context) error {
+	return c.call(ctx, http.MethodPut, apiPath("notifications", "mark_all_read"), nil, nil, nil)
+}
+
+var inboxCommand = &command{
+	name:  "inbox",
+	usage: "inbox [-all] [list | read <id>... | read-all] - list or mark read in-app notifications",
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		fs := flag.NewFlagSet("inbox", flag.ExitOnError)
+		all := fs.Bool("all", false, "list read notifications too")
+		fs.Parse(args)
+
+		switch sub := fs.Arg(0); sub {
+		case "", "list":
+			notifications, err := s.client.ListNotifications(ctx, !*all)
+			if err != nil {
+				return err
+			}
+			for _, n := range notifications {
+				status := "unread"
+				if n.Read {
+					status = "read"
+				}
+				s.printf("%d\t%s\t%s\t%s\t%s\n", n.ID, n.CreatedAt.Local().Format("2006-01-02 15:04"), status, n.Event, n.Title)
+			}
+			return nil
+		case "read":
+			if fs.NArg() < 2 {
+				return errors.New("inbox read requires notification ids")
+			}
+			var errs []error
+			for _, arg := range fs.Args()[1:] {
+				id, err := strconv.Atoi(arg)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("invalid notification id %q", arg))
+					continue
+				}
+				if s.dryRun {
+					s.printf("[dry-run] notification %d: mark read\n", id)
+					continue
+				}
+				if err := s.client.MarkNotificationRead(ctx, id); err != nil {
+					errs = append(errs, fmt.Errorf("notification %d: %w", id, err))
+				}
+			}
+			return errors.Join(errs...)
+		case "read-all":
+			if s.dryRun {
+				s.printf("[dry-run] mark all notifications read\n")
+				return nil
+			}
+			return s.client.MarkAllNotificationsRead(ctx)
+		default:
+			return fmt.Errorf("unknown inbox command %q", sub)
+		}
+	},
+}
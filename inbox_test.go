@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/graphaelli/zube-notifications/zubetest"
+)
+
+func TestInbox(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	now := time.Now().UTC()
+	older := srv.AddNotification(zubetest.Notification{Event: "card_moved", Title: "older", CreatedAt: now.Add(-time.Hour)})
+	newer := srv.AddNotification(zubetest.Notification{Event: "card_commented", Title: "newer", CreatedAt: now})
+	srv.AddNotification(zubetest.Notification{Event: "card_moved", Title: "seen", Read: true})
+	ctx := context.Background()
+	inbox := func(args ...string) string {
+		t.Helper()
+		var out bytes.Buffer
+		s := newTestSweep(srv)
+		s.out = &out
+		s.dryRun = len(args) > 0 && args[0] == "-dry-run"
+		if s.dryRun {
+			args = args[1:]
+		}
+		if err := inboxCommand.run(ctx, s, args); err != nil {
+			t.Fatal(err)
+		}
+		return out.String()
+	}
+
+	out := inbox()
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "\tnewer") || !strings.HasSuffix(lines[1], "\tolder") {
+		t.Errorf("unread notifications listed as %q", out)
+	}
+	if out := inbox("-all"); strings.Count(out, "\n") != 3 {
+		t.Errorf("all notifications listed as %q", out)
+	}
+
+	if out := inbox("-dry-run", "read", strconv.Itoa(older.ID)); !strings.Contains(out, "[dry-run] notification") {
+		t.Errorf("dry run printed %q", out)
+	}
+	if out := inbox("-dry-run", "read-all"); !strings.Contains(out, "[dry-run] mark all") {
+		t.Errorf("dry run printed %q", out)
+	}
+	if writes := srv.Writes(); len(writes) > 0 {
+		t.Errorf("dry runs wrote %v", writes)
+	}
+
+	inbox("read", strconv.Itoa(older.ID))
+	if n, _ := srv.Notification(older.ID); !n.Read {
+		t.Error("notification not marked read")
+	}
+	if n, _ := srv.Notification(newer.ID); n.Read {
+		t.Error("other notification marked read")
+	}
+	inbox("read-all")
+	if out := inbox(); out != "" {
+		t.Errorf("unread notifications after read-all: %q", out)
+	}
+}
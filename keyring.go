@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/zalando/go-keyring"
+)
+
+// keyringService names the os keyring entries owned by this tool
+const keyringService = "zube-notifications"
+
+func keyringKeyUser(clientId string) string   { return clientId + ":key" }
+func keyringTokenUser(clientId string) string { return clientId + ":token" }
+
+// keyringKey reads the private key stored for clientId by "keyring import"
+func keyringKey(clientId string) (crypto.Signer, error) {
+	pem, err := keyring.Get(keyringService, keyringKeyUser(clientId))
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil, fmt.Errorf("no key for client %s in the os keyring, run the keyring import command", clientId)
+	} else if err != nil {
+		return nil, fmt.Errorf("while reading key from os keyring: %w", err)
+	}
+	return parseKey([]byte(pem), "keyring key of client "+clientId)
+}
+
+// keyringTokenCache stores access tokens in the os keyring
+type keyringTokenCache struct{}
+
+// KeyringTokenCacheOption persists access tokens in the os keyring instead of a file
+func KeyringTokenCacheOption() option {
+	return func(c *client) {
+		c.tokenCache = keyringTokenCache{}
+	}
+}
+
+func (keyringTokenCache) load(clientId string) (cachedToken, bool) {
+	var token cachedToken
+	v, err := keyring.Get(keyringService, keyringTokenUser(clientId))
+	if err != nil || json.Unmarshal([]byte(v), &token) != nil {
+		return cachedToken{}, false
+	}
+	if token.AccessToken == "" || !token.Expiry.After(time.Now()) {
+		return cachedToken{}, false
+	}
+	return token, true
+}
+
+func (keyringTokenCache) store(clientId string, token cachedToken) error {
+	b, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	return keyring.Set(keyringService, keyringTokenUser(clientId), string(b))
+}
+
+var keyringCommand = &command{
+	name:    "keyring",
+	usage:   "keyring import <pem> | delete - manage the private key stored in the os keyring",
+	offline: true,
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		clientId := s.client.clientId
+		if len(args) == 0 {
+			return errors.New("keyring requires import or delete")
+		}
+		switch args[0] {
+		case "import":
+			if len(args) != 2 {
+				return errors.New("keyring import requires a pem file")
+			}
+			pem, err := os.ReadFile(args[1])
+			if err != nil {
+				return err
+			}
+			// refuse to store something that won't work later
+			if _, err := parseKey(pem, args[1]); err != nil {
+				return fmt.Errorf("while parsing %s: %w", args[1], err)
+			}
+			if err := keyring.Set(keyringService, keyringKeyUser(clientId), string(pem)); err != nil {
+				return err
+			}
+			fmt.Printf("stored key for client %s, %s can now be deleted\n", clientId, args[1])
+			return nil
+		case "delete":
+			var errs []error
+			for _, user := range []string{keyringKeyUser(clientId), keyringTokenUser(clientId)} {
+				if err := keyring.Delete(keyringService, user); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+					errs = append(errs, err)
+				}
+			}
+			return errors.Join(errs...)
+		}
+		return fmt.Errorf("unknown keyring command %q", args[0])
+	},
+}
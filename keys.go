@@ -0,0 +1,109 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/youmark/pkcs8"
+	"golang.org/x/term"
+)
+
+// keyPassphraseEnv holds the passphrase of encrypted private keys, avoiding the terminal prompt
+const keyPassphraseEnv = "ZUBE_KEY_PASSPHRASE"
+
+// loadKeys reads private keys from pem files, most recently modified first
+func loadKeys(paths []string) ([]crypto.Signer, error) {
+	type keyFile struct {
+		key     crypto.Signer
+		modTime int64
+	}
+	var files []keyFile
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := parseKey(b, path)
+		if err != nil {
+			return nil, fmt.Errorf("while parsing %s: %w", path, err)
+		}
+		files = append(files, keyFile{key: key, modTime: info.ModTime().UnixNano()})
+	}
+	sort.SliceStable(files, func(i, j int) bool { return files[i].modTime > files[j].modTime })
+	keys := make([]crypto.Signer, len(files))
+	for i, f := range files {
+		keys[i] = f.key
+	}
+	return keys, nil
+}
+
+// parseKey decodes an RSA, ECDSA or Ed25519 private key, decrypting passphrase protected PKCS#8 keys.
+// The passphrase is read from ZUBE_KEY_PASSPHRASE or prompted for on the terminal.
+func parseKey(b []byte, name string) (crypto.Signer, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("no pem encoded private key found")
+	}
+	var (
+		key interface{}
+		err error
+	)
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "ENCRYPTED PRIVATE KEY":
+		passphrase, perr := keyPassphrase(name)
+		if perr != nil {
+			return nil, perr
+		}
+		if key, err = pkcs8.ParsePKCS8PrivateKey(block.Bytes, passphrase); err != nil {
+			return nil, fmt.Errorf("while decrypting key: %w", err)
+		}
+	case "OPENSSH PRIVATE KEY":
+		return nil, errors.New("openssh keys aren't supported, convert with ssh-keygen -p -m pkcs8 or use -signer ssh-agent")
+	default:
+		return nil, fmt.Errorf("unsupported pem block %q, expected a private key", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		return k.(crypto.Signer), nil
+	}
+	return nil, fmt.Errorf("unsupported %T private key, expected rsa, ecdsa or ed25519", key)
+}
+
+func keyPassphrase(name string) ([]byte, error) {
+	if p, ok := os.LookupEnv(keyPassphraseEnv); ok {
+		return []byte(p), nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, errors.New("key is encrypted, set " + keyPassphraseEnv + " or run from a terminal")
+	}
+	fmt.Fprintf(os.Stderr, "passphrase for %s: ", name)
+	p, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("while reading passphrase: %w", err)
+	}
+	return p, nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// signRequest sends a json signing request with httpClient, decoding the response into out
+func signRequest(httpClient *http.Client, req *http.Request, what string, out interface{}) error {
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("User-Agent", userAgent())
+	rsp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("while signing with %s: %w", what, err)
+	}
+	defer closeBody(rsp)
+	if rsp.StatusCode/100 != 2 {
+		return fmt.Errorf("while signing with %s: %w", what, newAPIError(rsp))
+	}
+	return decodeResponse(rsp, what+" signature", out)
+}
+
+func jsonBody(v interface{}) (*bytes.Reader, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(b), nil
+}
+
+// vaultSigner signs with a Vault transit key
+type vaultSigner struct {
+	addr, token, namespace string
+	mount, key             string
+	httpClient             *http.Client
+}
+
+func newVaultSigner(ref string, httpClient *http.Client) (Signer, error) {
+	i := strings.LastIndex(ref, "/")
+	if i <= 0 || i == len(ref)-1 {
+		return nil, fmt.Errorf("vault signer %q must be <mount>/<key>, e.g. vault:transit/zube", ref)
+	}
+	s := &vaultSigner{
+		addr:       strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/"),
+		token:      os.Getenv("VAULT_TOKEN"),
+		namespace:  os.Getenv("VAULT_NAMESPACE"),
+		mount:      ref[:i],
+		key:        ref[i+1:],
+		httpClient: httpClient,
+	}
+	if s.addr == "" || s.token == "" {
+		return nil, errors.New("vault signer requires VAULT_ADDR and VAULT_TOKEN")
+	}
+	return s, nil
+}
+
+func (*vaultSigner) Algorithm() string { return "RS256" }
+
+func (s *vaultSigner) String() string { return "vault transit key " + s.mount + "/" + s.key }
+
+func (s *vaultSigner) Sign(ctx context.Context, signingInput []byte) ([]byte, error) {
+	body, err := jsonBody(map[string]string{
+		"input":               base64.StdEncoding.EncodeToString(signingInput),
+		"signature_algorithm": "pkcs1v15",
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/v1/%s/sign/%s/sha2-256", s.addr, s.mount, s.key), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", s.token)
+	if s.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", s.namespace)
+	}
+	var rsp struct {
+		Data struct {
+			Signature string `json:"signature"`
+		} `json:"data"`
+	}
+	if err := signRequest(s.httpClient, req, "vault", &rsp); err != nil {
+		return nil, err
+	}
+	// signatures are prefixed with the key version, e.g. vault:v1:<base64>
+	parts := strings.Split(rsp.Data.Signature, ":")
+	return base64.StdEncoding.DecodeString(parts[len(parts)-1])
+}
+
+// awsKMSSigner signs with an AWS KMS asymmetric RSA key
+type awsKMSSigner struct {
+	keyId      string
+	creds      awsCredentials
+	httpClient *http.Client
+}
+
+func newAWSKMSSigner(keyId string, httpClient *http.Client) (Signer, error) {
+	if keyId == "" {
+		return nil, errors.New("awskms signer requires a key id or arn, e.g. awskms:alias/zube")
+	}
+	creds := awsCredentialsFromEnv()
+	// arn:aws:kms:<region>:<account>:key/<id>
+	if arn := strings.Split(keyId, ":"); len(arn) > 3 && arn[0] == "arn" {
+		creds.region = arn[3]
+	}
+	if err := creds.validate("awskms signer"); err != nil {
+		return nil, err
+	}
+	return &awsKMSSigner{keyId: keyId, creds: creds, httpClient: httpClient}, nil
+}
+
+func (*awsKMSSigner) Algorithm() string { return "RS256" }
+
+func (s *awsKMSSigner) String() string { return "aws kms key " + s.keyId }
+
+func (s *awsKMSSigner) Sign(ctx context.Context, signingInput []byte) ([]byte, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"KeyId":            s.keyId,
+		"Message":          signingInput,
+		"MessageType":      "RAW",
+		"SigningAlgorithm": "RSASSA_PKCS1_V1_5_SHA_256",
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("https://kms.%s.amazonaws.com/", s.creds.region), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService.Sign")
+	s.creds.sign(req, payload, "kms")
+	var rsp struct {
+		Signature []byte `json:"Signature"`
+	}
+	if err := signRequest(s.httpClient, req, "aws kms", &rsp); err != nil {
+		return nil, err
+	}
+	return rsp.Signature, nil
+}
+
+// gcpKMSSigner signs with a Cloud KMS asymmetric RSA PKCS#1 SHA-256 key version
+type gcpKMSSigner struct {
+	name       string
+	httpClient *http.Client
+}
+
+func newGCPKMSSigner(name string, httpClient *http.Client) (Signer, error) {
+	if !strings.HasPrefix(name, "projects/") || !strings.Contains(name, "/cryptoKeyVersions/") {
+		return nil, fmt.Errorf("gcpkms signer %q must be a key version, projects/.../cryptoKeys/<key>/cryptoKeyVersions/<version>", name)
+	}
+	return &gcpKMSSigner{name: name, httpClient: httpClient}, nil
+}
+
+func (*gcpKMSSigner) Algorithm() string { return "RS256" }
+
+// accessToken reads GOOGLE_OAUTH_ACCESS_TOKEN, falling back to the gcloud cli
+func (*gcpKMSSigner) accessToken(ctx context.Context) (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+	out, err := exec.CommandContext(ctx, "gcloud", "auth", "print-access-token").Output()
+	if err != nil {
+		return "", fmt.Errorf("gcpkms signer requires GOOGLE_OAUTH_ACCESS_TOKEN or gcloud: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (s *gcpKMSSigner) String() string { return "gcp kms key " + s.name }
+
+func (s *gcpKMSSigner) Sign(ctx context.Context, signingInput []byte) ([]byte, error) {
+	token, err := s.accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(signingInput)
+	body, err := jsonBody(map[string]interface{}{"digest": map[string][]byte{"sha256": digest[:]}})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://cloudkms.googleapis.com/v1/"+s.name+":asymmetricSign", body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	var rsp struct {
+		Signature []byte `json:"signature"`
+	}
+	if err := signRequest(s.httpClient, req, "gcp kms", &rsp); err != nil {
+		return nil, err
+	}
+	return rsp.Signature, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"net/http"
+)
+
+// LabelParams are the writable label fields sent by CreateLabel
+type LabelParams struct {
+	Name        string `json:"name"`
+	Color       string `json:"color,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+func (c *client) ListLabels(ctx context.Context, projectId int) ([]Label, error) {
+	return paginate[Label](ctx, c, apiPath("projects", projectId, "labels"), nil)
+}
+
+func (c *client) CreateLabel(ctx context.Context, projectId int, params LabelParams) (*Label, error) {
+	var label Label
+	if err := c.call(ctx, http.MethodPost, apiPath("projects", projectId, "labels"), nil, params, &label); err != nil {
+		return nil, err
+	}
+	return &label, nil
+}
+
+// labelIds resolves the sweep's label rule names to the project's label ids
+func (s *sweep) labelIds(ctx context.Context, project Project) (map[int]bool, error) {
+	labels, err := s.client.ListLabels(ctx, project.ID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[int]bool)
+	for _, l := range labels {
+		if s.labels[l.Name] {
+			ids[l.ID] = true
+		}
+	}
+	return ids, nil
+}
+
+// hasLabeledCards reports whether the workspace has an open card with any of labelIds
+func (s *sweep) hasLabeledCards(ctx context.Context, workspace Workspace, labelIds map[int]bool) (bool, error) {
+	if len(labelIds) == 0 {
+		return false, nil
+	}
+	cards, err := s.client.ListCards(ctx, CardFilter{WorkspaceID: workspace.ID, State: "open"})
+	if err != nil {
+		return false, err
+	}
+	for _, card := range cards {
+		for _, id := range card.LabelIDs {
+			if labelIds[id] {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// LoggerOption sets the logger for client diagnostics, slog.Default() otherwise
+func LoggerOption(logger *slog.Logger) option {
+	return func(c *client) {
+		c.logger = logger
+	}
+}
+
+// setupLogging installs a stderr logger at level, e.g. debug, info, warn or error, as the default
+func setupLogging(level string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return l, fmt.Errorf("invalid log level %q, expected debug, info, warn or error", level)
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: l})))
+	return l, nil
+}
+
+// fatal logs msg and exits
+func fatal(msg string, args ...interface{}) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds in seconds of the request latency histogram
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type requestKey struct {
+	endpoint, method, status string
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// metrics counts api activity for the prometheus /metrics endpoint of long running commands
+type metrics struct {
+	mu                 sync.Mutex
+	requests           map[requestKey]uint64
+	latency            map[string]*histogram
+	tokenRefreshes     uint64
+	preferencesChanged map[string]uint64
+	errors             uint64
+	// connections counts the connections requests were sent on, by whether they were reused
+	connections map[bool]uint64
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		requests:           make(map[requestKey]uint64),
+		latency:            make(map[string]*histogram),
+		connections:        make(map[bool]uint64),
+		preferencesChanged: make(map[string]uint64),
+	}
+}
+
+// endpoint replaces ids in an api path so each endpoint is one label value, e.g. projects/:id/workspaces
+func endpoint(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) > 0 && segments[0] == "api" {
+		segments = segments[1:]
+	}
+	for i, s := range segments {
+		if _, err := strconv.Atoi(s); err == nil {
+			segments[i] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// middleware records every request sent by a client
+func (m *metrics) middleware(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		started := time.Now()
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) {
+				m.mu.Lock()
+				m.connections[info.Reused]++
+				m.mu.Unlock()
+			},
+		}))
+		rsp, err := next.RoundTrip(req)
+		status := "error"
+		if err == nil {
+			status = strconv.Itoa(rsp.StatusCode)
+		}
+		m.observeRequest(requestKey{endpoint(req.URL.Path), req.Method, status}, time.Since(started))
+		return rsp, err
+	})
+}
+
+func (m *metrics) observeRequest(key requestKey, took time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[key]++
+	if key.endpoint == "users/tokens" && key.status == "200" {
+		m.tokenRefreshes++
+	}
+	h, ok := m.latency[key.endpoint]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(latencyBuckets))}
+		m.latency[key.endpoint] = h
+	}
+	secs := took.Seconds()
+	for i, le := range latencyBuckets {
+		if secs <= le {
+			h.counts[i]++
+		}
+	}
+	h.sum += secs
+	h.count++
+}
+
+// total returns the number of requests sent, m may be nil
+func (m *metrics) total() uint64 {
+	if m == nil {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n uint64
+	for _, c := range m.requests {
+		n += c
+	}
+	return n
+}
+
+// observeChange counts an applied preference change, m may be nil
+func (m *metrics) observeChange(prefType string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.preferencesChanged[prefType]++
+	m.mu.Unlock()
+}
+
+// observeErrors counts failed operations, m may be nil
+func (m *metrics) observeErrors(n int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.errors += uint64(n)
+	m.mu.Unlock()
+}
+
+// writeTo writes the metrics in the prometheus text exposition format
+func (m *metrics) writeTo(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP zube_api_requests_total Zube api requests by endpoint, method and status.")
+	fmt.Fprintln(w, "# TYPE zube_api_requests_total counter")
+	keys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.endpoint != b.endpoint {
+			return a.endpoint < b.endpoint
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+	for _, k := range keys {
+		fmt.Fprintf(w, "zube_api_requests_total{endpoint=%q,method=%q,status=%q} %d\n", k.endpoint, k.method, k.status, m.requests[k])
+	}
+
+	fmt.Fprintln(w, "# HELP zube_api_request_duration_seconds Zube api request latency by endpoint.")
+	fmt.Fprintln(w, "# TYPE zube_api_request_duration_seconds histogram")
+	endpoints := make([]string, 0, len(m.latency))
+	for e := range m.latency {
+		endpoints = append(endpoints, e)
+	}
+	sort.Strings(endpoints)
+	for _, e := range endpoints {
+		h := m.latency[e]
+		for i, le := range latencyBuckets {
+			fmt.Fprintf(w, "zube_api_request_duration_seconds_bucket{endpoint=%q,le=%q} %d\n", e, strconv.FormatFloat(le, 'f', -1, 64), h.counts[i])
+		}
+		fmt.Fprintf(w, "zube_api_request_duration_seconds_bucket{endpoint=%q,le=\"+Inf\"} %d\n", e, h.count)
+		fmt.Fprintf(w, "zube_api_request_duration_seconds_sum{endpoint=%q} %g\n", e, h.sum)
+		fmt.Fprintf(w, "zube_api_request_duration_seconds_count{endpoint=%q} %d\n", e, h.count)
+	}
+
+	fmt.Fprintln(w, "# HELP zube_token_refreshes_total Access tokens minted.")
+	fmt.Fprintln(w, "# TYPE zube_token_refreshes_total counter")
+	fmt.Fprintf(w, "zube_token_refreshes_total %d\n", m.tokenRefreshes)
+
+	fmt.Fprintln(w, "# HELP zube_api_connections_total Connections api requests were sent on, by whether an idle one was reused.")
+	fmt.Fprintln(w, "# TYPE zube_api_connections_total counter")
+	for _, reused := range []bool{false, true} {
+		fmt.Fprintf(w, "zube_api_connections_total{reused=%q} %d\n", strconv.FormatBool(reused), m.connections[reused])
+	}
+
+	fmt.Fprintln(w, "# HELP zube_preferences_changed_total Preference updates applied by preference type.")
+	fmt.Fprintln(w, "# TYPE zube_preferences_changed_total counter")
+	prefTypes := make([]string, 0, len(m.preferencesChanged))
+	for t := range m.preferencesChanged {
+		prefTypes = append(prefTypes, t)
+	}
+	sort.Strings(prefTypes)
+	for _, t := range prefTypes {
+		fmt.Fprintf(w, "zube_preferences_changed_total{type=%q} %d\n", t, m.preferencesChanged[t])
+	}
+
+	fmt.Fprintln(w, "# HELP zube_errors_total Failed operations of enforcement runs and polls.")
+	fmt.Fprintln(w, "# TYPE zube_errors_total counter")
+	fmt.Fprintf(w, "zube_errors_total %d\n", m.errors)
+}
+
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.writeTo(w)
+}
+
+// serve exposes /metrics on addr until ctx is done
+func (m *metrics) serve(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("while listening for metrics: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("metrics server failed", "err", err)
+		}
+	}()
+	slog.Info("serving metrics", "addr", ln.Addr().String())
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/graphaelli/zube-notifications/zubetest"
+)
+
+func TestMetricsCountChanges(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	p := srv.AddProject("proj-a")
+	ctx := context.Background()
+
+	s := newTestSweep(srv)
+	s.metrics = newMetrics()
+	current, err := s.client.notificationPreferences(ctx, p.ID, "projects", emailPreferences)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rewriting the live preferences changes nothing
+	if err := s.update(ctx, "projects", p.ID, emailPreferences, current, copyPreferences(current)); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.metrics.preferencesChanged[emailPreferences]; got != 0 {
+		t.Errorf("%d email preference changes counted for an unchanged write", got)
+	}
+
+	desired := copyPreferences(current)
+	desired["card_moved"] = false
+	current, err = s.client.notificationPreferences(ctx, p.ID, "projects", emailPreferences)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.update(ctx, "projects", p.ID, emailPreferences, current, desired); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.metrics.preferencesChanged[emailPreferences]; got != 1 {
+		t.Errorf("%d email preference changes, want 1", got)
+	}
+}
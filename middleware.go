@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Middleware wraps the transport used for every api request, including retries and the token exchange
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// roundTripperFunc adapts a function to http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// MiddlewareOption registers middleware, the first one sees requests first and responses last
+func MiddlewareOption(middleware ...Middleware) option {
+	return func(c *client) {
+		c.middleware = append(c.middleware, middleware...)
+	}
+}
+
+// wrapTransport returns a copy of httpClient whose transport runs through middleware
+func wrapTransport(httpClient *http.Client, middleware []Middleware) *http.Client {
+	if len(middleware) == 0 {
+		return httpClient
+	}
+	wrapped := *httpClient
+	transport := wrapped.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	for i := len(middleware) - 1; i >= 0; i-- {
+		transport = middleware[i](transport)
+	}
+	wrapped.Transport = transport
+	return &wrapped
+}
+
+// debugMiddleware logs requests and response bodies. Bodies are logged whole
+// so concurrent requests don't interleave their output.
+func debugMiddleware(logger *slog.Logger) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			started := time.Now()
+			rsp, err := next.RoundTrip(req)
+			if err != nil {
+				logger.Debug("request failed", "method", req.Method, "url", req.URL.String(), "err", err)
+				return rsp, err
+			}
+			body, err := io.ReadAll(rsp.Body)
+			rsp.Body.Close()
+			rsp.Body = io.NopCloser(bytes.NewReader(body))
+			logger.Debug("request", "method", req.Method, "url", req.URL.String(), "status", rsp.StatusCode,
+				"took", time.Since(started).Round(time.Millisecond), "body", string(body))
+			return rsp, err
+		})
+	}
+}
+
+// DebugDumpOption writes every request and response, headers and bodies included, to w
+// with credentials redacted
+func DebugDumpOption(w io.Writer) option {
+	return func(c *client) {
+		c.dump = w
+	}
+}
+
+var (
+	redactHeaders = regexp.MustCompile(`(?im)^((?:Authorization|Proxy-Authorization|Cookie|Set-Cookie):[ \t]*(?:(?:Bearer|Basic)[ \t]+)?)[^\r\n]*`)
+	redactTokens  = regexp.MustCompile(`("(?:access_token|refresh_token|token)"\s*:\s*)"[^"]*"`)
+)
+
+// redact replaces credentials in a dumped request or response
+func redact(b []byte) []byte {
+	b = redactHeaders.ReplaceAll(b, []byte("${1}[redacted]"))
+	return redactTokens.ReplaceAll(b, []byte(`${1}"[redacted]"`))
+}
+
+// dumpMiddleware writes each exchange to w as one block so concurrent requests don't interleave
+func dumpMiddleware(w io.Writer) Middleware {
+	var mu sync.Mutex
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			started := time.Now()
+			var buf bytes.Buffer
+			fmt.Fprintf(&buf, "=== %s %s %s\n", started.Format(time.RFC3339Nano), req.Method, req.URL)
+			if b, err := httputil.DumpRequestOut(req, true); err != nil {
+				fmt.Fprintf(&buf, "(request dump failed: %s)\n", err)
+			} else {
+				buf.Write(redact(b))
+			}
+			rsp, err := next.RoundTrip(req)
+			fmt.Fprintf(&buf, "\n--- took %s\n", time.Since(started).Round(time.Millisecond))
+			if err != nil {
+				fmt.Fprintf(&buf, "(request failed: %s)\n", err)
+			} else if b, derr := httputil.DumpResponse(rsp, true); derr != nil {
+				fmt.Fprintf(&buf, "(response dump failed: %s)\n", derr)
+			} else {
+				buf.Write(redact(b))
+			}
+			buf.WriteString("\n\n")
+			mu.Lock()
+			w.Write(buf.Bytes())
+			mu.Unlock()
+			return rsp, err
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// priority values range from highest, 1, to lowest, 5
+const (
+	highestPriority = 1
+	lowestPriority  = 5
+)
+
+var priorityNames = [...]string{"", "Highest", "High", "Medium", "Low", "Lowest"}
+
+// PriorityLevel is a card priority value with its label in a project's priority format
+type PriorityLevel struct {
+	Value int    `json:"value"`
+	Label string `json:"label"`
+}
+
+func (c *client) ListMilestones(ctx context.Context, projectId int) ([]Milestone, error) {
+	return paginate[Milestone](ctx, c, apiPath("projects", projectId, "milestones"), nil)
+}
+
+// ProjectPriorities fetches the priority levels available in a project, nil when priorities are disabled
+func (c *client) ProjectPriorities(ctx context.Context, projectId int) ([]PriorityLevel, error) {
+	project, err := c.GetProject(ctx, projectId)
+	if err != nil {
+		return nil, err
+	}
+	return project.Priorities(), nil
+}
+
+// Priorities lists the project's priority levels labeled according to PriorityFormat
+func (p Project) Priorities() []PriorityLevel {
+	if !p.Priority {
+		return nil
+	}
+	levels := make([]PriorityLevel, 0, lowestPriority)
+	for v := highestPriority; v <= lowestPriority; v++ {
+		levels = append(levels, PriorityLevel{Value: v, Label: FormatPriority(p.PriorityFormat, v)})
+	}
+	return levels
+}
+
+// FormatPriority renders a priority value in a Zube priority format:
+// "p0" counts from P0, "p1" from P1, "text" uses names, anything else is numeric
+func FormatPriority(format string, value int) string {
+	if value < highestPriority || value > lowestPriority {
+		return ""
+	}
+	switch strings.ToLower(format) {
+	case "p0":
+		return fmt.Sprintf("P%d", value-1)
+	case "p1":
+		return fmt.Sprintf("P%d", value)
+	case "text":
+		return priorityNames[value]
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// subscriptionMethod is the card and epic endpoint holding the user's subscription
+const subscriptionMethod = "subscription"
+
+// Subscription is whether the user is notified about a single card or epic
+type Subscription struct {
+	Subscribed bool `json:"subscribed"`
+}
+
+// CardSubscription fetches the user's subscription to a card
+func (c *client) CardSubscription(ctx context.Context, cardId int) (*Subscription, error) {
+	return c.subscription(ctx, "cards", cardId)
+}
+
+// SetCardSubscription subscribes to, or mutes, a single card
+func (c *client) SetCardSubscription(ctx context.Context, cardId int, subscribed bool) error {
+	_, err := c.setSubscription(ctx, "cards", cardId, subscribed)
+	return err
+}
+
+func (c *client) EpicSubscription(ctx context.Context, epicId int) (*Subscription, error) {
+	return c.subscription(ctx, "epics", epicId)
+}
+
+func (c *client) SetEpicSubscription(ctx context.Context, epicId int, subscribed bool) error {
+	_, err := c.setSubscription(ctx, "epics", epicId, subscribed)
+	return err
+}
+
+func (c *client) subscription(ctx context.Context, object string, objectId int) (*Subscription, error) {
+	var sub Subscription
+	if err := c.call(ctx, http.MethodGet, apiPath(object, objectId, subscriptionMethod), nil, nil, &sub); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (c *client) setSubscription(ctx context.Context, object string, objectId int, subscribed bool) (int, error) {
+	err := c.call(ctx, http.MethodPut, apiPath(object, objectId, subscriptionMethod), nil, Subscription{Subscribed: subscribed}, nil)
+	return statusOf(err), err
+}
+
+// statusOf is the http status of a call's result, 200 for success
+func statusOf(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode
+	} else if err != nil {
+		return 0
+	}
+	return http.StatusOK
+}
+
+// subscribe changes the subscription to a card or epic, auditing the change
+func (s *sweep) subscribe(ctx context.Context, object string, objectId int, subscribed bool) error {
+	current, err := s.client.subscription(ctx, object, objectId)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s %d", object[:len(object)-1], objectId)
+	if current.Subscribed == subscribed {
+		s.printf("%s already %s\n", name, mutedState(subscribed))
+		return nil
+	}
+	if s.dryRun {
+		s.printf("[dry-run] %s: %s -> %s\n", name, mutedState(current.Subscribed), mutedState(subscribed))
+		s.outcome.changed()
+		return nil
+	}
+	status, err := s.client.setSubscription(ctx, object, objectId, subscribed)
+	changes := []preferenceChange{{Key: "subscribed", Before: current.Subscribed, After: subscribed}}
+	if err := s.recordAudit(http.MethodPut, object, objectId, subscriptionMethod, changes, status, err); err != nil {
+		return err
+	}
+	s.metrics.observeChange(subscriptionMethod)
+	s.outcome.changed()
+	s.printf("%s %s\n", name, mutedState(subscribed))
+	return nil
+}
+
+func mutedState(subscribed bool) string {
+	if subscribed {
+		return "unmuted"
+	}
+	return "muted"
+}
+
+func muteCommandFor(name, usage string, subscribed bool) *command {
+	return &command{
+		name:  name,
+		usage: usage,
+		run: func(ctx context.Context, s *sweep, args []string) error {
+			if len(args) < 2 || (args[0] != "card" && args[0] != "epic") {
+				return fmt.Errorf("%s requires card or epic and at least one id", name)
+			}
+			object := args[0] + "s"
+			var errs []error
+			for _, arg := range args[1:] {
+				id, err := strconv.Atoi(arg)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("invalid %s id %q", args[0], arg))
+					continue
+				}
+				if err := s.subscribe(ctx, object, id, subscribed); err != nil {
+					errs = append(errs, fmt.Errorf("%s %d: %w", args[0], id, err))
+				}
+			}
+			return errors.Join(errs...)
+		},
+	}
+}
+
+var (
+	muteCommand   = muteCommandFor("mute", "mute card|epic <id>... - stop notifications about single cards or epics", false)
+	unmuteCommand = muteCommandFor("unmute", "unmute card|epic <id>... - resume notifications about muted cards or epics", true)
+)
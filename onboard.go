@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// onboardBucket holds the workspace ids seen by watch -onboard, by client id
+const onboardBucket = "onboard"
+
+// onboarding wraps enforce to also apply p to workspaces created since the previous run.
+// Workspaces present on the very first run are only remembered, those that fail to configure are retried.
+// Seen workspaces are kept in the state store, so workspaces created while not watching are onboarded too.
+func (s *sweep) onboarding(p *profile, enforce func(context.Context) error) func(context.Context) error {
+	var seen map[int]bool
+	if s.store != nil {
+		if _, err := s.store.Get(onboardBucket, s.client.clientId, &seen); err != nil {
+			slog.Warn("ignoring unreadable onboarding state", "err", err)
+		}
+	}
+	save := func() error {
+		if s.store == nil {
+			return nil
+		}
+		if err := s.store.Put(onboardBucket, s.client.clientId, seen); err != nil {
+			return fmt.Errorf("while saving onboarding state: %w", err)
+		}
+		return nil
+	}
+	return func(ctx context.Context) error {
+		err := enforce(ctx)
+		workspaces, werr := s.client.ListAllWorkspaces(ctx)
+		if werr != nil {
+			return errors.Join(err, fmt.Errorf("onboarding: %w", werr))
+		}
+		if seen == nil {
+			seen = make(map[int]bool, len(workspaces))
+			for _, w := range workspaces {
+				seen[w.ID] = true
+			}
+			return errors.Join(err, save())
+		}
+		var created []Workspace
+		for _, w := range workspaces {
+			if !seen[w.ID] {
+				created = append(created, w)
+			}
+		}
+		if len(created) == 0 {
+			return err
+		}
+		projects, perr := s.client.ListProjects(ctx)
+		if perr != nil {
+			return errors.Join(err, fmt.Errorf("onboarding: %w", perr))
+		}
+		byId := make(map[int]Project, len(projects))
+		for _, project := range projects {
+			byId[project.ID] = project
+		}
+		errs := []error{err}
+		for _, w := range created {
+			workspace := w
+			project := byId[workspace.ProjectID]
+			if !s.group.hasWorkspace(project, workspace) {
+				// outside -group, remembered so it isn't considered again
+				seen[workspace.ID] = true
+				continue
+			}
+			slog.Info("onboarding new workspace", "project", project.Name, "workspace", workspace.Name, "id", workspace.ID, "profile", p.Name)
+			s.printf("\n### onboarding workspace %s/%s (%d)\n", project.Name, workspace.Name, workspace.ID)
+			if err := s.applyProfileTo(ctx, p, project, &workspace); err != nil {
+				errs = append(errs, fmt.Errorf("onboarding workspace %s/%s (%d): %w", project.Name, workspace.Name, workspace.ID, err))
+				continue
+			}
+			seen[workspace.ID] = true
+		}
+		return errors.Join(append(errs, save())...)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// ListProjectUserSettings lists the project subscription settings of every member.
+// That requires a key with admin scope, other keys only get their own user's settings.
+func (c *client) ListProjectUserSettings(ctx context.Context, projectId int) ([]UserSetting, error) {
+	return paginate[UserSetting](ctx, c, apiPath("projects", projectId, "user_settings"), nil)
+}
+
+// orgMember is a row of the org report, the subscription level of a member by project name
+type orgMember struct {
+	UserID     int               `json:"user_id"`
+	Username   string            `json:"username"`
+	Name       string            `json:"name"`
+	Role       string            `json:"role"`
+	Levels     map[string]string `json:"levels"`
+	Everything int               `json:"everything"`
+	Muted      int               `json:"muted"`
+}
+
+// orgReport is the members × projects matrix of an account
+type orgReport struct {
+	Account  string      `json:"account"`
+	Projects []string    `json:"projects"`
+	Members  []orgMember `json:"members"`
+}
+
+var reportCommand = &command{
+	name:  "report",
+	usage: "report org [-account ref] - subscription levels of every member in every project, requires an admin key",
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		if len(args) == 0 || args[0] != "org" {
+			return errors.New("report requires org")
+		}
+		fs := flag.NewFlagSet("report org", flag.ExitOnError)
+		accountRef := fs.String("account", "", "account id or slug to report on, every account of the key's projects by default")
+		fs.Parse(args[1:])
+		reports, err := s.orgReports(ctx, *accountRef)
+		for _, r := range reports {
+			if rerr := r.render(s.reportWriter(), s.format); rerr != nil {
+				err = errors.Join(err, rerr)
+			}
+		}
+		return err
+	},
+}
+
+// orgReports builds an org report for every account with projects, or only the referenced one
+func (s *sweep) orgReports(ctx context.Context, accountRef string) ([]*orgReport, error) {
+	projects, err := s.targetProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byAccount := make(map[int][]Project)
+	for _, p := range projects {
+		byAccount[p.AccountID] = append(byAccount[p.AccountID], p)
+	}
+	accountIds := make([]int, 0, len(byAccount))
+	for id := range byAccount {
+		accountIds = append(accountIds, id)
+	}
+	sort.Ints(accountIds)
+	var (
+		reports []*orgReport
+		errs    []error
+	)
+	for _, id := range accountIds {
+		account, err := s.client.GetAccount(ctx, id)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("account %d: %w", id, err))
+			continue
+		}
+		if accountRef != "" && accountRef != strconv.Itoa(account.ID) && accountRef != account.Slug {
+			continue
+		}
+		r, err := s.orgReport(ctx, account, byAccount[id])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("account %s (%d): %w", account.Name, account.ID, err))
+		}
+		if r != nil {
+			reports = append(reports, r)
+		}
+	}
+	if accountRef != "" && len(reports) == 0 && len(errs) == 0 {
+		return nil, fmt.Errorf("no account %q among the key's projects", accountRef)
+	}
+	return reports, errors.Join(errs...)
+}
+
+// orgReport collects the subscription level of every member of account in each of its projects
+func (s *sweep) orgReport(ctx context.Context, account *Account, projects []Project) (*orgReport, error) {
+	members, err := s.client.ListAccountMembers(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	projects = sortedByName(projects, func(p Project) string { return p.Name })
+	r := &orgReport{Account: account.Name}
+	rows := make(map[int]*orgMember, len(members))
+	for _, m := range members {
+		rows[m.UserID] = &orgMember{UserID: m.UserID, Username: m.User.Username, Name: m.User.Name, Role: m.Role, Levels: make(map[string]string)}
+	}
+	var (
+		mu     sync.Mutex
+		errs   []error
+		others bool
+	)
+	me, err := s.client.CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var g errgroup.Group
+	g.SetLimit(s.concurrency)
+	for _, p := range projects {
+		project := p
+		r.Projects = append(r.Projects, project.Name)
+		g.Go(func() error {
+			settings, err := s.client.ListProjectUserSettings(ctx, project.ID)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("project %s (%d): %w", project.Name, project.ID, err))
+				return nil
+			}
+			s.outcome.succeed()
+			for _, setting := range settings {
+				row, ok := rows[setting.UserID]
+				if !ok {
+					continue
+				}
+				others = others || setting.UserID != me.ID
+				row.Levels[project.Name] = setting.SubscriptionLevel
+			}
+			return nil
+		})
+	}
+	g.Wait()
+	if !others && len(members) > 1 {
+		errs = append(errs, errors.New("only your own settings were returned, the key likely lacks admin scope"))
+	}
+	for _, row := range rows {
+		for _, level := range row.Levels {
+			switch level {
+			case "everything":
+				row.Everything++
+			case "mute":
+				row.Muted++
+			}
+		}
+		r.Members = append(r.Members, *row)
+	}
+	sort.Slice(r.Members, func(i, j int) bool { return r.Members[i].Username < r.Members[j].Username })
+	return r, errors.Join(errs...)
+}
+
+func (r *orgReport) render(w io.Writer, format string) error {
+	switch format {
+	case jsonFormat:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case csvFormat:
+		cw := csv.NewWriter(w)
+		cw.Write(append([]string{"account", "user_id", "username", "name", "role"}, r.Projects...))
+		for _, m := range r.Members {
+			record := []string{r.Account, strconv.Itoa(m.UserID), m.Username, m.Name, m.Role}
+			for _, p := range r.Projects {
+				record = append(record, m.Levels[p])
+			}
+			cw.Write(record)
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	fmt.Fprintf(w, "\n*** %s\n", r.Account)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprint(tw, "MEMBER\tROLE")
+	for _, p := range r.Projects {
+		fmt.Fprintf(tw, "\t%s", p)
+	}
+	fmt.Fprintln(tw, "\tEVERYTHING\tMUTED")
+	for _, m := range r.Members {
+		fmt.Fprintf(tw, "%s\t%s", m.Username, dash(m.Role))
+		for _, p := range r.Projects {
+			fmt.Fprintf(tw, "\t%s", dash(m.Levels[p]))
+		}
+		fmt.Fprintf(tw, "\t%d\t%d\n", m.Everything, m.Muted)
+	}
+	return tw.Flush()
+}
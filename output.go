@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// ansi color escape sequences used in the text report
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+)
+
+// useColor reports whether f is a terminal and the user hasn't opted out with NO_COLOR
+func useColor(f *os.File) bool {
+	return os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" && term.IsTerminal(int(f.Fd()))
+}
+
+// paint wraps text in color when the sweep's output is colored
+func (s *sweep) paint(color, text string) string {
+	if !s.color {
+		return text
+	}
+	return color + text + colorReset
+}
+
+// notifying formats the number of enabled notifications out of total boolean preferences:
+// green when none are on, red when every one is, yellow in between
+func (s *sweep) notifying(n, total int) string {
+	color := colorYellow
+	switch {
+	case n == 0:
+		color = colorGreen
+	case n >= total:
+		color = colorRed
+	}
+	return s.paint(color, fmt.Sprintf("notifying: %d", n))
+}
+
+// booleans counts the boolean keys of the given preferences
+func booleans(prefs ...UserPreference) int {
+	n := 0
+	for _, p := range prefs {
+		for _, v := range p {
+			if _, ok := v.(bool); ok {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// printf writes report output to the sweep's writer, stdout by default
+func (s *sweep) printf(format string, args ...interface{}) {
+	fmt.Fprintf(s.writer(), format, args...)
+}
+
+// narrate writes the per-object report, which -quiet suppresses
+func (s *sweep) narrate(format string, args ...interface{}) {
+	if !s.quiet {
+		s.printf(format, args...)
+	}
+}
+
+// reportWriter is where rendered reports go, which -output redirects
+func (s *sweep) reportWriter() io.Writer {
+	if s.reportOut == nil {
+		return os.Stdout
+	}
+	return s.reportOut
+}
+
+func (s *sweep) writer() io.Writer {
+	if s.out == nil {
+		return os.Stdout
+	}
+	return s.out
+}
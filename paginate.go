@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// defaultPerPage is the page size requested from list endpoints
+const defaultPerPage = 30
+
+// listResponse is the envelope returned by every Zube list endpoint
+type listResponse[T any] struct {
+	Pagination Pagination `json:"pagination"`
+	Data       []T        `json:"data"`
+}
+
+// listPage fetches a single page of the list endpoint at path, filtered by query
+func listPage[T any](ctx context.Context, c *client, path string, query url.Values, page int) (*listResponse[T], error) {
+	q := url.Values{}
+	for k, vs := range query {
+		q[k] = vs
+	}
+	q.Set("page", strconv.Itoa(page))
+	q.Set("per_page", strconv.Itoa(c.perPage))
+	req, err := c.newQueryRequest(ctx, http.MethodGet, path, q, nil)
+	if err != nil {
+		return nil, err
+	}
+	rsp, err := c.doRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(rsp)
+	return decodeList[T](rsp, path)
+}
+
+// decodeList decodes a list response one item at a time rather than buffering the whole body,
+// keeping memory flat for pages of large accounts
+func decodeList[T any](rsp *http.Response, what string) (*listResponse[T], error) {
+	head := &headBuffer{limit: 4 << 10}
+	dec := json.NewDecoder(io.TeeReader(rsp.Body, head))
+	var r listResponse[T]
+	if err := decodeListFields(dec, &r); err != nil {
+		return nil, decodeError(rsp, what, err, head.b)
+	}
+	return &r, nil
+}
+
+func decodeListFields[T any](dec *json.Decoder, r *listResponse[T]) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "data":
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if tok == nil {
+				continue
+			}
+			if tok != json.Delim('[') {
+				return fmt.Errorf("expected data array, got %v", tok)
+			}
+			for dec.More() {
+				var item T
+				if err := dec.Decode(&item); err != nil {
+					return err
+				}
+				r.Data = append(r.Data, item)
+			}
+			if err := expectDelim(dec, ']'); err != nil {
+				return err
+			}
+		case "pagination":
+			if err := dec.Decode(&r.Pagination); err != nil {
+				return err
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+// expectDelim consumes the next token, which must be delim
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		return fmt.Errorf("expected %v, got %v", delim, tok)
+	}
+	return nil
+}
+
+// paginate fetches every page of the list endpoint at path, filtered by query
+func paginate[T any](ctx context.Context, c *client, path string, query url.Values) ([]T, error) {
+	var items []T
+	it := iterate[T](ctx, c, path, query)
+	for it.Next() {
+		items = append(items, it.Item())
+	}
+	return items, it.Err()
+}
+
+// Iterator yields the items of a list endpoint, fetching a page at a time so only one is held in memory:
+//
+//	it := c.CardsIter(ctx, CardFilter{ProjectID: 1})
+//	for it.Next() {
+//		card := it.Item()
+//	}
+//	if err := it.Err(); err != nil {
+//		// the items before the failed page were yielded
+//	}
+type Iterator[T any] struct {
+	ctx   context.Context
+	c     *client
+	path  string
+	query url.Values
+	page  int
+	last  bool
+	items []T
+	item  T
+	err   error
+}
+
+func iterate[T any](ctx context.Context, c *client, path string, query url.Values) *Iterator[T] {
+	return &Iterator[T]{ctx: ctx, c: c, path: path, query: query}
+}
+
+// Next advances to the next item, fetching the next page when the current one is used up.
+// It returns false when there are no more items or a request failed, see Err.
+func (it *Iterator[T]) Next() bool {
+	for len(it.items) == 0 {
+		if it.last || it.err != nil {
+			return false
+		}
+		it.page++
+		rsp, err := listPage[T](it.ctx, it.c, it.path, it.query, it.page)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.items = rsp.Data
+		it.last = rsp.Pagination.TotalPages <= it.page
+	}
+	var zero T
+	it.item, it.items[0], it.items = it.items[0], zero, it.items[1:]
+	return true
+}
+
+// Item is the current item, valid after Next returned true
+func (it *Iterator[T]) Item() T {
+	return it.item
+}
+
+// Err is the error that stopped iteration, nil when every page was fetched
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/graphaelli/zube-notifications/zubetest"
+)
+
+func TestPaginate(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	p := srv.AddProject("proj-a")
+	for i := 0; i < 5; i++ {
+		srv.AddWorkspace(p.ID, fmt.Sprintf("workspace-%d", i))
+	}
+	var pages int
+	c := newTestClient(srv, PerPageOption(2), MiddlewareOption(countRequests("/workspaces", &pages)))
+
+	workspaces, err := c.ListWorkspaces(context.Background(), p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(workspaces) != 5 {
+		t.Fatalf("%d workspaces, want 5", len(workspaces))
+	}
+	for i, w := range workspaces {
+		if want := fmt.Sprintf("workspace-%d", i); w.Name != want {
+			t.Errorf("workspace %d is %s, want %s", i, w.Name, want)
+		}
+	}
+	if pages != 3 {
+		t.Errorf("%d page requests, want 3", pages)
+	}
+}
+
+func TestIterator(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	p := srv.AddProject("proj-a")
+	for i := 0; i < 5; i++ {
+		srv.AddWorkspace(p.ID, fmt.Sprintf("workspace-%d", i))
+	}
+	var pages int
+	c := newTestClient(srv, PerPageOption(2), MiddlewareOption(countRequests("/workspaces", &pages)))
+
+	it := iterate[Workspace](context.Background(), c, apiPath("projects", p.ID, "workspaces"), nil)
+	var names []string
+	for it.Next() {
+		names = append(names, it.Item().Name)
+		if len(names) == 3 {
+			break
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 3 || names[2] != "workspace-2" {
+		t.Errorf("iterated %v", names)
+	}
+	if pages != 2 {
+		t.Errorf("%d page requests for 3 items, want 2", pages)
+	}
+}
+
+func TestIteratorError(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	c := newTestClient(srv)
+
+	it := iterate[Workspace](context.Background(), c, apiPath("projects", 999, "nothing"), nil)
+	if it.Next() {
+		t.Fatal("expected no items")
+	}
+	if it.Err() == nil {
+		t.Error("expected the failed page request to be reported")
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// plan is the set of preference writes computed against a profile, for review before apply
+type plan struct {
+	CreatedAt time.Time       `json:"created_at"`
+	ClientID  string          `json:"client_id"`
+	Profile   string          `json:"profile"`
+	Changes   []plannedChange `json:"changes"`
+}
+
+// plannedChange replaces the Before state of an object's preferences with After
+type plannedChange struct {
+	Object   string             `json:"object"`
+	ObjectID int                `json:"object_id"`
+	PrefType string             `json:"pref_type"`
+	Before   UserPreference     `json:"before"`
+	After    UserPreference     `json:"after"`
+	Changes  []preferenceChange `json:"changes"`
+}
+
+func (c plannedChange) String() string {
+	return fmt.Sprintf("%s %d %s", strings.TrimSuffix(c.Object, "s"), c.ObjectID, c.PrefType)
+}
+
+// planner collects the writes update would make instead of making them
+type planner struct {
+	mu      sync.Mutex
+	changes []plannedChange
+}
+
+func (p *planner) add(c plannedChange) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.changes = append(p.changes, c)
+}
+
+var planCommand = &command{
+	name:  "plan",
+	usage: "plan -profile <file> [-out plan.json] - write the changes apply would make, for review and apply <planfile>",
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		fs := flag.NewFlagSet("plan", flag.ExitOnError)
+		profileFile := fs.String("profile", "", "path to yaml or json profile, or the name of a config file preference profile")
+		fs.StringVar(profileFile, "policy", "", "alias for -profile")
+		workspaceFilter := fs.String("workspace-filter", "", "only plan changes to workspaces whose name or slug matches this pattern")
+		out := fs.String("out", "plan.json", "file to write the plan to")
+		fs.Parse(args)
+		if *profileFile == "" {
+			return errors.New("plan requires -profile")
+		}
+		p, err := s.loadProfile(*profileFile)
+		if err != nil {
+			return err
+		}
+		projects, err := s.targetProjects(ctx)
+		if err != nil {
+			return err
+		}
+		if err := s.validateProfile(ctx, p, projects); err != nil {
+			return err
+		}
+		s.showChanges = true
+		s.skipUnchanged = true
+		s.planner = &planner{}
+		err = s.applyProfile(ctx, p, projects, *workspaceFilter)
+		if err != nil {
+			// a partial plan would silently skip objects
+			return err
+		}
+		pl := &plan{CreatedAt: time.Now().UTC(), ClientID: s.client.clientId, Profile: p.Name, Changes: s.planner.changes}
+		sort.Slice(pl.Changes, func(i, j int) bool {
+			a, b := pl.Changes[i], pl.Changes[j]
+			if a.Object != b.Object {
+				return a.Object < b.Object
+			}
+			if a.ObjectID != b.ObjectID {
+				return a.ObjectID < b.ObjectID
+			}
+			return a.PrefType < b.PrefType
+		})
+		b, err := json.MarshalIndent(pl, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*out, append(b, '\n'), 0600); err != nil {
+			return err
+		}
+		s.printf("\nplan: %d objects to change, written to %s\n", len(pl.Changes), *out)
+		if len(pl.Changes) > 0 {
+			s.outcome.changed()
+		}
+		return nil
+	},
+}
+
+// readPlan reads a plan written by the plan command
+func readPlan(path string) (*plan, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p plan
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("while decoding plan %s: %w", path, err)
+	}
+	return &p, nil
+}
+
+// applyPlan makes exactly the planned changes, refusing all of them when any object
+// changed since planning
+func (s *sweep) applyPlan(ctx context.Context, p *plan) error {
+	if p.ClientID != s.client.clientId {
+		return fmt.Errorf("plan was made for client %s, not %s", p.ClientID, s.client.clientId)
+	}
+	current := make([]UserPreference, len(p.Changes))
+	var drifted []string
+	for i, c := range p.Changes {
+		live, err := s.client.notificationPreferences(ctx, c.ObjectID, c.Object, c.PrefType)
+		if err != nil {
+			return fmt.Errorf("%s: %w", c, err)
+		}
+		if !reflect.DeepEqual(live, c.Before) {
+			var keys []string
+			for _, d := range diffPreferences(c.Before, live) {
+				keys = append(keys, d.String())
+			}
+			drifted = append(drifted, fmt.Sprintf("%s: %s", c, strings.Join(keys, ", ")))
+		}
+		current[i] = live
+	}
+	if len(drifted) > 0 {
+		return fmt.Errorf("live state drifted since planning at %s, plan again:\n  %s", p.CreatedAt.Format(time.RFC3339), strings.Join(drifted, "\n  "))
+	}
+	s.showChanges = true
+	var errs []error
+	for i, c := range p.Changes {
+		if err := s.update(ctx, c.Object, c.ObjectID, c.PrefType, current[i], c.After); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c, err))
+		}
+	}
+	return errors.Join(errs...)
+}
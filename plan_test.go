@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/graphaelli/zube-notifications/zubetest"
+)
+
+// planProfile plans the builtin everything profile against srv into a file
+func planProfile(t *testing.T, srv *zubetest.Server) (string, *plan) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := planCommand.run(context.Background(), newTestSweep(srv), []string{"-profile", "everything", "-out", path}); err != nil {
+		t.Fatal(err)
+	}
+	pl, err := readPlan(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path, pl
+}
+
+func TestPlanApply(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	p := srv.AddProject("proj-a")
+	w := srv.AddWorkspace(p.ID, "web")
+	srv.SetPreference("workspaces", w.ID, zubetest.EmailPreferences, "card_moved", false)
+
+	path, pl := planProfile(t, srv)
+	if writes := srv.Writes(); len(writes) > 0 {
+		t.Fatalf("plan wrote %v", writes)
+	}
+	if len(pl.Changes) != 1 || pl.Changes[0].Object != "workspaces" || pl.Changes[0].ObjectID != w.ID {
+		t.Fatalf("planned %v, want only the workspace email preferences", pl.Changes)
+	}
+
+	if err := applyCommand.run(context.Background(), newTestSweep(srv), []string{path}); err != nil {
+		t.Fatal(err)
+	}
+	if email := srv.Preferences("workspaces", w.ID, zubetest.EmailPreferences); email["card_moved"] != true {
+		t.Errorf("card_moved not enabled: %v", email)
+	}
+	if puts := countWrites(srv, http.MethodPut); puts != 1 {
+		t.Errorf("%d writes, want the planned one", puts)
+	}
+}
+
+func TestApplyPlanRefusesDrift(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	p := srv.AddProject("proj-a")
+	w := srv.AddWorkspace(p.ID, "web")
+	srv.SetPreference("workspaces", w.ID, zubetest.EmailPreferences, "card_moved", false)
+
+	path, _ := planProfile(t, srv)
+	srv.SetPreference("workspaces", w.ID, zubetest.EmailPreferences, "card_closed", false)
+	err := applyCommand.run(context.Background(), newTestSweep(srv), []string{path})
+	if err == nil || !strings.Contains(err.Error(), "drifted") || !strings.Contains(err.Error(), "card_closed") {
+		t.Fatalf("got %v, want drift of card_closed", err)
+	}
+	if writes := srv.Writes(); len(writes) > 0 {
+		t.Errorf("drifted plan wrote %v", writes)
+	}
+}
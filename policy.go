@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+var exportPolicyCommand = &command{
+	name:  "export-policy",
+	usage: "export-policy -workspace <ref> [-name <name>] - write a workspace's preferences as a profile for apply -policy",
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		fs := flag.NewFlagSet("export-policy", flag.ExitOnError)
+		workspaceRef := fs.String("workspace", "", "id, name, slug or project/workspace of the workspace to capture")
+		name := fs.String("name", "", "policy name, defaults to the workspace slug")
+		fs.Parse(args)
+		if *workspaceRef == "" {
+			return errors.New("export-policy requires -workspace")
+		}
+		workspace, err := s.resolveWorkspace(ctx, "", *workspaceRef)
+		if err != nil {
+			return err
+		}
+		if *name == "" {
+			*name = workspace.Slug
+		}
+		p, err := s.exportPolicy(ctx, workspace.ID, *name)
+		if err != nil {
+			return err
+		}
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(p); err != nil {
+			return err
+		}
+		return enc.Close()
+	},
+}
+
+// exportPolicy captures every preference of a workspace as a single rule matching all workspaces
+func (s *sweep) exportPolicy(ctx context.Context, workspaceId int, name string) (*profile, error) {
+	rule := profileRule{Project: "*", Workspace: "*"}
+	for _, prefType := range []string{emailPreferences, inAppPreferences} {
+		prefs, err := s.client.notificationPreferences(ctx, workspaceId, "workspaces", prefType)
+		if err != nil {
+			return nil, fmt.Errorf("workspace %d: %w", workspaceId, err)
+		}
+		keys := make(map[string]bool)
+		settings := make(map[string]interface{})
+		for k, v := range prefs {
+			if b, ok := v.(bool); ok {
+				keys[k] = b
+			} else if !isMetadataKey(k) {
+				settings[k] = v
+			}
+		}
+		if len(settings) == 0 {
+			settings = nil
+		}
+		if prefType == emailPreferences {
+			rule.Email, rule.EmailSettings = keys, settings
+		} else {
+			rule.InApp, rule.InAppSettings = keys, settings
+		}
+	}
+	return &profile{Name: name, Rules: []profileRule{rule}}, nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+func enabled(m map[string]interface{}) []string {
+	var matches []string
+	for k, v := range m {
+		if b, ok := v.(bool); ok && b {
+			matches = append(matches, k)
+		}
+	}
+	return matches
+}
+
+// keyFilter selects which preference keys are toggled, the zero value matches all keys
+type keyFilter struct {
+	only   map[string]bool
+	except map[string]bool
+}
+
+func newKeyFilter(only, except string) keyFilter {
+	return keyFilter{only: splitKeys(only), except: splitKeys(except)}
+}
+
+func splitKeys(s string) map[string]bool {
+	if s == "" {
+		return nil
+	}
+	keys := make(map[string]bool)
+	for _, k := range strings.Split(s, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keys[k] = true
+		}
+	}
+	return keys
+}
+
+func (f keyFilter) match(key string) bool {
+	if len(f.only) > 0 && !f.only[key] {
+		return false
+	}
+	return !f.except[key]
+}
+
+// disableAll turns off the matching keys of m, reporting whether any was on
+func disableAll(m map[string]interface{}, f keyFilter) bool {
+	flipped := false
+	for k, v := range m {
+		if b, ok := v.(bool); ok && b && f.match(k) {
+			m[k] = false
+			flipped = true
+		}
+	}
+	return flipped
+}
+
+// enableAll turns on the matching keys of m, reporting whether any was off
+func enableAll(m map[string]interface{}, f keyFilter) bool {
+	flipped := false
+	for k, v := range m {
+		if b, ok := v.(bool); ok && !b && f.match(k) {
+			m[k] = true
+			flipped = true
+		}
+	}
+	return flipped
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func preferenceId(m map[string]interface{}) int {
+	return int(m["id"].(float64))
+}
+
+func copyPreferences(m map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// versionKeys are the revision fields of preferences, sent back as read so zube can refuse stale writes.
+// They aren't preferences, diffPreferences ignores them.
+var versionKeys = []string{"updated_at", "lock_version"}
+
+func isVersionKey(k string) bool {
+	return slices.Contains(versionKeys, k)
+}
+
+// isMetadataKey reports whether k identifies or versions preferences rather than being one
+func isMetadataKey(k string) bool {
+	return k == "id" || k == "created_at" || strings.HasSuffix(k, "_id") || isVersionKey(k)
+}
+
+// withVersion returns desired carrying the revision of current
+func withVersion(desired, current map[string]interface{}) map[string]interface{} {
+	var d map[string]interface{}
+	for _, k := range versionKeys {
+		v, ok := current[k]
+		if !ok || reflect.DeepEqual(desired[k], v) {
+			continue
+		}
+		if d == nil {
+			d = copyPreferences(desired)
+		}
+		d[k] = v
+	}
+	if d == nil {
+		return desired
+	}
+	return d
+}
+
+// rebase returns fresh with the changes from current to desired applied on top,
+// for reapplying a write that conflicted with another session's
+func rebase(current, desired, fresh map[string]interface{}) map[string]interface{} {
+	rebased := copyPreferences(fresh)
+	for _, c := range diffPreferences(current, desired) {
+		rebased[c.Key] = c.After
+	}
+	return rebased
+}
+
+// preferenceChange is a single preference key flipped by an update
+type preferenceChange struct {
+	Key    string      `json:"key"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+func (c preferenceChange) String() string {
+	return fmt.Sprintf("%s: %v -> %v", c.Key, c.Before, c.After)
+}
+
+// diffPreferences lists the keys whose values differ between before and after, sorted by key
+func diffPreferences(before, after map[string]interface{}) []preferenceChange {
+	var changes []preferenceChange
+	for k, v := range after {
+		if isVersionKey(k) {
+			continue
+		}
+		if old, ok := before[k]; !ok || !reflect.DeepEqual(old, v) {
+			changes = append(changes, preferenceChange{Key: k, Before: before[k], After: v})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
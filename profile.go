@@ -0,0 +1,366 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+	"gopkg.in/yaml.v3"
+)
+
+// profile declares the desired state of preference keys for matching projects and workspaces
+type profile struct {
+	Name  string        `yaml:"name" json:"name"`
+	Rules []profileRule `yaml:"rules" json:"rules"`
+}
+
+// profileRule sets preference keys on objects matching its patterns.
+// Rules without a workspace pattern apply to project preferences,
+// rules with one apply to matching workspaces in matching projects.
+// The key "*" sets every boolean preference, later rules override earlier ones.
+// The settings maps set preferences that aren't booleans, such as the email frequency.
+type profileRule struct {
+	Project       string                 `yaml:"project" json:"project"`
+	Workspace     string                 `yaml:"workspace" json:"workspace"`
+	Email         map[string]bool        `yaml:"email" json:"email"`
+	InApp         map[string]bool        `yaml:"in_app" json:"in_app"`
+	EmailSettings map[string]interface{} `yaml:"email_settings,omitempty" json:"email_settings,omitempty"`
+	InAppSettings map[string]interface{} `yaml:"in_app_settings,omitempty" json:"in_app_settings,omitempty"`
+}
+
+// loadProfile returns the named preference profile from the config file, a builtin one,
+// or reads the yaml or json profile definition in file
+func (s *sweep) loadProfile(nameOrFile string) (*profile, error) {
+	if s.config != nil {
+		if p, ok := s.config.Preferences[nameOrFile]; ok {
+			if p.Name == "" {
+				p.Name = nameOrFile
+			}
+			return p, nil
+		}
+	}
+	if builtin, ok := builtinProfiles[nameOrFile]; ok {
+		return builtin(), nil
+	}
+	return loadProfile(nameOrFile)
+}
+
+// loadProfile reads a yaml or json profile definition
+func loadProfile(file string) (*profile, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var p profile
+	if err := yaml.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("while decoding profile %s: %w", file, err)
+	}
+	if p.Name == "" {
+		p.Name = file
+	}
+	for _, r := range p.Rules {
+		for _, pattern := range []string{r.Project, r.Workspace} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("profile %s: invalid pattern %q: %w", file, pattern, err)
+			}
+		}
+	}
+	return &p, nil
+}
+
+// matchName reports whether pattern matches either the name or slug, an empty pattern matches everything
+func matchName(pattern, name, slug string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	if ok, _ := path.Match(pattern, name); ok {
+		return true
+	}
+	ok, _ := path.Match(pattern, slug)
+	return ok
+}
+
+// desired returns a copy of prefs with the profile applied, workspace is nil for project preferences
+func (p *profile) desired(project Project, workspace *Workspace, prefType string, prefs UserPreference) UserPreference {
+	d := copyPreferences(prefs)
+	for _, r := range p.Rules {
+		if !matchName(r.Project, project.Name, project.Slug) {
+			continue
+		}
+		if workspace == nil {
+			if r.Workspace != "" {
+				continue
+			}
+		} else if r.Workspace == "" || !matchName(r.Workspace, workspace.Name, workspace.Slug) {
+			continue
+		}
+		keys, settings := r.Email, r.EmailSettings
+		if prefType == inAppPreferences {
+			keys, settings = r.InApp, r.InAppSettings
+		}
+		if all, ok := keys["*"]; ok {
+			for k, v := range d {
+				if _, isBool := v.(bool); isBool {
+					d[k] = all
+				}
+			}
+		}
+		for k, v := range keys {
+			if k != "*" {
+				d[k] = v
+			}
+		}
+		for k, v := range settings {
+			d[k] = v
+		}
+	}
+	return d
+}
+
+// validateProfile fails listing the keys of p that aren't preference keys of any object in scope,
+// rather than letting them be sent to the api as extra fields
+func (s *sweep) validateProfile(ctx context.Context, p *profile, projects []Project) error {
+	if len(projects) == 0 {
+		return nil
+	}
+	known, err := s.preferenceKeys(ctx, projects)
+	if err != nil {
+		return fmt.Errorf("while validating profile %s: %w", p.Name, err)
+	}
+	var unknown []string
+	for _, pt := range []struct {
+		prefType, name string
+		keys           func(profileRule) map[string]bool
+		settings       func(profileRule) map[string]interface{}
+	}{
+		{emailPreferences, "email", func(r profileRule) map[string]bool { return r.Email }, func(r profileRule) map[string]interface{} { return r.EmailSettings }},
+		{inAppPreferences, "in_app", func(r profileRule) map[string]bool { return r.InApp }, func(r profileRule) map[string]interface{} { return r.InAppSettings }},
+	} {
+		for i, r := range p.Rules {
+			var keys []string
+			for k := range pt.keys(r) {
+				if isBool := known[pt.prefType][k]; k != "*" && !isBool {
+					keys = append(keys, k)
+				}
+			}
+			for k := range pt.settings(r) {
+				if isBool, ok := known[pt.prefType][k]; !ok || isBool {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				unknown = append(unknown, fmt.Sprintf("rule %d %s: %s", i+1, pt.name, k))
+			}
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("profile %s has unknown preference keys, see the schema command for valid ones:\n  %s", p.Name, strings.Join(unknown, "\n  "))
+	}
+	return nil
+}
+
+// preferenceKeys collects the preference keys, by preference type and whether they're booleans,
+// of the projects in scope and, when workspaces are, of one active workspace of each project
+func (s *sweep) preferenceKeys(ctx context.Context, projects []Project) (map[string]map[string]bool, error) {
+	known := map[string]map[string]bool{emailPreferences: {}, inAppPreferences: {}}
+	var mu sync.Mutex
+	add := func(ctx context.Context, object string, id int) error {
+		for prefType, keys := range known {
+			live, err := s.client.notificationPreferences(ctx, id, object, prefType)
+			if errors.Is(err, ErrNotFound) {
+				// no settings yet
+				continue
+			} else if err != nil {
+				return err
+			}
+			mu.Lock()
+			for k, v := range live {
+				if !isMetadataKey(k) {
+					_, isBool := v.(bool)
+					keys[k] = isBool
+				}
+			}
+			mu.Unlock()
+		}
+		return nil
+	}
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(s.concurrency)
+	for _, pr := range projects {
+		project := pr
+		g.Go(func() error {
+			if s.inScope(projectScope) && s.group.hasWholeProject(project) {
+				if err := add(gctx, "projects", project.ID); err != nil {
+					return fmt.Errorf("project %s (%d): %w", project.Name, project.ID, err)
+				}
+			}
+			if !s.inScope(workspaceScope) {
+				return nil
+			}
+			workspaces, err := s.client.ListWorkspaces(gctx, project.ID)
+			if err != nil {
+				return fmt.Errorf("project %s (%d) workspaces: %w", project.Name, project.ID, err)
+			}
+			if active := s.activeWorkspaces(project, workspaces); len(active) > 0 {
+				if err := add(gctx, "workspaces", active[0].ID); err != nil {
+					return fmt.Errorf("workspace %s/%s (%d): %w", project.Name, active[0].Name, active[0].ID, err)
+				}
+			}
+			return nil
+		})
+	}
+	return known, g.Wait()
+}
+
+// validateProfileLive validates p against the keys of the account's projects and workspaces
+func (s *sweep) validateProfileLive(ctx context.Context, p *profile) error {
+	projects, err := s.client.ListProjects(ctx)
+	if err != nil {
+		return err
+	}
+	return s.validateProfile(ctx, p, projects)
+}
+
+var applyCommand = &command{
+	name:  "apply",
+	usage: "apply -profile <file> | -defaults [-workspace-filter <pattern>] [-verify=false] | apply <planfile> - reconcile preferences toward a declared profile, the config's per-project defaults, or make planned changes",
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		fs := flag.NewFlagSet("apply", flag.ExitOnError)
+		profileFile := fs.String("profile", "", "path to yaml or json profile, or the name of a config file preference profile")
+		fs.StringVar(profileFile, "policy", "", "alias for -profile, for policies written by export-policy")
+		workspaceFilter := fs.String("workspace-filter", "", "only reconcile workspaces whose name or slug matches this pattern, leaving project preferences alone")
+		defaults := fs.Bool("defaults", false, "reconcile each project toward the profile the config file's defaults map it to")
+		verify := fs.Bool("verify", true, "re-fetch written objects, retrying and reporting those that don't match the profile")
+		fs.Parse(args)
+		if fs.NArg() > 0 {
+			if *profileFile != "" || *defaults || fs.NArg() > 1 {
+				return errors.New("apply requires either -profile or a single plan file")
+			}
+			p, err := readPlan(fs.Arg(0))
+			if err != nil {
+				return err
+			}
+			if *verify {
+				s.verifier = &verifier{}
+			}
+			err = s.applyPlan(ctx, p)
+			return errors.Join(err, s.verify(ctx))
+		}
+		if *profileFile != "" && *defaults {
+			return errors.New("apply requires either -profile or -defaults")
+		}
+		if *profileFile == "" && !*defaults {
+			return errors.New("apply requires -profile, -defaults or a plan file")
+		}
+		if _, err := path.Match(*workspaceFilter, ""); err != nil {
+			return fmt.Errorf("invalid -workspace-filter %q: %w", *workspaceFilter, err)
+		}
+		if *defaults {
+			projects, err := s.targetProjects(ctx)
+			if err != nil {
+				return err
+			}
+			s.showChanges = true
+			s.skipUnchanged = true
+			if *verify {
+				s.verifier = &verifier{}
+			}
+			err = s.applyDefaults(ctx, projects, *workspaceFilter)
+			return errors.Join(err, s.verify(ctx))
+		}
+		p, err := s.loadProfile(*profileFile)
+		if err != nil {
+			return err
+		}
+		projects, err := s.targetProjects(ctx)
+		if err != nil {
+			return err
+		}
+		if err := s.validateProfile(ctx, p, projects); err != nil {
+			return err
+		}
+		s.showChanges = true
+		s.skipUnchanged = true
+		if *verify {
+			s.verifier = &verifier{}
+		}
+		err = s.applyProfile(ctx, p, projects, *workspaceFilter)
+		return errors.Join(err, s.verify(ctx))
+	},
+}
+
+// applyProfile reconciles live preferences of every project and workspace toward p.
+// A non-empty workspaceFilter restricts it to matching workspaces only.
+func (s *sweep) applyProfile(ctx context.Context, p *profile, projects []Project, workspaceFilter string) error {
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+	for _, project := range projects {
+		if ctx.Err() != nil {
+			return errors.Join(append(errs, ctx.Err())...)
+		}
+		if workspaceFilter == "" && s.group.hasWholeProject(project) {
+			if err := s.applyProfileTo(ctx, p, project, nil); err != nil {
+				fail(fmt.Errorf("project %s (%d): %w", project.Name, project.ID, err))
+			} else {
+				s.outcome.succeed()
+			}
+		}
+		workspaces, err := s.client.ListWorkspaces(ctx, project.ID)
+		if err != nil {
+			fail(fmt.Errorf("project %s (%d) workspaces: %w", project.Name, project.ID, err))
+			continue
+		}
+		var g errgroup.Group
+		g.SetLimit(s.concurrency)
+		for _, w := range s.activeWorkspaces(project, workspaces) {
+			if !matchName(workspaceFilter, w.Name, w.Slug) {
+				continue
+			}
+			project, workspace := project, w
+			g.Go(func() error {
+				if err := s.applyProfileTo(ctx, p, project, &workspace); err != nil {
+					fail(fmt.Errorf("workspace %s/%s (%d): %w", project.Name, workspace.Name, workspace.ID, err))
+				} else {
+					s.outcome.succeed()
+				}
+				return nil
+			})
+		}
+		g.Wait()
+	}
+	return errors.Join(errs...)
+}
+
+func (s *sweep) applyProfileTo(ctx context.Context, p *profile, project Project, workspace *Workspace) error {
+	object, objectId := "projects", project.ID
+	if workspace != nil {
+		object, objectId = "workspaces", workspace.ID
+	}
+	var errs []error
+	for _, prefType := range []string{emailPreferences, inAppPreferences} {
+		current, err := s.client.notificationPreferences(ctx, objectId, object, prefType)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := s.update(ctx, object, objectId, prefType, current, p.desired(project, workspace, prefType, current)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/graphaelli/zube-notifications/zubetest"
+)
+
+func TestApplyProfile(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	p := srv.AddProject("proj-a")
+	w := srv.AddWorkspace(p.ID, "web")
+	srv.SetPreference("workspaces", w.ID, zubetest.EmailPreferences, "card_moved", false)
+
+	s := newTestSweep(srv)
+	if err := applyCommand.run(context.Background(), s, []string{"-profile", "everything"}); err != nil {
+		t.Fatal(err)
+	}
+	if email := srv.Preferences("workspaces", w.ID, zubetest.EmailPreferences); email["card_moved"] != true {
+		t.Errorf("card_moved not enabled: %v", email)
+	}
+	if puts := countWrites(srv, http.MethodPut); puts != 1 {
+		t.Errorf("%d writes, want only the workspace email preferences", puts)
+	}
+	if got := s.outcome.exitCode(nil); got != exitChanged {
+		t.Errorf("exit code %d, want %d", got, exitChanged)
+	}
+}
+
+func TestApplyCompliantProfile(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	p := srv.AddProject("proj-a")
+	srv.AddWorkspace(p.ID, "web")
+
+	s := newTestSweep(srv)
+	if err := applyCommand.run(context.Background(), s, []string{"-profile", "everything"}); err != nil {
+		t.Fatal(err)
+	}
+	if writes := srv.Writes(); len(writes) > 0 {
+		t.Errorf("already compliant apply wrote %v", writes)
+	}
+	if got := s.outcome.exitCode(nil); got != exitOK {
+		t.Errorf("exit code %d, want %d", got, exitOK)
+	}
+}
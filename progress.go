@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// progressEvent is written for every processed object with -progress json
+type progressEvent struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	ID      int       `json:"id"`
+	Name    string    `json:"name"`
+	Project string    `json:"project,omitempty"`
+	Status  string    `json:"status"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// progress streams progress events as json lines, a nil progress discards them
+type progress struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newProgress(w io.Writer) *progress {
+	return &progress{enc: json.NewEncoder(w)}
+}
+
+// done emits the event for an object of type typ processed with result err
+func (p *progress) done(typ string, id int, name, project string, err error) {
+	if p == nil {
+		return
+	}
+	e := progressEvent{Time: time.Now().UTC(), Type: typ, ID: id, Name: name, Project: project, Status: "ok"}
+	switch {
+	case errors.Is(err, errNoSettings):
+		e.Status, e.Error = "skipped", err.Error()
+	case err != nil:
+		e.Status, e.Error = "error", err.Error()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.enc.Encode(e)
+}
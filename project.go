@@ -1,17 +1,24 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
 )
 
-type ProjectsResponse struct {
-	Pagination Pagination `json:"pagination"`
-	Projects   []Project  `json:"data"`
-}
+const (
+	emailPreferences = "user_email_preferences"
+	inAppPreferences = "user_in_app_preferences"
+
+	mutedSubscriptionLevel = "mute"
+)
 
 type EmailPreferencesResponse struct {
 	Pagination           Pagination       `json:"pagination"`
@@ -23,55 +30,80 @@ type UserSettingResponse struct {
 	UserSettings []UserSetting `json:"data"`
 }
 
-func (c *client) projects(page int) (*ProjectsResponse, error) {
-	req, err := c.newRequest(http.MethodGet, "projects", nil)
-	if err != nil {
-		return nil, err
-	}
-	rsp, err := c.doRequest(req)
-	if err != nil {
-		return nil, err
+// ListProjects fetches the projects visible to the user, narrowed by ProjectFilterOption
+// and ProjectFieldsOption
+func (c *client) ListProjects(ctx context.Context) ([]Project, error) {
+	return paginate[Project](ctx, c, "projects", c.projectQuery)
+}
+
+// ProjectsIter iterates over the projects ListProjects returns, a page at a time
+func (c *client) ProjectsIter(ctx context.Context) *Iterator[Project] {
+	return iterate[Project](ctx, c, "projects", c.projectQuery)
+}
+
+// ProjectFilterOption has zube only list projects whose field equals value,
+// e.g. ProjectFilterOption("account_id", 42) or ProjectFilterOption("private", false)
+func ProjectFilterOption(field string, value interface{}) option {
+	return func(c *client) {
+		if c.projectQuery == nil {
+			c.projectQuery = url.Values{}
+		}
+		c.projectQuery.Set("where["+field+"]", fmt.Sprint(value))
 	}
-	defer rsp.Body.Close()
-	var r ProjectsResponse
-	if err := json.NewDecoder(rsp.Body).Decode(&r); err != nil {
-		return nil, fmt.Errorf("while decoding projects response: %w", err)
+}
+
+// projectFilter describes the ProjectFilterOption filters, e.g. "account_id=42", empty without any
+func (c *client) projectFilter() string {
+	var filters []string
+	for k, v := range c.projectQuery {
+		if field, ok := strings.CutPrefix(k, "where["); ok {
+			filters = append(filters, strings.TrimSuffix(field, "]")+"="+strings.Join(v, ","))
+		}
 	}
-	return &r, nil
+	slices.Sort(filters)
+	return strings.Join(filters, ", ")
 }
 
-func (c *client) ListProjects() ([]Project, error) {
-	var projects []Project
-	for page := 1; ; page++ {
-		rsp, err := c.projects(page)
-		if err != nil {
-			return nil, err
+// ProjectFieldsOption has zube only return the given fields of listed projects, always including id.
+// Fields left out are zero valued, so it suits callers reading few of them.
+func ProjectFieldsOption(fields ...string) option {
+	return func(c *client) {
+		if c.projectQuery == nil {
+			c.projectQuery = url.Values{}
 		}
-		projects = append(projects, rsp.Projects...)
-		if rsp.Pagination.TotalPages <= page {
-			return projects, nil
+		if !slices.Contains(fields, "id") {
+			fields = append([]string{"id"}, fields...)
 		}
+		c.projectQuery["select[]"] = fields
+	}
+}
+
+func (c *client) GetProject(ctx context.Context, projectId int) (*Project, error) {
+	var project Project
+	if err := c.call(ctx, http.MethodGet, apiPath("projects", projectId), nil, nil, &project); err != nil {
+		return nil, err
 	}
+	return &project, nil
 }
 
-func (c *client) ProjectEmailPreferences(projectId int) (UserPreference, error) {
-	return c.notificationPreferences(projectId, "projects", "user_email_preferences")
+func (c *client) ProjectEmailPreferences(ctx context.Context, projectId int) (UserPreference, error) {
+	return c.notificationPreferences(ctx, projectId, "projects", emailPreferences)
 }
 
-func (c *client) WorkspaceEmailPreferences(workspaceId int) (UserPreference, error) {
-	return c.notificationPreferences(workspaceId, "workspaces", "user_email_preferences")
+func (c *client) WorkspaceEmailPreferences(ctx context.Context, workspaceId int) (UserPreference, error) {
+	return c.notificationPreferences(ctx, workspaceId, "workspaces", emailPreferences)
 }
 
-func (c *client) ProjectInAppPreferences(projectId int) (UserPreference, error) {
-	return c.notificationPreferences(projectId, "projects", "user_in_app_preferences")
+func (c *client) ProjectInAppPreferences(ctx context.Context, projectId int) (UserPreference, error) {
+	return c.notificationPreferences(ctx, projectId, "projects", inAppPreferences)
 }
 
-func (c *client) WorkspaceInAppPreferences(workspaceId int) (UserPreference, error) {
-	return c.notificationPreferences(workspaceId, "workspaces", "user_in_app_preferences")
+func (c *client) WorkspaceInAppPreferences(ctx context.Context, workspaceId int) (UserPreference, error) {
+	return c.notificationPreferences(ctx, workspaceId, "workspaces", inAppPreferences)
 }
 
-func (c *client) notificationPreferences(objectId int, object, prefType string) (UserPreference, error) {
-	req, err := c.newRequest(http.MethodGet, fmt.Sprintf("%s/%d/%s", object, objectId, prefType), nil)
+func (c *client) notificationPreferences(ctx context.Context, objectId int, object, prefType string) (UserPreference, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, apiPath(object, objectId, prefType), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -79,36 +111,43 @@ func (c *client) notificationPreferences(objectId int, object, prefType string)
 	if err != nil {
 		return nil, err
 	}
-	defer rsp.Body.Close()
+	defer closeBody(rsp)
 	var r EmailPreferencesResponse
-	if err := json.NewDecoder(rsp.Body).Decode(&r); err != nil {
-		return nil, fmt.Errorf("while decoding project user email prefs response: %w", err)
+	if err := decodeResponse(rsp, prefType, &r); err != nil {
+		return nil, err
 	}
 	prefs := r.UserEmailPreferences
+	if len(prefs) == 0 {
+		return nil, fmt.Errorf("no %s for %s %d: %w", prefType, strings.TrimSuffix(object, "s"), objectId, ErrNotFound)
+	}
 	if len(prefs) > 1 {
-		log.Printf("unexpected project user email preferences response: %#v", r)
+		c.logger.Warn("unexpected notification preferences response", "object", object, "id", objectId, "count", len(prefs))
 	}
 	return prefs[0], nil
 }
 
-func (c *client) ProjectTriageUserSettings(projectId int) (*UserSetting, error) {
-	return c.userSettings(projectId, "projects", true)
+func (c *client) ProjectTriageUserSettings(ctx context.Context, projectId int) (*UserSetting, error) {
+	return c.userSettings(ctx, projectId, "projects", true)
 }
 
-func (c *client) ProjectUserSettings(projectId int) (*UserSetting, error) {
-	return c.userSettings(projectId, "projects", false)
+func (c *client) ProjectUserSettings(ctx context.Context, projectId int) (*UserSetting, error) {
+	return c.userSettings(ctx, projectId, "projects", false)
 }
 
-func (c *client) WorkspaceUserSettings(workspaceId int) (*UserSetting, error) {
-	return c.userSettings(workspaceId, "workspaces", false)
+func (c *client) WorkspaceUserSettings(ctx context.Context, workspaceId int) (*UserSetting, error) {
+	return c.userSettings(ctx, workspaceId, "workspaces", false)
 }
 
-func (c *client) userSettings(objectId int, object string, triage bool) (*UserSetting, error) {
-	method := "user_settings"
+func userSettingsMethod(triage bool) string {
 	if triage {
-		method = "triage_user_settings"
+		return "triage_user_settings"
 	}
-	req, err := c.newRequest(http.MethodGet, fmt.Sprintf("%s/%d/%s", object, objectId, method), nil)
+	return "user_settings"
+}
+
+func (c *client) userSettings(ctx context.Context, objectId int, object string, triage bool) (*UserSetting, error) {
+	method := userSettingsMethod(triage)
+	req, err := c.newRequest(ctx, http.MethodGet, apiPath(object, objectId, method), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -116,53 +155,146 @@ func (c *client) userSettings(objectId int, object string, triage bool) (*UserSe
 	if err != nil {
 		return nil, err
 	}
-	defer rsp.Body.Close()
+	defer closeBody(rsp)
 	var r UserSettingResponse
-	if err := json.NewDecoder(rsp.Body).Decode(&r); err != nil {
-		return nil, fmt.Errorf("while decoding project triage user settings: %w", err)
+	if err := decodeResponse(rsp, method, &r); err != nil {
+		return nil, err
 	}
 	settings := r.UserSettings
+	if len(settings) == 0 {
+		return nil, fmt.Errorf("no %s for %s %d: %w", method, strings.TrimSuffix(object, "s"), objectId, ErrNotFound)
+	}
 	if len(settings) > 1 {
-		log.Printf("unexpected project triage user settings: %#v", r)
+		c.logger.Warn("unexpected user settings response", "object", object, "id", objectId, "count", len(settings))
 	}
 	return &settings[0], nil
 }
 
-func (c *client) DisableProjectEmailNotifications(projectId, prefId int, body io.Reader) error {
-	return c.disableNotifications(projectId, "projects", prefId, "user_email_preferences", body)
+// SetProjectSubscriptionLevel changes the project subscription level, e.g. to "mute", "participating" or "everything"
+func (c *client) SetProjectSubscriptionLevel(ctx context.Context, projectId, settingId int, level string) error {
+	_, err := c.updateUserSettings(ctx, projectId, "projects", settingId, false, level)
+	return err
+}
+
+func (c *client) SetProjectTriageSubscriptionLevel(ctx context.Context, projectId, settingId int, level string) error {
+	_, err := c.updateUserSettings(ctx, projectId, "projects", settingId, true, level)
+	return err
+}
+
+// DisableProjectTriageNotifications mutes triage notifications for the project,
+// independently of the project's regular card notifications
+func (c *client) DisableProjectTriageNotifications(ctx context.Context, projectId, settingId int) error {
+	return c.SetProjectTriageSubscriptionLevel(ctx, projectId, settingId, mutedSubscriptionLevel)
+}
+
+func (c *client) SetWorkspaceSubscriptionLevel(ctx context.Context, workspaceId, settingId int, level string) error {
+	_, err := c.updateUserSettings(ctx, workspaceId, "workspaces", settingId, false, level)
+	return err
+}
+
+// updateUserSettings changes a subscription level, returning the response status
+func (c *client) updateUserSettings(ctx context.Context, objectId int, object string, settingId int, triage bool, level string) (int, error) {
+	body, err := json.Marshal(map[string]string{"subscription_level": level})
+	if err != nil {
+		return 0, err
+	}
+	return c.writeNotifications(ctx, http.MethodPut, objectId, object, settingId, userSettingsMethod(triage), bytes.NewReader(body))
+}
+
+func (c *client) DisableProjectEmailNotifications(ctx context.Context, projectId, prefId int, body io.Reader) error {
+	_, err := c.updateNotifications(ctx, projectId, "projects", prefId, emailPreferences, body)
+	return err
+}
+
+func (c *client) DisableProjectInAppNotifications(ctx context.Context, projectId, prefId int, body io.Reader) error {
+	_, err := c.updateNotifications(ctx, projectId, "projects", prefId, inAppPreferences, body)
+	return err
+}
+
+func (c *client) DisableWorkspaceEmailNotifications(ctx context.Context, workspaceId, prefId int, body io.Reader) error {
+	_, err := c.updateNotifications(ctx, workspaceId, "workspaces", prefId, emailPreferences, body)
+	return err
+}
+
+func (c *client) DisableWorkspaceInAppNotifications(ctx context.Context, workspaceId, prefId int, body io.Reader) error {
+	_, err := c.updateNotifications(ctx, workspaceId, "workspaces", prefId, inAppPreferences, body)
+	return err
 }
 
-func (c *client) DisableProjectInAppNotifications(projectId, prefId int, body io.Reader) error {
-	return c.disableNotifications(projectId, "projects", prefId, "user_in_app_preferences", body)
+func (c *client) EnableProjectEmailNotifications(ctx context.Context, projectId, prefId int, body io.Reader) error {
+	_, err := c.updateNotifications(ctx, projectId, "projects", prefId, emailPreferences, body)
+	return err
 }
 
-func (c *client) DisableWorkspaceEmailNotifications(workspaceId, prefId int, body io.Reader) error {
-	return c.disableNotifications(workspaceId, "workspaces", prefId, "user_email_preferences", body)
+func (c *client) EnableProjectInAppNotifications(ctx context.Context, projectId, prefId int, body io.Reader) error {
+	_, err := c.updateNotifications(ctx, projectId, "projects", prefId, inAppPreferences, body)
+	return err
 }
 
-func (c *client) DisableWorkspaceInAppNotifications(workspaceId, prefId int, body io.Reader) error {
-	return c.disableNotifications(workspaceId, "workspaces", prefId, "user_in_app_preferences", body)
+func (c *client) EnableWorkspaceEmailNotifications(ctx context.Context, workspaceId, prefId int, body io.Reader) error {
+	_, err := c.updateNotifications(ctx, workspaceId, "workspaces", prefId, emailPreferences, body)
+	return err
 }
 
-func (c *client) disableNotifications(objectId int, object string, prefId int, prefType string, body io.Reader) error {
-	req, err := c.newRequest(http.MethodPut,
-		fmt.Sprintf("%s/%d/%s/%d", object, objectId, prefType, prefId), body)
+func (c *client) EnableWorkspaceInAppNotifications(ctx context.Context, workspaceId, prefId int, body io.Reader) error {
+	_, err := c.updateNotifications(ctx, workspaceId, "workspaces", prefId, inAppPreferences, body)
+	return err
+}
+
+// ResetProjectEmailNotifications deletes the user's email preference override,
+// Zube falls back to its defaults for the project afterwards.
+func (c *client) ResetProjectEmailNotifications(ctx context.Context, projectId, prefId int) error {
+	_, err := c.resetNotifications(ctx, projectId, "projects", prefId, emailPreferences)
+	return err
+}
+
+func (c *client) ResetProjectInAppNotifications(ctx context.Context, projectId, prefId int) error {
+	_, err := c.resetNotifications(ctx, projectId, "projects", prefId, inAppPreferences)
+	return err
+}
+
+func (c *client) ResetWorkspaceEmailNotifications(ctx context.Context, workspaceId, prefId int) error {
+	_, err := c.resetNotifications(ctx, workspaceId, "workspaces", prefId, emailPreferences)
+	return err
+}
+
+func (c *client) ResetWorkspaceInAppNotifications(ctx context.Context, workspaceId, prefId int) error {
+	_, err := c.resetNotifications(ctx, workspaceId, "workspaces", prefId, inAppPreferences)
+	return err
+}
+
+func (c *client) updateNotifications(ctx context.Context, objectId int, object string, prefId int, prefType string, body io.Reader) (int, error) {
+	return c.writeNotifications(ctx, http.MethodPut, objectId, object, prefId, prefType, body)
+}
+
+func (c *client) resetNotifications(ctx context.Context, objectId int, object string, prefId int, prefType string) (int, error) {
+	return c.writeNotifications(ctx, http.MethodDelete, objectId, object, prefId, prefType, nil)
+}
+
+// writeNotifications sends a preference or user settings change, returning the response status
+func (c *client) writeNotifications(ctx context.Context, method string, objectId int, object string, prefId int, prefType string, body io.Reader) (int, error) {
+	req, err := c.newRequest(ctx, method, apiPath(object, objectId, prefType, prefId), body)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	rsp, err := c.doRequest(req)
 	if err != nil {
-		return err
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			return apiErr.StatusCode, err
+		}
+		return 0, err
 	}
+	defer closeBody(rsp)
 	var i interface{}
-	if err := json.NewDecoder(rsp.Body).Decode(&i); err != nil {
-		return err
+	if err := json.NewDecoder(rsp.Body).Decode(&i); err != nil && err != io.EOF {
+		return rsp.StatusCode, err
 	}
 	if m, ok := i.(map[string]interface{}); ok {
 		if msg, present := m["error"]; present {
-			return fmt.Errorf("error disabling notifications for %s: %s", req.URL.String(), msg)
+			return rsp.StatusCode, fmt.Errorf("error updating notifications for %s: %s", req.URL.String(), msg)
 		}
 	}
 
-	return nil
+	return rsp.StatusCode, nil
 }
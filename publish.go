@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// outputSecretEnv holds the secret http -output destinations are signed with, like webhook forwarding
+const outputSecretEnv = "ZUBE_OUTPUT_SECRET"
+
+// reportSink publishes a rendered report somewhere other than stdout
+type reportSink interface {
+	Publish(ctx context.Context, report []byte, contentType string) error
+}
+
+// parseReportSink returns the sink for an -output destination:
+//
+//	s3://<bucket>/<key>, with AWS_REGION and AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN
+//	http(s)://<url>, posted to, signed with ZUBE_OUTPUT_SECRET when set
+//	<path>, a local file
+//
+// remote destinations are sent to with httpClient
+func parseReportSink(dest string, httpClient *http.Client) (reportSink, error) {
+	u, err := url.Parse(dest)
+	if err != nil || u.Scheme == "" || len(u.Scheme) == 1 {
+		// windows drive letters parse as single letter schemes
+		return fileReportSink(expandHome(dest)), nil
+	}
+	switch u.Scheme {
+	case "s3":
+		key := strings.TrimPrefix(u.Path, "/")
+		if u.Host == "" || key == "" {
+			return nil, fmt.Errorf("s3 output %q must be s3://<bucket>/<key>", dest)
+		}
+		creds := awsCredentialsFromEnv()
+		if err := creds.validate("s3 output"); err != nil {
+			return nil, err
+		}
+		return &s3ReportSink{bucket: u.Host, key: key, creds: creds, httpClient: httpClient}, nil
+	case "http", "https":
+		return &httpReportSink{url: dest, secret: []byte(os.Getenv(outputSecretEnv)), httpClient: httpClient}, nil
+	}
+	return nil, fmt.Errorf("unsupported output %q, expected a path, s3:// or http(s):// url", dest)
+}
+
+// reportContentType is the media type of reports rendered in format
+func reportContentType(format string) string {
+	switch format {
+	case jsonFormat:
+		return "application/json"
+	case csvFormat:
+		return "text/csv"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// fileReportSink writes the report to a local file, replacing it
+type fileReportSink string
+
+func (f fileReportSink) Publish(_ context.Context, report []byte, _ string) error {
+	if err := os.WriteFile(string(f), report, 0600); err != nil {
+		return fmt.Errorf("while writing report: %w", err)
+	}
+	return nil
+}
+
+// s3ReportSink puts the report in an S3 bucket
+type s3ReportSink struct {
+	bucket, key string
+	creds       awsCredentials
+	httpClient  *http.Client
+}
+
+func (s *s3ReportSink) Publish(ctx context.Context, report []byte, contentType string) error {
+	u := &url.URL{Scheme: "https", Host: fmt.Sprintf("%s.s3.%s.amazonaws.com", s.bucket, s.creds.region), Path: "/" + s.key}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(report))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", userAgent())
+	s.creds.sign(req, report, "s3")
+	return publish(s.httpClient, req, "s3://"+s.bucket+"/"+s.key)
+}
+
+// httpReportSink posts the report to a collector
+type httpReportSink struct {
+	url        string
+	secret     []byte
+	httpClient *http.Client
+}
+
+func (h *httpReportSink) Publish(ctx context.Context, report []byte, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(report))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", userAgent())
+	if len(h.secret) > 0 {
+		req.Header.Set(signatureHeader, "sha256="+sign(h.secret, report))
+	}
+	return publish(h.httpClient, req, h.url)
+}
+
+func publish(httpClient *http.Client, req *http.Request, dest string) error {
+	rsp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("while publishing report to %s: %w", dest, err)
+	}
+	defer closeBody(rsp)
+	if rsp.StatusCode/100 != 2 {
+		return fmt.Errorf("while publishing report to %s: %w", dest, newAPIError(rsp))
+	}
+	return nil
+}
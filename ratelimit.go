@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimitLowWater is the remaining request count at which the client pauses until the limit resets
+const rateLimitLowWater = 2
+
+// RateLimitOption throttles requests to rps per second with bursts of up to burst requests
+func RateLimitOption(rps float64, burst int) option {
+	return func(c *client) {
+		if rps > 0 {
+			c.limiter = rate.NewLimiter(rate.Limit(rps), burst)
+		}
+	}
+}
+
+// ConcurrencyOption caps the requests in flight at once at n, however many goroutines share the client
+func ConcurrencyOption(n int) option {
+	return func(c *client) {
+		if n > 0 {
+			c.inflight = make(chan struct{}, n)
+		}
+	}
+}
+
+// acquire takes one of the in flight request slots, waiting for one to free up
+func (c *client) acquire(ctx context.Context) error {
+	if c.inflight == nil {
+		return nil
+	}
+	select {
+	case c.inflight <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *client) release() {
+	if c.inflight != nil {
+		<-c.inflight
+	}
+}
+
+// serverLimit tracks the rate limit reported by Zube in X-RateLimit-* response headers
+type serverLimit struct {
+	mu         sync.Mutex
+	pauseUntil time.Time
+}
+
+// wait blocks until the client may send another request
+func (c *client) wait(ctx context.Context) error {
+	c.serverLimit.mu.Lock()
+	pause := time.Until(c.serverLimit.pauseUntil)
+	c.serverLimit.mu.Unlock()
+	if pause > 0 {
+		c.logger.Debug("rate limit nearly exhausted, pausing", "pause", pause)
+		t := time.NewTimer(pause)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	if c.limiter != nil {
+		return c.limiter.Wait(ctx)
+	}
+	return nil
+}
+
+// observeRateLimit records the server's remaining request budget from rsp
+func (c *client) observeRateLimit(rsp *http.Response) {
+	remaining, err := strconv.Atoi(rsp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil || remaining > rateLimitLowWater {
+		return
+	}
+	reset, err := strconv.ParseInt(rsp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+	var until time.Time
+	// reset is either a unix timestamp or seconds until the window resets
+	if reset > 1e9 {
+		until = time.Unix(reset, 0)
+	} else {
+		until = time.Now().Add(time.Duration(reset) * time.Second)
+	}
+	c.serverLimit.mu.Lock()
+	if until.After(c.serverLimit.pauseUntil) {
+		c.serverLimit.pauseUntil = until
+	}
+	c.serverLimit.mu.Unlock()
+}
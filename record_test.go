@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/graphaelli/zube-notifications/zubetest"
+)
+
+func TestRecordReplay(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	srv.AddProject("proj-a")
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	record := func() {
+		t.Helper()
+		mw, err := recordMiddleware(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := newTestClient(srv, MiddlewareOption(mw)).ListProjects(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	record()
+	// a later run into the same directory adds to the fixtures
+	srv.AddProject("proj-b")
+	record()
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("fixtures %v, want one per run", files)
+	}
+
+	mw, err := replayMiddleware(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+	c := newTestClient(srv, MiddlewareOption(mw))
+	for _, want := range []int{1, 2, 2} {
+		projects, err := c.ListProjects(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(projects) != want {
+			t.Errorf("replayed %d projects, want %d", len(projects), want)
+		}
+	}
+	if _, err := c.ListAccounts(ctx); err == nil {
+		t.Error("expected an error replaying a request that wasn't recorded")
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+)
+
+// report formats selectable with -format, text streams the narrative report as projects complete
+const (
+	textFormat  = "text"
+	tableFormat = "table"
+	wideFormat  = "wide"
+	jsonFormat  = "json"
+	csvFormat   = "csv"
+)
+
+var reportFormats = []string{textFormat, tableFormat, wideFormat, jsonFormat, csvFormat}
+
+// reportRow summarizes the preferences of a project, or a workspace when Workspace is set
+type reportRow struct {
+	Project                 string `json:"project"`
+	ProjectID               int    `json:"project_id"`
+	Workspace               string `json:"workspace,omitempty"`
+	WorkspaceID             int    `json:"workspace_id,omitempty"`
+	SubscriptionLevel       string `json:"subscription_level"`
+	TriageSubscriptionLevel string `json:"triage_subscription_level,omitempty"`
+	EmailFrequency          string `json:"email_frequency"`
+	EmailEnabled            int    `json:"email_enabled"`
+	InAppEnabled            int    `json:"in_app_enabled"`
+	Archived                bool   `json:"archived,omitempty"`
+	ArchiveMergedPrs        bool   `json:"archive_merged_prs,omitempty"`
+	// WeeklyEstimate is the expected notifications per week, set with -estimate
+	WeeklyEstimate *float64       `json:"weekly_estimate,omitempty"`
+	Email          UserPreference `json:"-"`
+	InApp          UserPreference `json:"-"`
+}
+
+// deadNotifying reports whether the row is an archived workspace that still sends notifications
+func (row reportRow) deadNotifying() bool {
+	return row.Archived && row.EmailEnabled+row.InAppEnabled > 0
+}
+
+// workspaceLabel is the workspace name, marked when archived
+func (row reportRow) workspaceLabel() string {
+	if row.Archived {
+		return row.Workspace + " (archived)"
+	}
+	return dash(row.Workspace)
+}
+
+// estimateLabel formats the weekly estimate, a dash when there's none
+func (row reportRow) estimateLabel() string {
+	if row.WeeklyEstimate == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f", *row.WeeklyEstimate)
+}
+
+type reportTotal struct {
+	EmailEnabled int `json:"email_enabled"`
+	InAppEnabled int `json:"in_app_enabled"`
+}
+
+// report collects rows from concurrently processed projects for rendering once a sweep is done
+type report struct {
+	mu   sync.Mutex
+	rows []reportRow
+}
+
+func (r *report) add(row reportRow) {
+	r.mu.Lock()
+	r.rows = append(r.rows, row)
+	r.mu.Unlock()
+}
+
+// sorted returns rows by project name, each project followed by its workspaces in name order
+func (r *report) sorted() []reportRow {
+	r.mu.Lock()
+	rows := append([]reportRow(nil), r.rows...)
+	r.mu.Unlock()
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.Project != b.Project {
+			return a.Project < b.Project
+		}
+		if a.ProjectID != b.ProjectID {
+			return a.ProjectID < b.ProjectID
+		}
+		return a.Workspace < b.Workspace
+	})
+	return rows
+}
+
+func (r *report) render(w io.Writer, format string) error {
+	rows := r.sorted()
+	var total reportTotal
+	for _, row := range rows {
+		total.EmailEnabled += row.EmailEnabled
+		total.InAppEnabled += row.InAppEnabled
+	}
+	switch format {
+	case jsonFormat:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Rows   []reportRow `json:"rows"`
+			Totals reportTotal `json:"totals"`
+		}{rows, total})
+	case csvFormat:
+		return writeCSV(w, rows)
+	case tableFormat, wideFormat:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		wide := format == wideFormat
+		estimated := slices.ContainsFunc(rows, func(row reportRow) bool { return row.WeeklyEstimate != nil })
+		header := "PROJECT\tWORKSPACE\tSUBSCRIPTION\tEMAIL\tIN-APP"
+		if wide {
+			header = "PROJECT\tID\tWORKSPACE\tID\tSUBSCRIPTION\tTRIAGE\tEMAIL FREQUENCY\tEMAIL\tIN-APP"
+		}
+		if estimated {
+			header += "\tEST/WEEK"
+		}
+		fmt.Fprintln(tw, header)
+		for _, row := range rows {
+			if wide {
+				fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%d\t%d", row.Project, row.ProjectID, row.workspaceLabel(), idOrDash(row.WorkspaceID),
+					row.SubscriptionLevel, dash(row.TriageSubscriptionLevel), row.EmailFrequency, row.EmailEnabled, row.InAppEnabled)
+			} else {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d", row.Project, row.workspaceLabel(), row.SubscriptionLevel, row.EmailEnabled, row.InAppEnabled)
+			}
+			if estimated {
+				fmt.Fprintf(tw, "\t%s", row.estimateLabel())
+			}
+			fmt.Fprintln(tw)
+		}
+		if wide {
+			fmt.Fprintf(tw, "TOTAL\t\t\t\t\t\t\t%d\t%d\n", total.EmailEnabled, total.InAppEnabled)
+		} else {
+			fmt.Fprintf(tw, "TOTAL\t\t\t%d\t%d\n", total.EmailEnabled, total.InAppEnabled)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if row.deadNotifying() {
+				fmt.Fprintf(w, "warning: archived workspace %s/%s (%d) still notifying (email: %d, in-app: %d)\n",
+					row.Project, row.Workspace, row.WorkspaceID, row.EmailEnabled, row.InAppEnabled)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// writeCSV writes the preference matrix, one record per project or workspace and preference key
+func writeCSV(w io.Writer, rows []reportRow) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"project", "project_id", "workspace", "workspace_id", "key", "email", "in_app"})
+	for _, row := range rows {
+		keys := make(map[string]bool)
+		for _, prefs := range []UserPreference{row.Email, row.InApp} {
+			for k, v := range prefs {
+				if _, isBool := v.(bool); isBool {
+					keys[k] = true
+				}
+			}
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		workspaceId := ""
+		if row.WorkspaceID != 0 {
+			workspaceId = strconv.Itoa(row.WorkspaceID)
+		}
+		for _, k := range sorted {
+			cw.Write([]string{row.Project, strconv.Itoa(row.ProjectID), row.Workspace, workspaceId, k, csvBool(row.Email[k]), csvBool(row.InApp[k])})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvBool formats a preference value, empty when the key is missing
+func csvBool(v interface{}) string {
+	if b, ok := v.(bool); ok {
+		return strconv.FormatBool(b)
+	}
+	return ""
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func idOrDash(id int) string {
+	if id == 0 {
+		return "-"
+	}
+	return fmt.Sprint(id)
+}
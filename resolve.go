@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ambiguous marks a workspace reference matching more than one workspace
+const ambiguous = -1
+
+// resolverTTL is how long persisted mappings are trusted, after it renamed and deleted objects are relisted
+const resolverTTL = 24 * time.Hour
+
+// resolver caches project and workspace name and slug to id mappings,
+// persisted to path, when set, so later runs skip listing
+type resolver struct {
+	mu   sync.Mutex
+	path string
+	// Projects maps lowercased names and slugs to ids
+	Projects map[string]int `json:"projects"`
+	// Workspaces maps "<project id>/<lowercased name or slug>" and bare lowercased names and slugs to ids
+	Workspaces map[string]int `json:"workspaces"`
+	// Created is when the oldest persisted mapping was listed
+	Created time.Time `json:"created"`
+}
+
+func defaultResolverCachePath(clientId string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "zube", "slugs-"+clientId+".json")
+}
+
+func newResolver(path string) *resolver {
+	r := &resolver{path: path, Projects: make(map[string]int), Workspaces: make(map[string]int)}
+	if path == "" {
+		return r
+	}
+	// an unreadable cache is rebuilt from the api
+	if b, err := os.ReadFile(path); err == nil {
+		json.Unmarshal(b, r)
+	}
+	if time.Since(r.Created) > resolverTTL {
+		r.Projects, r.Workspaces, r.Created = nil, nil, time.Time{}
+	}
+	if r.Projects == nil {
+		r.Projects = make(map[string]int)
+	}
+	if r.Workspaces == nil {
+		r.Workspaces = make(map[string]int)
+	}
+	return r
+}
+
+// save persists the mappings, failing silently since they can always be rebuilt
+func (r *resolver) save() {
+	if r.path == "" {
+		return
+	}
+	if r.Created.IsZero() {
+		r.Created = time.Now()
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0700); err != nil {
+		return
+	}
+	os.WriteFile(r.path, b, 0600)
+}
+
+func (r *resolver) lookup(m map[string]int, key string) (int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id, ok := m[key]
+	return id, ok
+}
+
+// forget drops the mappings to id from m, once the api no longer finds it
+func (r *resolver) forget(m map[string]int, id int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for k, v := range m {
+		if v == id {
+			delete(m, k)
+		}
+	}
+	r.save()
+}
+
+// ResolverCacheOption persists resolved slugs to path so they are reused across runs
+func ResolverCacheOption(path string) option {
+	return func(c *client) {
+		c.resolver = newResolver(path)
+	}
+}
+
+// ResolveProject returns the id of the project with id, name or slug ref
+func (c *client) ResolveProject(ctx context.Context, ref string) (int, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return id, nil
+	}
+	key := strings.ToLower(ref)
+	if id, ok := c.resolver.lookup(c.resolver.Projects, key); ok {
+		return id, nil
+	}
+	projects, err := c.ListProjects(ctx)
+	if err != nil {
+		return 0, err
+	}
+	r := c.resolver
+	r.mu.Lock()
+	for _, p := range projects {
+		r.Projects[strings.ToLower(p.Slug)] = p.ID
+		r.Projects[strings.ToLower(p.Name)] = p.ID
+	}
+	r.save()
+	id, ok := r.Projects[key]
+	r.mu.Unlock()
+	if !ok {
+		if filter := c.projectFilter(); filter != "" {
+			return 0, fmt.Errorf("no project %q among projects with %s", ref, filter)
+		}
+		return 0, fmt.Errorf("no project %q", ref)
+	}
+	return id, nil
+}
+
+// ResolveWorkspace returns the id of the workspace with id, name or slug ref,
+// qualified as "project/workspace" when the workspace name is not unique
+func (c *client) ResolveWorkspace(ctx context.Context, ref string) (int, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return id, nil
+	}
+	projectRef, name, qualified := strings.Cut(ref, "/")
+	if !qualified {
+		return c.resolveBareWorkspace(ctx, ref)
+	}
+	projectId, err := c.ResolveProject(ctx, projectRef)
+	if err != nil {
+		return 0, err
+	}
+	if id, err := strconv.Atoi(name); err == nil {
+		return id, nil
+	}
+	key := fmt.Sprintf("%d/%s", projectId, strings.ToLower(name))
+	if id, ok := c.resolver.lookup(c.resolver.Workspaces, key); ok {
+		return id, nil
+	}
+	workspaces, err := c.ListWorkspaces(ctx, projectId)
+	if err != nil {
+		return 0, err
+	}
+	r := c.resolver
+	r.mu.Lock()
+	for _, w := range workspaces {
+		r.Workspaces[fmt.Sprintf("%d/%s", projectId, strings.ToLower(w.Slug))] = w.ID
+		r.Workspaces[fmt.Sprintf("%d/%s", projectId, strings.ToLower(w.Name))] = w.ID
+	}
+	r.save()
+	id, ok := r.Workspaces[key]
+	r.mu.Unlock()
+	if !ok {
+		return 0, fmt.Errorf("no workspace %q in project %s", name, projectRef)
+	}
+	return id, nil
+}
+
+func (c *client) resolveBareWorkspace(ctx context.Context, ref string) (int, error) {
+	key := strings.ToLower(ref)
+	id, ok := c.resolver.lookup(c.resolver.Workspaces, key)
+	if !ok {
+		workspaces, err := c.ListAllWorkspaces(ctx)
+		if err != nil {
+			return 0, err
+		}
+		r := c.resolver
+		r.mu.Lock()
+		found := make(map[string]int)
+		for _, w := range workspaces {
+			for _, k := range []string{strings.ToLower(w.Slug), strings.ToLower(w.Name)} {
+				if prev, seen := found[k]; seen && prev != w.ID {
+					found[k] = ambiguous
+				} else {
+					found[k] = w.ID
+				}
+			}
+			r.Workspaces[fmt.Sprintf("%d/%s", w.ProjectID, strings.ToLower(w.Slug))] = w.ID
+			r.Workspaces[fmt.Sprintf("%d/%s", w.ProjectID, strings.ToLower(w.Name))] = w.ID
+		}
+		for k, v := range found {
+			r.Workspaces[k] = v
+		}
+		r.save()
+		id, ok = r.Workspaces[key]
+		r.mu.Unlock()
+	}
+	switch {
+	case !ok:
+		return 0, fmt.Errorf("no workspace %q", ref)
+	case id == ambiguous:
+		return 0, fmt.Errorf("workspace %q is ambiguous, qualify it as project/%s", ref, ref)
+	}
+	return id, nil
+}
+
+func (c *client) GetWorkspace(ctx context.Context, workspaceId int) (*Workspace, error) {
+	var workspace Workspace
+	if err := c.call(ctx, http.MethodGet, apiPath("workspaces", workspaceId), nil, nil, &workspace); err != nil {
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+// resolveProject fetches the project with id, name or slug ref, resolving ref again when a cached
+// mapping points at a project that's gone
+func (s *sweep) resolveProject(ctx context.Context, ref string) (Project, error) {
+	id, err := s.client.ResolveProject(ctx, ref)
+	if err != nil {
+		return Project{}, err
+	}
+	project, err := s.client.GetProject(ctx, id)
+	if errors.Is(err, ErrNotFound) && !isID(ref) {
+		s.client.resolver.forget(s.client.resolver.Projects, id)
+		if id, err = s.client.ResolveProject(ctx, ref); err != nil {
+			return Project{}, err
+		}
+		project, err = s.client.GetProject(ctx, id)
+	}
+	if err != nil {
+		return Project{}, err
+	}
+	return *project, nil
+}
+
+// resolveWorkspace fetches the workspace with id, name or slug ref, in projectRef unless it's empty or ref is qualified
+func (s *sweep) resolveWorkspace(ctx context.Context, projectRef, ref string) (Workspace, error) {
+	if projectRef != "" && !strings.Contains(ref, "/") {
+		ref = projectRef + "/" + ref
+	}
+	id, err := s.client.ResolveWorkspace(ctx, ref)
+	if err != nil {
+		return Workspace{}, err
+	}
+	workspace, err := s.client.GetWorkspace(ctx, id)
+	if _, name, _ := strings.Cut(ref, "/"); errors.Is(err, ErrNotFound) && !isID(name) && !isID(ref) {
+		s.client.resolver.forget(s.client.resolver.Workspaces, id)
+		if id, err = s.client.ResolveWorkspace(ctx, ref); err != nil {
+			return Workspace{}, err
+		}
+		workspace, err = s.client.GetWorkspace(ctx, id)
+	}
+	if err != nil {
+		return Workspace{}, err
+	}
+	return *workspace, nil
+}
+
+// isID reports whether ref is a numeric id rather than a name or slug
+func isID(ref string) bool {
+	_, err := strconv.Atoi(ref)
+	return err == nil
+}
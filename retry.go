@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how requests failing with transient errors are retried
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first; values below 2 disable retries
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubled for every subsequent retry
+	BaseDelay time.Duration
+	// MaxDelay caps the computed delay, zero means no cap
+	MaxDelay time.Duration
+	// Jitter randomizes each delay by up to this fraction, between 0 and 1
+	Jitter float64
+}
+
+func RetryOption(policy RetryPolicy) option {
+	return func(c *client) {
+		c.retry = policy
+	}
+}
+
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable:
+		return true
+	}
+	return false
+}
+
+func retryableError(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// neverSent reports whether err means the request didn't reach the server
+func neverSent(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// idempotent reports whether repeating req has no effect beyond the first time,
+// the token exchange only mints another access token
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return isTokenExchange(req)
+}
+
+// backoff returns the delay before retry number attempt, starting at 1
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := float64(p.BaseDelay) * math.Pow(2, float64(attempt-1))
+	if p.MaxDelay > 0 && d > float64(p.MaxDelay) {
+		d = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
+
+// retryAfter parses a Retry-After header, either delay seconds or an http date
+func retryAfter(rsp *http.Response) (time.Duration, bool) {
+	v := rsp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t), true
+	}
+	return 0, false
+}
+
+// send performs req, retrying transient failures according to the client retry policy
+func (c *client) send(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		if err := c.wait(req.Context()); err != nil {
+			return nil, err
+		}
+		if err := c.acquire(req.Context()); err != nil {
+			return nil, err
+		}
+		rsp, err := c.httpClient.Do(req)
+		c.release()
+		if err == nil {
+			c.observeRateLimit(rsp)
+		}
+		if attempt >= c.retry.MaxAttempts || (req.Body != nil && req.GetBody == nil) {
+			return rsp, err
+		}
+		var delay time.Duration
+		switch {
+		case err != nil && retryableError(err) && (idempotent(req) || neverSent(err)):
+			delay = c.retry.backoff(attempt)
+		case err == nil && retryableStatus(rsp.StatusCode):
+			d, ok := retryAfter(rsp)
+			// a failed POST may have taken effect, only retry those the server explicitly asks to
+			if !idempotent(req) && !(ok && (rsp.StatusCode == http.StatusTooManyRequests || rsp.StatusCode == http.StatusServiceUnavailable)) {
+				return rsp, err
+			}
+			delay = c.retry.backoff(attempt)
+			if ok {
+				delay = d
+			}
+			closeBody(rsp)
+		default:
+			return rsp, err
+		}
+		c.logger.Debug("retrying request", "method", req.Method, "url", req.URL.String(), "delay", delay, "attempt", attempt+1, "max_attempts", c.retry.MaxAttempts)
+		t := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			t.Stop()
+			return nil, req.Context().Err()
+		case <-t.C:
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/graphaelli/zube-notifications/zubetest"
+)
+
+// failFirst answers the first n requests to paths ending with suffix with status, passing the rest on
+func failFirst(suffix string, status, n int) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if strings.HasSuffix(req.URL.Path, suffix) && n > 0 {
+				n--
+				return &http.Response{StatusCode: status, Status: http.StatusText(status), Header: http.Header{},
+					Body: io.NopCloser(strings.NewReader("{}")), Request: req}, nil
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+var testRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+func TestRetryTransientStatus(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	srv.AddProject("proj-a")
+	var attempts int
+	// the counter sees every attempt, it wraps the failing middleware
+	c := newTestClient(srv, RetryOption(testRetryPolicy),
+		MiddlewareOption(countRequests("/projects", &attempts), failFirst("/projects", http.StatusServiceUnavailable, 2)))
+
+	projects, err := c.ListProjects(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(projects) != 1 {
+		t.Errorf("%d projects, want 1", len(projects))
+	}
+	if attempts != 3 {
+		t.Errorf("%d attempts, want 3", attempts)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	var attempts int
+	c := newTestClient(srv, RetryOption(testRetryPolicy),
+		MiddlewareOption(countRequests("/projects", &attempts), failFirst("/projects", http.StatusBadGateway, 5)))
+
+	if _, err := c.ListProjects(context.Background()); err == nil {
+		t.Fatal("expected failure after the last attempt")
+	}
+	if attempts != 3 {
+		t.Errorf("%d attempts, want 3", attempts)
+	}
+}
+
+func TestRetrySkipsNonIdempotent(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	p := srv.AddProject("proj-a")
+	var attempts int
+	c := newTestClient(srv, RetryOption(testRetryPolicy),
+		MiddlewareOption(countRequests("/comments", &attempts), failFirst("/comments", http.StatusInternalServerError, 1)))
+	card := srv.AddCard(zubetest.Card{ProjectID: p.ID})
+
+	if _, err := c.CreateComment(context.Background(), card.ID, "hello"); err == nil {
+		t.Fatal("expected the post to fail")
+	}
+	if attempts != 1 {
+		t.Errorf("%d attempts of a post, want 1", attempts)
+	}
+	if writes := srv.Writes(); len(writes) != 0 {
+		t.Errorf("comment posted by a retry: %v", writes)
+	}
+}
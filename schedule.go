@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schedule is a set of weekly windows during which notifications are muted, e.g.
+//
+//	mute 18:00-09:00 Mon-Fri; mute 00:00-24:00 Sat,Sun
+//
+// Windows ending before they start run past midnight into the following day.
+type schedule struct {
+	loc   *time.Location
+	rules []scheduleRule
+}
+
+type scheduleRule struct {
+	start, end int // minutes since midnight
+	days       map[time.Weekday]bool
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func parseSchedule(s string, loc *time.Location) (*schedule, error) {
+	sched := &schedule{loc: loc}
+	for _, expr := range strings.Split(s, ";") {
+		if expr = strings.TrimSpace(expr); expr == "" {
+			continue
+		}
+		rule, err := parseScheduleRule(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", expr, err)
+		}
+		sched.rules = append(sched.rules, rule)
+	}
+	if len(sched.rules) == 0 {
+		return nil, fmt.Errorf("empty schedule")
+	}
+	return sched, nil
+}
+
+func parseScheduleRule(expr string) (scheduleRule, error) {
+	var rule scheduleRule
+	fields := strings.Fields(expr)
+	if len(fields) < 2 || len(fields) > 3 || fields[0] != "mute" {
+		return rule, fmt.Errorf(`expected "mute HH:MM-HH:MM [days]"`)
+	}
+	window := strings.SplitN(fields[1], "-", 2)
+	if len(window) != 2 {
+		return rule, fmt.Errorf("expected a time range, got %q", fields[1])
+	}
+	var err error
+	if rule.start, err = parseClock(window[0]); err != nil {
+		return rule, err
+	}
+	if rule.end, err = parseClock(window[1]); err != nil {
+		return rule, err
+	}
+	days := "mon-sun"
+	if len(fields) == 3 {
+		days = fields[2]
+	}
+	rule.days, err = parseDays(days)
+	return rule, err
+}
+
+// parseClock parses HH:MM into minutes since midnight, 24:00 is the end of the day
+func parseClock(s string) (int, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	h, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	m, err := strconv.Atoi(parts[1])
+	if err != nil || h < 0 || m < 0 || m > 59 || h*60+m > 24*60 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return h*60 + m, nil
+}
+
+// parseDays parses comma separated days and day ranges, e.g. "Mon-Fri" or "Sat,Sun"
+func parseDays(s string) (map[time.Weekday]bool, error) {
+	days := make(map[time.Weekday]bool)
+	for _, part := range strings.Split(strings.ToLower(s), ",") {
+		bounds := strings.SplitN(part, "-", 2)
+		first, ok := weekdays[bounds[0]]
+		if !ok {
+			return nil, fmt.Errorf("invalid day %q", bounds[0])
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, ok = weekdays[bounds[1]]; !ok {
+				return nil, fmt.Errorf("invalid day %q", bounds[1])
+			}
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// muted reports whether t falls within any mute window
+func (s *schedule) muted(t time.Time) bool {
+	t = t.In(s.loc)
+	minute := t.Hour()*60 + t.Minute()
+	today := t.Weekday()
+	yesterday := (today + 6) % 7
+	for _, r := range s.rules {
+		if r.start <= r.end {
+			if r.days[today] && minute >= r.start && minute < r.end {
+				return true
+			}
+			continue
+		}
+		if (r.days[today] && minute >= r.start) || (r.days[yesterday] && minute < r.end) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/graphaelli/zube-notifications/zubetest"
+)
+
+func TestScheduleMuted(t *testing.T) {
+	sched, err := parseSchedule("mute 18:00-09:00 Mon-Fri; mute 00:00-24:00 Sat,Sun", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		at    string
+		muted bool
+	}{
+		{"2026-10-14T12:00:00Z", false}, // wednesday noon
+		{"2026-10-14T18:00:00Z", true},
+		{"2026-10-15T08:59:00Z", true}, // thursday morning, from wednesday evening
+		{"2026-10-15T09:00:00Z", false},
+		{"2026-10-17T12:00:00Z", true},  // saturday
+		{"2026-10-19T08:00:00Z", false}, // monday morning, sunday evening is not in Mon-Fri
+		{"2026-10-19T12:00:00Z", false},
+	} {
+		at, err := time.Parse(time.RFC3339, tc.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := sched.muted(at); got != tc.muted {
+			t.Errorf("muted(%s %s) = %v, want %v", at.Weekday(), tc.at, got, tc.muted)
+		}
+	}
+	for _, expr := range []string{"", "mute 18:00", "mute 25:00-09:00", "mute 18:00-09:00 Someday", "unmute 18:00-09:00"} {
+		if _, err := parseSchedule(expr, time.UTC); err == nil {
+			t.Errorf("parsed invalid schedule %q", expr)
+		}
+	}
+}
+
+func TestScheduledEnforcement(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	p := srv.AddProject("proj-a")
+	w := srv.AddWorkspace(p.ID, "web")
+	sched, err := parseSchedule("mute 00:00-24:00", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var requests int
+	s := newTestSweep(srv, MiddlewareOption(countRequests("", &requests)))
+	enforce := s.scheduled(sched, noAction, noAction)
+	ctx := context.Background()
+
+	if err := enforce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if email := srv.Preferences("workspaces", w.ID, zubetest.EmailPreferences); email["card_moved"] != false {
+		t.Errorf("card_moved not muted: %v", email)
+	}
+	before := requests
+	if err := enforce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if requests != before {
+		t.Error("enforced again inside the same mute window")
+	}
+
+	sched.rules = []scheduleRule{{start: 0, end: 0, days: map[time.Weekday]bool{}}}
+	if err := enforce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if email := srv.Preferences("workspaces", w.ID, zubetest.EmailPreferences); email["card_moved"] != true {
+		t.Errorf("card_moved not unmuted: %v", email)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// schemaKey is a preference key as returned by the api
+type schemaKey struct {
+	Key         string      `json:"key"`
+	Value       interface{} `json:"value"`
+	Description string      `json:"description"`
+}
+
+// schema lists the preference keys of one project or workspace
+type schema struct {
+	Object string      `json:"object"`
+	ID     int         `json:"id"`
+	Name   string      `json:"name"`
+	Email  []schemaKey `json:"email"`
+	InApp  []schemaKey `json:"in_app"`
+}
+
+var schemaCommand = &command{
+	name:  "schema",
+	usage: "schema [-project ref] [-workspace ref] - list the preference keys valid for -only, -except and profiles",
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		fs := flag.NewFlagSet("schema", flag.ExitOnError)
+		projectRef := fs.String("project", "", "project id, name or slug to read keys from, the first project by default")
+		workspaceRef := fs.String("workspace", "", "workspace id, name or slug to read keys from instead of a project")
+		fs.Parse(args)
+		sc, err := s.schema(ctx, *projectRef, *workspaceRef)
+		if err != nil {
+			return err
+		}
+		return sc.render(s.reportWriter(), s.format)
+	},
+}
+
+// schema fetches the email and in-app preferences of the referenced project or workspace
+func (s *sweep) schema(ctx context.Context, projectRef, workspaceRef string) (*schema, error) {
+	sc := &schema{Object: "projects"}
+	switch {
+	case workspaceRef != "":
+		w, err := s.resolveWorkspace(ctx, projectRef, workspaceRef)
+		if err != nil {
+			return nil, err
+		}
+		sc.Object, sc.ID, sc.Name = "workspaces", w.ID, w.Name
+	case projectRef != "":
+		p, err := s.resolveProject(ctx, projectRef)
+		if err != nil {
+			return nil, err
+		}
+		sc.ID, sc.Name = p.ID, p.Name
+	default:
+		projects, err := s.client.ListProjects(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(projects) == 0 {
+			return nil, errors.New("no projects to read preference keys from")
+		}
+		sc.ID, sc.Name = projects[0].ID, projects[0].Name
+	}
+	for _, pt := range []struct {
+		prefType string
+		keys     *[]schemaKey
+	}{{emailPreferences, &sc.Email}, {inAppPreferences, &sc.InApp}} {
+		prefs, err := s.client.notificationPreferences(ctx, sc.ID, sc.Object, pt.prefType)
+		if err != nil {
+			return nil, fmt.Errorf("while reading %s of %s: %w", pt.prefType, sc.Name, err)
+		}
+		for _, k := range sortedKeys(prefs) {
+			if k == "id" || isVersionKey(k) {
+				continue
+			}
+			*pt.keys = append(*pt.keys, schemaKey{Key: k, Value: prefs[k], Description: describeKey(k, prefs[k])})
+		}
+	}
+	return sc, nil
+}
+
+// describeKey infers a description from a key name, e.g. card_assigned is "a card is assigned"
+func describeKey(key string, value interface{}) string {
+	if _, ok := value.(bool); !ok {
+		return "setting, not changed by -E/-I or -enable-*"
+	}
+	// leading, trailing and doubled underscores don't make words
+	words := strings.FieldsFunc(key, func(r rune) bool { return r == '_' })
+	if len(words) < 2 {
+		return "notify on " + key
+	}
+	subject, event := strings.Join(words[:len(words)-1], " "), words[len(words)-1]
+	article := "a"
+	if strings.ContainsRune("aeiou", rune(subject[0])) {
+		article = "an"
+	}
+	if strings.HasSuffix(event, "ed") {
+		return fmt.Sprintf("notify when %s %s is %s", article, subject, event)
+	}
+	return fmt.Sprintf("notify on %s %s", subject, event)
+}
+
+func (sc *schema) render(w io.Writer, format string) error {
+	if format == jsonFormat {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(sc)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "preference keys of %s %s (%d)\n", strings.TrimSuffix(sc.Object, "s"), sc.Name, sc.ID)
+	for _, group := range []struct {
+		name string
+		keys []schemaKey
+	}{{"email", sc.Email}, {"in-app", sc.InApp}} {
+		fmt.Fprintf(tw, "\n%s\nKEY\tVALUE\tDESCRIPTION\n", group.name)
+		for _, k := range group.keys {
+			fmt.Fprintf(tw, "%s\t%v\t%s\n", k.Key, k.Value, k.Description)
+		}
+	}
+	return tw.Flush()
+}
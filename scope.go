@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// preference levels selectable with -scope
+const (
+	accountScope   = "account"
+	projectScope   = "project"
+	workspaceScope = "workspace"
+)
+
+func (s *sweep) inScope(scope string) bool {
+	if s.scopes == nil {
+		return scope != accountScope
+	}
+	return s.scopes[scope]
+}
+
+// accounts reports on and updates account-wide notification defaults
+func (s *sweep) accounts(ctx context.Context) error {
+	accounts, err := s.client.ListAccounts(ctx)
+	if err != nil {
+		return fmt.Errorf("accounts: %w", err)
+	}
+	var errs []error
+	for _, account := range accounts {
+		err := s.account(ctx, account)
+		s.progress.done("account", account.ID, account.Name, "", err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("account %s (%d): %w", account.Name, account.ID, err))
+			continue
+		}
+		s.outcome.succeed()
+	}
+	return errors.Join(errs...)
+}
+
+func (s *sweep) account(ctx context.Context, account Account) error {
+	emailPrefs, err := s.client.AccountEmailPreferences(ctx, account.ID)
+	if err != nil {
+		return err
+	}
+	inAppPrefs, err := s.client.AccountInAppPreferences(ctx, account.ID)
+	if err != nil {
+		return err
+	}
+	emailNotifying := enabled(emailPrefs)
+	inAppNotifying := enabled(inAppPrefs)
+	s.narrate("\n### account %s %s (email: %d in-app: %d)\n",
+		account.Name,
+		s.notifying(len(emailNotifying)+len(inAppNotifying), booleans(emailPrefs, inAppPrefs)),
+		len(emailNotifying),
+		len(inAppNotifying),
+	)
+	return errors.Join(
+		s.apply(ctx, "accounts", account.ID, emailPreferences, emailPrefs, s.email),
+		s.apply(ctx, "accounts", account.ID, inAppPreferences, inAppPrefs, s.inApp),
+	)
+}
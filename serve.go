@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// zube webhook event types
+const (
+	eventCardCreated    = "card.created"
+	eventCardMoved      = "card.moved"
+	eventCommentCreated = "comment.created"
+)
+
+// maxEventSize bounds webhook request bodies
+const maxEventSize = 1 << 20
+
+// eventQueueSize bounds events acknowledged but not yet handled, deliveries beyond it are refused
+// for the sender to retry
+const eventQueueSize = 256
+
+// eventMemory is the number of recent event ids remembered to drop redeliveries
+const eventMemory = 5000
+
+// eventAttempts is how many times failed handlers of an event are run before giving up on them
+const eventAttempts = 3
+
+// Event is a decoded zube webhook delivery
+type Event struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	CreatedAt   time.Time `json:"created_at"`
+	ProjectID   int       `json:"project_id"`
+	WorkspaceID int       `json:"workspace_id"`
+	Actor       *User     `json:"actor,omitempty"`
+	Card        *Card     `json:"card,omitempty"`
+	Comment     *Comment  `json:"comment,omitempty"`
+	// From and To are the card's previous and new board location for card.moved
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// Raw is the body as delivered
+	Raw json.RawMessage `json:"-"`
+}
+
+// eventHandler reacts to webhook events
+type eventHandler interface {
+	Handle(ctx context.Context, e Event) error
+}
+
+// logHandler logs every event
+type logHandler struct{}
+
+func (logHandler) Handle(ctx context.Context, e Event) error {
+	attrs := []any{"type", e.Type, "id", e.ID, "project", e.ProjectID}
+	if e.Card != nil {
+		attrs = append(attrs, "card", e.Card.ID, "title", e.Card.Title)
+	}
+	if e.Type == eventCardMoved {
+		attrs = append(attrs, "from", e.From, "to", e.To)
+	}
+	slog.InfoContext(ctx, "webhook event", attrs...)
+	return nil
+}
+
+const defaultSlackEventTemplate = `{{.Type}}{{with .Card}} *#{{.Number}} {{.Title}}*{{end}}` +
+	`{{if .To}} moved {{.From}} → {{.To}}{{end}}{{with .Comment}}
+{{.Body}}{{end}}`
+
+// slackEventHandler posts events to slack
+type slackEventHandler struct {
+	sink *slackSink
+	tmpl *template.Template
+}
+
+func newSlackEventHandler(webhookURL, tmpl string, httpClient *http.Client) (*slackEventHandler, error) {
+	if tmpl == "" {
+		tmpl = defaultSlackEventTemplate
+	}
+	t, err := template.New("slack-event").Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid slack template: %w", err)
+	}
+	return &slackEventHandler{sink: &slackSink{webhookURL: webhookURL, httpClient: httpClient}, tmpl: t}, nil
+}
+
+func (h *slackEventHandler) Handle(ctx context.Context, e Event) error {
+	text := new(bytes.Buffer)
+	if err := h.tmpl.Execute(text, e); err != nil {
+		return err
+	}
+	return h.sink.post(ctx, text.String())
+}
+
+// eventServer verifies and decodes webhook deliveries, acknowledging them before they are dispatched
+// to handlers in the background
+type eventServer struct {
+	secret   []byte
+	types    map[string]bool
+	handlers []eventHandler
+	queue    chan Event
+
+	mu sync.Mutex
+	// events holds the progress of recently received events by id, oldest first in order
+	events map[string]*eventProgress
+	order  []string
+}
+
+// eventProgress tracks an event across redeliveries
+type eventProgress struct {
+	queued bool
+	// handled marks the handlers that succeeded, by index
+	handled []bool
+}
+
+func newEventServer(secret []byte, types map[string]bool) *eventServer {
+	return &eventServer{
+		secret: secret,
+		types:  types,
+		queue:  make(chan Event, eventQueueSize),
+		events: make(map[string]*eventProgress),
+	}
+}
+
+// verify checks the sha256=<hex> HMAC of body in header against the secret
+func (s *eventServer) verify(header string, body []byte) bool {
+	got, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	return hmac.Equal([]byte(got), []byte(sign(s.secret, body)))
+}
+
+func (s *eventServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxEventSize))
+	if err != nil {
+		http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if !s.verify(r.Header.Get(signatureHeader), body) {
+		slog.Warn("rejected webhook with invalid signature", "remote", r.RemoteAddr)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	var e Event
+	if err := json.Unmarshal(body, &e); err != nil || e.Type == "" {
+		http.Error(w, "invalid event", http.StatusBadRequest)
+		return
+	}
+	e.Raw = body
+	if s.types != nil && !s.types[e.Type] {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if !s.accept(e.ID) {
+		// queued or already handled
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	select {
+	case s.queue <- e:
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		s.requeue(e.ID)
+		// let the sender retry
+		http.Error(w, "too many events queued", http.StatusServiceUnavailable)
+	}
+}
+
+// accept reports whether the event with id should be queued, marking it queued,
+// events without an id are always queued
+func (s *eventServer) accept(id string) bool {
+	if id == "" {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.events[id]
+	if !ok {
+		p = &eventProgress{handled: make([]bool, len(s.handlers))}
+		s.events[id] = p
+		s.order = append(s.order, id)
+		s.forgetOldest()
+	}
+	if p.queued || !slices.Contains(p.handled, false) {
+		return false
+	}
+	p.queued = true
+	return true
+}
+
+// requeue lets the event with id be queued again by a redelivery
+func (s *eventServer) requeue(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.events[id]; ok {
+		p.queued = false
+	}
+}
+
+// forgetOldest drops the oldest events beyond eventMemory that aren't queued
+func (s *eventServer) forgetOldest() {
+	for len(s.order) > eventMemory {
+		p := s.events[s.order[0]]
+		if p != nil && p.queued {
+			return
+		}
+		delete(s.events, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+// handle runs the handlers of queued events until the queue is closed
+func (s *eventServer) handle(ctx context.Context) {
+	for e := range s.queue {
+		var err error
+		for attempt := 1; attempt <= eventAttempts; attempt++ {
+			if err = s.dispatch(ctx, e); err == nil {
+				break
+			}
+			if attempt < eventAttempts {
+				time.Sleep(time.Duration(attempt) * time.Second)
+			}
+		}
+		if err != nil {
+			slog.Error("webhook handlers failed", "type", e.Type, "id", e.ID, "err", err)
+		}
+		if e.ID != "" {
+			s.requeue(e.ID)
+		}
+	}
+}
+
+// dispatch runs every handler that hasn't yet succeeded for e, one failing doesn't stop the rest
+func (s *eventServer) dispatch(ctx context.Context, e Event) error {
+	var errs []error
+	for i, h := range s.handlers {
+		if s.handled(e.ID, i) {
+			continue
+		}
+		if err := h.Handle(ctx, e); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.mu.Lock()
+		if p, ok := s.events[e.ID]; ok {
+			p.handled[i] = true
+		}
+		s.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// handled reports whether handler i succeeded for the event with id
+func (s *eventServer) handled(id string, i int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.events[id]
+	return ok && p.handled[i]
+}
+
+var serveCommand = &command{
+	name:    "serve",
+	usage:   "serve [-addr :8080] -secret <s> [-slack-webhook <url>] [-hook event=cmd] - receive zube webhooks and dispatch them to handlers",
+	offline: true,
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		fs := flag.NewFlagSet("serve", flag.ExitOnError)
+		addr := fs.String("addr", ":8080", "address to listen on")
+		path := fs.String("path", "/webhook", "path webhooks are posted to")
+		secret := fs.String("secret", os.Getenv("ZUBE_WEBHOOK_SECRET"), "secret for verifying the "+signatureHeader+" HMAC-SHA256 header")
+		events := fs.String("events", "", "comma separated event types to handle, all by default")
+		quiet := fs.Bool("quiet", false, "don't log every event")
+		slackWebhook := fs.String("slack-webhook", os.Getenv("ZUBE_SLACK_WEBHOOK"), "slack incoming webhook url to post events to")
+		slackTemplate := fs.String("slack-template", "", "text/template for slack messages, fields of Event are available")
+		hooks := hookFlags{}
+		fs.Var(hooks, "hook", `"event=command" run with the event json on stdin, "*" matches every event, repeatable`)
+		hookTimeout := fs.Duration("hook-timeout", 30*time.Second, "time a hook command may run before it is killed")
+		hookConcurrency := fs.Int("hook-concurrency", 4, "maximum hook commands running at once")
+		desktop := fs.Bool("desktop", false, "show native desktop notifications")
+		desktopProjects := fs.String("desktop-projects", "", "comma separated project ids to show desktop notifications for, all by default")
+		fs.Parse(args)
+		if *secret == "" {
+			return errors.New("serve requires -secret or ZUBE_WEBHOOK_SECRET")
+		}
+		srv := newEventServer([]byte(*secret), splitKeys(*events))
+		if !*quiet {
+			srv.handlers = append(srv.handlers, logHandler{})
+		}
+		if *slackWebhook != "" {
+			h, err := newSlackEventHandler(*slackWebhook, *slackTemplate, s.client.transport.externalClient())
+			if err != nil {
+				return err
+			}
+			srv.handlers = append(srv.handlers, h)
+		}
+		if len(hooks) > 0 {
+			srv.handlers = append(srv.handlers, newShellHook(hooks, *hookTimeout, *hookConcurrency))
+		}
+		if *desktop {
+			// serve runs without api credentials, so projects can't be resolved by name
+			d, err := newDesktopSink(ctx, *desktopProjects, nil)
+			if err != nil {
+				return err
+			}
+			srv.handlers = append(srv.handlers, d)
+		}
+		return srv.listen(ctx, *addr, *path)
+	},
+}
+
+// listen serves webhooks on addr until ctx is done
+func (s *eventServer) listen(ctx context.Context, addr, path string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("while listening for webhooks: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(path, s)
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+	// handlers finish the events acknowledged before shutdown
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.handle(context.WithoutCancel(ctx))
+	}()
+	slog.Info("receiving webhooks", "addr", ln.Addr().String(), "path", path, "handlers", len(s.handlers))
+	err = srv.Serve(ln)
+	if errors.Is(err, http.ErrServerClosed) {
+		// Serve returns as Shutdown starts, wait for in-flight deliveries before closing the queue
+		<-stopped
+		err = nil
+	}
+	close(s.queue)
+	<-done
+	return err
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// recordHandler records the events it handles
+type recordHandler struct {
+	events []Event
+}
+
+func (h *recordHandler) Handle(ctx context.Context, e Event) error {
+	h.events = append(h.events, e)
+	return nil
+}
+
+func TestEventServer(t *testing.T) {
+	secret := []byte("shh")
+	h := &recordHandler{}
+	srv := newEventServer(secret, splitKeys(eventCardMoved+","+eventCardCreated))
+	srv.handlers = append(srv.handlers, h)
+	deliver := func(body, signature string) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+		req.Header.Set(signatureHeader, signature)
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	signed := func(body string) int {
+		t.Helper()
+		return deliver(body, "sha256="+sign(secret, []byte(body)))
+	}
+
+	moved := `{"id":"evt-1","type":"card.moved","from":"Backlog","to":"Done"}`
+	if code := deliver(moved, "sha256="+sign([]byte("wrong"), []byte(moved))); code != http.StatusUnauthorized {
+		t.Errorf("forged delivery answered %d", code)
+	}
+	if code := signed(moved); code != http.StatusAccepted {
+		t.Errorf("delivery answered %d", code)
+	}
+	if code := signed(moved); code != http.StatusNoContent {
+		t.Errorf("queued redelivery answered %d", code)
+	}
+	if code := signed(`{"id":"evt-2","type":"comment.created"}`); code != http.StatusNoContent {
+		t.Errorf("filtered event answered %d", code)
+	}
+	if code := signed(`{"id":"evt-3"`); code != http.StatusBadRequest {
+		t.Errorf("invalid event answered %d", code)
+	}
+
+	close(srv.queue)
+	srv.handle(context.Background())
+	if len(h.events) != 1 || h.events[0].ID != "evt-1" || h.events[0].To != "Done" {
+		t.Fatalf("handled %+v, want evt-1 once", h.events)
+	}
+	if code := signed(moved); code != http.StatusNoContent {
+		t.Errorf("handled redelivery answered %d", code)
+	}
+}
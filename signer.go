@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// Signer signs refresh tokens, letting the private key live outside the process,
+// e.g. in a KMS, Vault or an SSH agent.
+type Signer interface {
+	// Algorithm is the jwt signing method of the signatures, e.g. RS256
+	Algorithm() string
+	// Sign returns the signature of the jwt signing input
+	Sign(ctx context.Context, signingInput []byte) ([]byte, error)
+}
+
+// SignerOption signs refresh tokens with signer instead of the key passed to NewClient
+func SignerOption(signer Signer) option {
+	return func(c *client) {
+		c.signers[0] = signer
+	}
+}
+
+// keySigner signs with a private key held in memory
+type keySigner struct {
+	key crypto.Signer
+}
+
+// KeySigner returns a Signer for a local RSA, ECDSA or Ed25519 private key
+func KeySigner(key crypto.Signer) Signer {
+	return keySigner{key: key}
+}
+
+// Algorithm picks the jwt signing method matching the key type, RS256 for rsa keys
+func (s keySigner) Algorithm() string {
+	switch k := s.key.(type) {
+	case *ecdsa.PrivateKey:
+		switch k.Curve.Params().BitSize {
+		case 384:
+			return "ES384"
+		case 521:
+			return "ES512"
+		}
+		return "ES256"
+	case ed25519.PrivateKey:
+		return "EdDSA"
+	}
+	return "RS256"
+}
+
+func (s keySigner) String() string {
+	switch k := s.key.(type) {
+	case *rsa.PrivateKey:
+		return fmt.Sprintf("rsa %d bit key", k.N.BitLen())
+	case *ecdsa.PrivateKey:
+		return "ecdsa " + k.Curve.Params().Name + " key"
+	case ed25519.PrivateKey:
+		return "ed25519 key"
+	}
+	return fmt.Sprintf("%T key", s.key)
+}
+
+func (s keySigner) Sign(_ context.Context, signingInput []byte) ([]byte, error) {
+	if s.key == nil || reflect.ValueOf(s.key).IsNil() {
+		return nil, errors.New("no private key to sign refresh tokens with")
+	}
+	return jwt.GetSigningMethod(s.Algorithm()).Sign(string(signingInput), s.key)
+}
+
+// agentSigner signs with an RSA key held by an SSH agent, over a connection Close ends
+type agentSigner struct {
+	conn  net.Conn
+	agent agent.ExtendedAgent
+	key   ssh.PublicKey
+}
+
+// newAgentSigner connects to the agent at SSH_AUTH_SOCK and picks the RSA key whose comment or
+// SHA256 fingerprint is match, the only RSA key when match is empty
+func newAgentSigner(match string) (Signer, error) {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, errors.New("SSH_AUTH_SOCK is not set, is an ssh agent running?")
+	}
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, fmt.Errorf("while connecting to ssh agent: %w", err)
+	}
+	a := agent.NewClient(conn)
+	keys, err := a.List()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("while listing ssh agent keys: %w", err)
+	}
+	var found []*agent.Key
+	for _, k := range keys {
+		if k.Type() != ssh.KeyAlgoRSA {
+			continue
+		}
+		if match == "" || k.Comment == match || ssh.FingerprintSHA256(k) == match {
+			found = append(found, k)
+		}
+	}
+	switch {
+	case len(found) == 0:
+		conn.Close()
+		return nil, fmt.Errorf("no rsa key matching %q in ssh agent", match)
+	case len(found) > 1:
+		conn.Close()
+		return nil, fmt.Errorf("%d rsa keys in ssh agent, choose one by comment or fingerprint with -signer ssh-agent:<key>", len(found))
+	}
+	return &agentSigner{conn: conn, agent: a, key: found[0]}, nil
+}
+
+func (s *agentSigner) Close() error { return s.conn.Close() }
+
+func (*agentSigner) Algorithm() string { return "RS256" }
+
+func (s *agentSigner) String() string {
+	return "ssh agent key " + ssh.FingerprintSHA256(s.key)
+}
+
+func (s *agentSigner) Sign(_ context.Context, signingInput []byte) ([]byte, error) {
+	// rsa-sha2-256 signatures are RSASSA-PKCS1-v1_5 with SHA-256, the same as RS256
+	sig, err := s.agent.SignWithFlags(s.key, signingInput, agent.SignatureFlagRsaSha256)
+	if err != nil {
+		return nil, fmt.Errorf("while signing with ssh agent: %w", err)
+	}
+	return sig.Blob, nil
+}
+
+// parseSigner returns the Signer described by spec:
+//
+//	ssh-agent[:<comment or fingerprint>]
+//	vault:<mount>/<key>, with VAULT_ADDR and VAULT_TOKEN
+//	awskms:<key id or arn>, with AWS_REGION and AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN
+//	gcpkms:projects/<p>/locations/<l>/keyRings/<r>/cryptoKeys/<k>/cryptoKeyVersions/<v>, with GOOGLE_OAUTH_ACCESS_TOKEN
+//
+// kms signers send their requests with httpClient
+func parseSigner(spec string, httpClient *http.Client) (Signer, error) {
+	kind, ref, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "ssh-agent":
+		return newAgentSigner(ref)
+	case "vault":
+		return newVaultSigner(ref, httpClient)
+	case "awskms":
+		return newAWSKMSSigner(ref, httpClient)
+	case "gcpkms":
+		return newGCPKMSSigner(ref, httpClient)
+	}
+	return nil, fmt.Errorf("unknown signer %q, expected ssh-agent, vault, awskms or gcpkms", spec)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/graphaelli/zube-notifications/zubetest"
+)
+
+// verifySigner exchanges a refresh token signed by signer and checks it against public
+func verifySigner(t *testing.T, signer Signer, public crypto.PublicKey) {
+	t.Helper()
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	var refreshToken string
+	capture := func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if strings.HasSuffix(req.URL.Path, "users/tokens") {
+				refreshToken = strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+			}
+			return next.RoundTrip(req)
+		})
+	}
+	c := newTestClient(srv, SignerOption(signer), MiddlewareOption(capture))
+	if _, err := c.ListProjects(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	_, err := jwt.Parse(refreshToken, func(*jwt.Token) (interface{}, error) { return public, nil },
+		jwt.WithValidMethods([]string{signer.Algorithm()}), jwt.WithIssuer("test"))
+	if err != nil {
+		t.Errorf("%s refresh token: %v", signer.Algorithm(), err)
+	}
+}
+
+func TestKeySigner(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		key       crypto.Signer
+		public    crypto.PublicKey
+		algorithm string
+	}{
+		{testKey, &testKey.PublicKey, "RS256"},
+		{ecKey, &ecKey.PublicKey, "ES384"},
+		{edKey, edPublic, "EdDSA"},
+	} {
+		signer := KeySigner(tc.key)
+		if got := signer.Algorithm(); got != tc.algorithm {
+			t.Errorf("%s algorithm %s, want %s", signer, got, tc.algorithm)
+		}
+		verifySigner(t, signer, tc.public)
+	}
+}
+
+func TestVaultSigner(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/transit/sign/zube/sha2-256" || r.Header.Get("X-Vault-Token") != "vault-token" {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+		var req struct {
+			Input string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		input, err := base64.StdEncoding.DecodeString(req.Input)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		digest := sha256.Sum256(input)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, testKey, crypto.SHA256, digest[:])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]string{"signature": "vault:v1:" + base64.StdEncoding.EncodeToString(sig)},
+		})
+	}))
+	defer vault.Close()
+	t.Setenv("VAULT_ADDR", vault.URL)
+	t.Setenv("VAULT_TOKEN", "vault-token")
+
+	signer, err := parseSigner("vault:transit/zube", vault.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifySigner(t, signer, &testKey.PublicKey)
+}
+
+func TestAgentSigner(t *testing.T) {
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: testKey, Comment: "zube"}); err != nil {
+		t.Fatal(err)
+	}
+	sock := filepath.Join(t.TempDir(), "agent.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", sock)
+
+	if _, err := parseSigner("ssh-agent:other", nil); err == nil {
+		t.Error("picked an agent key not matching the comment")
+	}
+	signer, err := parseSigner("ssh-agent:zube", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer signer.(*agentSigner).Close()
+	verifySigner(t, signer, &testKey.PublicKey)
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// snapshot is the complete notification configuration for the authenticated user
+type snapshot struct {
+	CreatedAt time.Time `json:"created_at"`
+	// Accounts are only captured when -scope includes account
+	Accounts []accountSnapshot `json:"accounts,omitempty"`
+	Projects []projectSnapshot `json:"projects"`
+}
+
+type accountSnapshot struct {
+	ID    int            `json:"id"`
+	Name  string         `json:"name"`
+	Email UserPreference `json:"email"`
+	InApp UserPreference `json:"in_app"`
+}
+
+type projectSnapshot struct {
+	ID                      int                 `json:"id"`
+	Name                    string              `json:"name"`
+	Email                   UserPreference      `json:"email"`
+	InApp                   UserPreference      `json:"in_app"`
+	SubscriptionLevel       string              `json:"subscription_level"`
+	TriageSubscriptionLevel string              `json:"triage_subscription_level"`
+	Workspaces              []workspaceSnapshot `json:"workspaces"`
+}
+
+type workspaceSnapshot struct {
+	ID                int            `json:"id"`
+	Name              string         `json:"name"`
+	Email             UserPreference `json:"email"`
+	InApp             UserPreference `json:"in_app"`
+	SubscriptionLevel string         `json:"subscription_level"`
+}
+
+var snapshotCommand = &command{
+	name:  "snapshot",
+	usage: "snapshot [file] - write all notification preferences to file, stdout by default",
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		snap, err := s.snapshot(ctx)
+		if err != nil {
+			return err
+		}
+		var w io.Writer = os.Stdout
+		if len(args) > 0 && args[0] != "-" {
+			f, err := os.OpenFile(args[0], os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(snap)
+	},
+}
+
+var restoreCommand = &command{
+	name:  "restore",
+	usage: "restore [-verify=false] <file> - replay preferences from a snapshot file and check they took effect",
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		fs := flag.NewFlagSet("restore", flag.ExitOnError)
+		verify := fs.Bool("verify", true, "re-fetch written objects, retrying and reporting those that don't match the snapshot")
+		fs.Parse(args)
+		if fs.NArg() != 1 {
+			return errors.New("restore requires a snapshot file")
+		}
+		b, err := os.ReadFile(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		var snap snapshot
+		if err := json.Unmarshal(b, &snap); err != nil {
+			return fmt.Errorf("while decoding snapshot %s: %w", fs.Arg(0), err)
+		}
+		s.skipUnchanged = true
+		if *verify {
+			s.verifier = &verifier{}
+		}
+		err = s.restore(ctx, &snap)
+		return errors.Join(err, s.verify(ctx))
+	},
+}
+
+// snapshot captures every project and workspace preference, and account ones when in scope
+func (s *sweep) snapshot(ctx context.Context) (*snapshot, error) {
+	projects, err := s.targetProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snap := &snapshot{CreatedAt: time.Now().UTC()}
+	if s.inScope(accountScope) {
+		if snap.Accounts, err = s.snapshotAccounts(ctx); err != nil {
+			return nil, err
+		}
+	}
+	for _, project := range projects {
+		ps, err := s.snapshotProject(ctx, project)
+		if err != nil {
+			return nil, fmt.Errorf("project %s (%d): %w", project.Name, project.ID, err)
+		}
+		snap.Projects = append(snap.Projects, *ps)
+	}
+	return snap, nil
+}
+
+func (s *sweep) snapshotAccounts(ctx context.Context) ([]accountSnapshot, error) {
+	accounts, err := s.client.ListAccounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("accounts: %w", err)
+	}
+	snaps := make([]accountSnapshot, 0, len(accounts))
+	for _, account := range accounts {
+		as := accountSnapshot{ID: account.ID, Name: account.Name}
+		if as.Email, err = s.client.AccountEmailPreferences(ctx, account.ID); err != nil {
+			return nil, fmt.Errorf("account %s (%d): %w", account.Name, account.ID, err)
+		}
+		if as.InApp, err = s.client.AccountInAppPreferences(ctx, account.ID); err != nil {
+			return nil, fmt.Errorf("account %s (%d): %w", account.Name, account.ID, err)
+		}
+		snaps = append(snaps, as)
+	}
+	return snaps, nil
+}
+
+func (s *sweep) snapshotProject(ctx context.Context, project Project) (*projectSnapshot, error) {
+	client := s.client
+	ps := &projectSnapshot{ID: project.ID, Name: project.Name}
+	// project preferences are only captured when -group includes the whole project, restore skips them otherwise
+	if s.group.hasWholeProject(project) {
+		var err error
+		if ps.Email, err = client.ProjectEmailPreferences(ctx, project.ID); err != nil {
+			return nil, err
+		}
+		if ps.InApp, err = client.ProjectInAppPreferences(ctx, project.ID); err != nil {
+			return nil, err
+		}
+		settings, err := client.ProjectUserSettings(ctx, project.ID)
+		if err != nil {
+			return nil, err
+		}
+		ps.SubscriptionLevel = settings.SubscriptionLevel
+		triage, err := client.ProjectTriageUserSettings(ctx, project.ID)
+		if err != nil {
+			return nil, err
+		}
+		ps.TriageSubscriptionLevel = triage.SubscriptionLevel
+	}
+
+	workspaces, err := client.ListWorkspaces(ctx, project.ID)
+	if err != nil {
+		return nil, err
+	}
+	workspaces = s.groupWorkspaces(project, workspaces)
+	ps.Workspaces = make([]workspaceSnapshot, len(workspaces))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(s.concurrency)
+	for i, w := range workspaces {
+		i, workspace := i, w
+		g.Go(func() error {
+			ws := workspaceSnapshot{ID: workspace.ID, Name: workspace.Name}
+			var err error
+			if ws.Email, err = client.WorkspaceEmailPreferences(gctx, workspace.ID); err != nil {
+				return fmt.Errorf("workspace %s (%d): %w", workspace.Name, workspace.ID, err)
+			}
+			if ws.InApp, err = client.WorkspaceInAppPreferences(gctx, workspace.ID); err != nil {
+				return fmt.Errorf("workspace %s (%d): %w", workspace.Name, workspace.ID, err)
+			}
+			settings, err := client.WorkspaceUserSettings(gctx, workspace.ID)
+			if err != nil {
+				return fmt.Errorf("workspace %s (%d): %w", workspace.Name, workspace.ID, err)
+			}
+			ws.SubscriptionLevel = settings.SubscriptionLevel
+			ps.Workspaces[i] = ws
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return ps, nil
+}
+
+// restore writes the preferences in snap back, accounts, projects and workspaces concurrently, continuing past failures
+func (s *sweep) restore(ctx context.Context, snap *snapshot) error {
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+	var g errgroup.Group
+	g.SetLimit(s.concurrency)
+	for _, a := range snap.Accounts {
+		as := a
+		g.Go(func() error {
+			if err := s.restorePreferences(ctx, "accounts", as.ID, as.Email, as.InApp); err != nil {
+				fail(fmt.Errorf("account %s (%d): %w", as.Name, as.ID, err))
+			} else {
+				s.outcome.succeed()
+			}
+			return nil
+		})
+	}
+	for _, p := range snap.Projects {
+		if ctx.Err() != nil {
+			break
+		}
+		ps := p
+		g.Go(func() error {
+			ok := true
+			if err := s.restorePreferences(ctx, "projects", ps.ID, ps.Email, ps.InApp); err != nil {
+				fail(fmt.Errorf("project %s (%d): %w", ps.Name, ps.ID, err))
+				ok = false
+			}
+			if err := s.restoreSubscriptionLevel(ctx, "projects", ps.ID, false, ps.SubscriptionLevel); err != nil {
+				fail(fmt.Errorf("project %s (%d): %w", ps.Name, ps.ID, err))
+				ok = false
+			}
+			if err := s.restoreSubscriptionLevel(ctx, "projects", ps.ID, true, ps.TriageSubscriptionLevel); err != nil {
+				fail(fmt.Errorf("project %s (%d) triage: %w", ps.Name, ps.ID, err))
+				ok = false
+			}
+			if ok {
+				s.outcome.succeed()
+			}
+			return nil
+		})
+		for _, w := range ps.Workspaces {
+			ws := w
+			g.Go(func() error {
+				ok := true
+				if err := s.restorePreferences(ctx, "workspaces", ws.ID, ws.Email, ws.InApp); err != nil {
+					fail(fmt.Errorf("workspace %s/%s (%d): %w", ps.Name, ws.Name, ws.ID, err))
+					ok = false
+				}
+				if err := s.restoreSubscriptionLevel(ctx, "workspaces", ws.ID, false, ws.SubscriptionLevel); err != nil {
+					fail(fmt.Errorf("workspace %s/%s (%d): %w", ps.Name, ws.Name, ws.ID, err))
+					ok = false
+				}
+				if ok {
+					s.outcome.succeed()
+				}
+				return nil
+			})
+		}
+	}
+	g.Wait()
+	if ctx.Err() != nil {
+		errs = append(errs, ctx.Err())
+	}
+	return errors.Join(errs...)
+}
+
+// restorePreferences replaces the live email and in-app preferences of an object with saved ones
+func (s *sweep) restorePreferences(ctx context.Context, object string, objectId int, email, inApp UserPreference) error {
+	var errs []error
+	for _, saved := range []struct {
+		prefType string
+		prefs    UserPreference
+	}{{emailPreferences, email}, {inAppPreferences, inApp}} {
+		if saved.prefs == nil {
+			continue
+		}
+		current, err := s.client.notificationPreferences(ctx, objectId, object, saved.prefType)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := s.update(ctx, object, objectId, saved.prefType, current, saved.prefs); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *sweep) restoreSubscriptionLevel(ctx context.Context, object string, objectId int, triage bool, level string) error {
+	if level == "" {
+		return nil
+	}
+	current, err := s.client.userSettings(ctx, objectId, object, triage)
+	if err != nil {
+		return err
+	}
+	return s.setSubscriptionLevel(ctx, object, objectId, triage, current, level)
+}
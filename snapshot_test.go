@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/graphaelli/zube-notifications/zubetest"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	p := srv.AddProject("proj-a")
+	w := srv.AddWorkspace(p.ID, "web")
+	srv.SetPreference("workspaces", w.ID, zubetest.InAppPreferences, "card_moved", false)
+	ctx := context.Background()
+
+	s := newTestSweep(srv)
+	snap, err := s.snapshot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := os.WriteFile(file, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// restoring what is already live writes nothing
+	if err := restoreCommand.run(ctx, newTestSweep(srv), []string{file}); err != nil {
+		t.Fatal(err)
+	}
+	if writes := srv.Writes(); len(writes) > 0 {
+		t.Errorf("restoring the live state wrote %v", writes)
+	}
+
+	s = newTestSweep(srv)
+	s.email, s.inApp = disableAction, enableAction
+	if err := defaultCommand.run(ctx, s, nil); err != nil {
+		t.Fatal(err)
+	}
+	before := countWrites(srv, http.MethodPut)
+	if err := restoreCommand.run(ctx, newTestSweep(srv), []string{file}); err != nil {
+		t.Fatal(err)
+	}
+	if email := srv.Preferences("projects", p.ID, zubetest.EmailPreferences); email["card_moved"] != true {
+		t.Errorf("project email not restored: %v", email)
+	}
+	if in := srv.Preferences("workspaces", w.ID, zubetest.InAppPreferences); in["card_moved"] != false {
+		t.Errorf("workspace in-app not restored: %v", in)
+	}
+	// both email preferences and the workspace in-app ones differ from the snapshot
+	if puts := countWrites(srv, http.MethodPut) - before; puts != 3 {
+		t.Errorf("%d restore writes, want 3", puts)
+	}
+}
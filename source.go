@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+	"net/http"
+)
+
+func (c *client) ListSources(ctx context.Context, projectId int) ([]Sources, error) {
+	return paginate[Sources](ctx, c, apiPath("projects", projectId, "sources"), nil)
+}
+
+func (c *client) GetSource(ctx context.Context, sourceId int) (*Sources, error) {
+	var source Sources
+	if err := c.call(ctx, http.MethodGet, apiPath("sources", sourceId), nil, nil, &source); err != nil {
+		return nil, err
+	}
+	return &source, nil
+}
+
+// WebhookVerified reports whether GitHub has delivered a webhook to Zube for this source
+func (s Sources) WebhookVerified() bool {
+	return !s.WebhookVerifiedAt.IsZero()
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"context"
+	"net/http"
+)
+
+func (c *client) ListSprints(ctx context.Context, workspaceId int) ([]Sprint, error) {
+	return paginate[Sprint](ctx, c, apiPath("workspaces", workspaceId, "sprints"), nil)
+}
+
+func (c *client) GetSprint(ctx context.Context, workspaceId, sprintId int) (*Sprint, error) {
+	var sprint Sprint
+	if err := c.call(ctx, http.MethodGet, apiPath("workspaces", workspaceId, "sprints", sprintId), nil, nil, &sprint); err != nil {
+		return nil, err
+	}
+	return &sprint, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+)
+
+func defaultStatePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "zube", "state.db")
+}
+
+// maxStateValue bounds how much of a value state show prints
+const maxStateValue = 120
+
+var stateCommand = &command{
+	name:    "state",
+	usage:   "state show [bucket] | reset [bucket] - inspect or clear the -state-db",
+	offline: true,
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		if s.store == nil {
+			return errors.New("state requires -state-db")
+		}
+		if len(args) == 0 || len(args) > 2 {
+			return errors.New("state requires show or reset and an optional bucket")
+		}
+		bucket := ""
+		if len(args) == 2 {
+			bucket = args[1]
+		}
+		switch args[0] {
+		case "show":
+			entries, err := s.store.Entries(bucket)
+			if err != nil {
+				return err
+			}
+			if len(entries) == 0 {
+				fmt.Printf("no state in %s\n", s.store.Path())
+				return nil
+			}
+			tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "BUCKET\tKEY\tSIZE\tVALUE")
+			for _, e := range entries {
+				value := string(e.Value)
+				if len(value) > maxStateValue {
+					value = value[:maxStateValue] + "..."
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", e.Bucket, e.Key, len(e.Value), value)
+			}
+			return tw.Flush()
+		case "reset":
+			if err := s.store.Reset(bucket); err != nil {
+				return err
+			}
+			if bucket == "" {
+				fmt.Printf("cleared all state in %s\n", s.store.Path())
+			} else {
+				fmt.Printf("cleared %s state in %s\n", bucket, s.store.Path())
+			}
+			return nil
+		}
+		return fmt.Errorf("unknown state command %q", args[0])
+	},
+}
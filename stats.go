@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// keyStats counts where a preference key is enabled
+type keyStats struct {
+	Key             string `json:"key"`
+	EmailProjects   int    `json:"email_projects"`
+	EmailWorkspaces int    `json:"email_workspaces"`
+	InAppProjects   int    `json:"in_app_projects"`
+	InAppWorkspaces int    `json:"in_app_workspaces"`
+}
+
+// stats aggregates enabled preference keys across projects and workspaces
+type stats struct {
+	mu         sync.Mutex
+	Projects   int
+	Workspaces int
+	keys       map[string]*keyStats
+}
+
+func (st *stats) add(workspace bool, email, inApp UserPreference) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if workspace {
+		st.Workspaces++
+	} else {
+		st.Projects++
+	}
+	count := func(prefs UserPreference, projects, workspaces func(*keyStats) *int) {
+		for k, v := range prefs {
+			b, isBool := v.(bool)
+			if !isBool {
+				continue
+			}
+			ks, ok := st.keys[k]
+			if !ok {
+				ks = &keyStats{Key: k}
+				st.keys[k] = ks
+			}
+			if !b {
+				continue
+			}
+			if workspace {
+				*workspaces(ks)++
+			} else {
+				*projects(ks)++
+			}
+		}
+	}
+	count(email, func(ks *keyStats) *int { return &ks.EmailProjects }, func(ks *keyStats) *int { return &ks.EmailWorkspaces })
+	count(inApp, func(ks *keyStats) *int { return &ks.InAppProjects }, func(ks *keyStats) *int { return &ks.InAppWorkspaces })
+}
+
+// sorted returns keys enabled in the most places first
+func (st *stats) sorted() []keyStats {
+	keys := make([]keyStats, 0, len(st.keys))
+	for _, ks := range st.keys {
+		keys = append(keys, *ks)
+	}
+	total := func(ks keyStats) int {
+		return ks.EmailProjects + ks.EmailWorkspaces + ks.InAppProjects + ks.InAppWorkspaces
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if ti, tj := total(keys[i]), total(keys[j]); ti != tj {
+			return ti > tj
+		}
+		return keys[i].Key < keys[j].Key
+	})
+	return keys
+}
+
+func (st *stats) render(w io.Writer, format string) error {
+	keys := st.sorted()
+	if format == jsonFormat {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Projects   int        `json:"projects"`
+			Workspaces int        `json:"workspaces"`
+			Keys       []keyStats `json:"keys"`
+		}{st.Projects, st.Workspaces, keys})
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tEMAIL PROJECTS\tEMAIL WORKSPACES\tIN-APP PROJECTS\tIN-APP WORKSPACES")
+	for _, ks := range keys {
+		fmt.Fprintf(tw, "%s\t%d/%d\t%d/%d\t%d/%d\t%d/%d\n", ks.Key,
+			ks.EmailProjects, st.Projects, ks.EmailWorkspaces, st.Workspaces,
+			ks.InAppProjects, st.Projects, ks.InAppWorkspaces, st.Workspaces)
+	}
+	return tw.Flush()
+}
+
+var statsCommand = &command{
+	name:  "stats",
+	usage: "stats - count where each preference key is enabled across projects and workspaces",
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		projects, err := s.targetProjects(ctx)
+		if err != nil {
+			return err
+		}
+		st := &stats{keys: make(map[string]*keyStats)}
+		err = s.collectStats(ctx, st, projects)
+		// show what was counted even when some objects failed
+		if rerr := st.render(s.reportWriter(), s.format); rerr != nil {
+			return errors.Join(err, rerr)
+		}
+		return err
+	},
+}
+
+func (s *sweep) collectStats(ctx context.Context, st *stats, projects []Project) error {
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+	preferences := func(object string, id int) (UserPreference, UserPreference, error) {
+		email, err := s.client.notificationPreferences(ctx, id, object, emailPreferences)
+		if err != nil {
+			return nil, nil, err
+		}
+		inApp, err := s.client.notificationPreferences(ctx, id, object, inAppPreferences)
+		return email, inApp, err
+	}
+	var g errgroup.Group
+	g.SetLimit(s.concurrency)
+	for _, p := range projects {
+		project := p
+		g.Go(func() error {
+			email, inApp, err := preferences("projects", project.ID)
+			if err != nil {
+				fail(fmt.Errorf("project %s (%d): %w", project.Name, project.ID, err))
+			} else {
+				st.add(false, email, inApp)
+				s.outcome.succeed()
+			}
+			workspaces, err := s.client.ListWorkspaces(ctx, project.ID)
+			if err != nil {
+				fail(fmt.Errorf("project %s (%d) workspaces: %w", project.Name, project.ID, err))
+				return nil
+			}
+			for _, workspace := range s.activeWorkspaces(project, workspaces) {
+				email, inApp, err := preferences("workspaces", workspace.ID)
+				if err != nil {
+					fail(fmt.Errorf("workspace %s/%s (%d): %w", project.Name, workspace.Name, workspace.ID, err))
+					continue
+				}
+				st.add(true, email, inApp)
+				s.outcome.succeed()
+			}
+			return nil
+		})
+	}
+	g.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,175 @@
+// Package store keeps durable state for long running features, like delivered notification
+// ids and do not disturb windows, in an embedded bbolt database.
+//
+// The database is opened for each operation rather than held open, so a running daemon
+// doesn't lock out other invocations sharing the same file.
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// openTimeout bounds waiting for another process to release the database
+const openTimeout = 5 * time.Second
+
+// Store is a bucketed key value store of json values
+type Store struct {
+	path string
+}
+
+// New returns a store backed by the database at path, created on first write
+func New(path string) *Store {
+	return &Store{path: path}
+}
+
+// Path is the database file
+func (s *Store) Path() string {
+	return s.path
+}
+
+func (s *Store) open(readOnly bool) (*bolt.DB, error) {
+	if readOnly {
+		if _, err := os.Stat(s.path); err != nil {
+			return nil, err
+		}
+	} else if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(s.path, 0600, &bolt.Options{Timeout: openTimeout, ReadOnly: readOnly})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("state store %s is locked by another process", s.path)
+	} else if err != nil {
+		return nil, fmt.Errorf("while opening state store %s: %w", s.path, err)
+	}
+	return db, nil
+}
+
+func (s *Store) view(fn func(tx *bolt.Tx) error) error {
+	db, err := s.open(true)
+	if errors.Is(err, os.ErrNotExist) {
+		// nothing was ever stored
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(fn)
+}
+
+func (s *Store) update(fn func(tx *bolt.Tx) error) error {
+	db, err := s.open(false)
+	if err != nil {
+		return err
+	}
+	if err := db.Update(fn); err != nil {
+		db.Close()
+		return err
+	}
+	return db.Close()
+}
+
+// Get decodes the value of key in bucket into v, reporting whether it was found
+func (s *Store) Get(bucket, key string, v interface{}) (bool, error) {
+	var b []byte
+	err := s.view(func(tx *bolt.Tx) error {
+		if bk := tx.Bucket([]byte(bucket)); bk != nil {
+			if value := bk.Get([]byte(key)); value != nil {
+				b = append([]byte(nil), value...)
+			}
+		}
+		return nil
+	})
+	if err != nil || b == nil {
+		return false, err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return false, fmt.Errorf("while decoding %s/%s: %w", bucket, key, err)
+	}
+	return true, nil
+}
+
+// Put stores v as json under key in bucket
+func (s *Store) Put(bucket, key string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.update(func(tx *bolt.Tx) error {
+		bk, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+		return bk.Put([]byte(key), b)
+	})
+}
+
+// Delete removes key from bucket, a missing key is not an error
+func (s *Store) Delete(bucket, key string) error {
+	return s.update(func(tx *bolt.Tx) error {
+		if bk := tx.Bucket([]byte(bucket)); bk != nil {
+			return bk.Delete([]byte(key))
+		}
+		return nil
+	})
+}
+
+// Entry is a stored value
+type Entry struct {
+	Bucket string
+	Key    string
+	Value  json.RawMessage
+}
+
+// Entries returns every entry, of bucket only unless it is empty, ordered by bucket and key
+func (s *Store) Entries(bucket string) ([]Entry, error) {
+	var entries []Entry
+	err := s.view(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bk *bolt.Bucket) error {
+			if bucket != "" && string(name) != bucket {
+				return nil
+			}
+			return bk.ForEach(func(k, v []byte) error {
+				entries = append(entries, Entry{Bucket: string(name), Key: string(k), Value: append(json.RawMessage(nil), v...)})
+				return nil
+			})
+		})
+	})
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Bucket != entries[j].Bucket {
+			return entries[i].Bucket < entries[j].Bucket
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	return entries, err
+}
+
+// Reset deletes bucket, or every bucket when it is empty
+func (s *Store) Reset(bucket string) error {
+	return s.update(func(tx *bolt.Tx) error {
+		if bucket != "" {
+			if err := tx.DeleteBucket([]byte(bucket)); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+				return err
+			}
+			return nil
+		}
+		var names [][]byte
+		tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			names = append(names, append([]byte(nil), name...))
+			return nil
+		})
+		for _, name := range names {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func subscribeCommandFor(name, usage, defaultLevel string) *command {
+	return &command{
+		name:  name,
+		usage: usage,
+		run: func(ctx context.Context, s *sweep, args []string) error {
+			if len(args) < 2 || (args[0] != "workspace" && args[0] != "project") {
+				return fmt.Errorf("%s requires workspace or project and a slug", name)
+			}
+			fs := flag.NewFlagSet(name, flag.ExitOnError)
+			level := fs.String("level", defaultLevel, "subscription level, e.g. mute, participating or everything")
+			projectName := fs.String("project", "", "project id, name or slug of the workspace, when its slug is ambiguous")
+			github := fs.Bool("github", false, "also ignore, watch or stop ignoring the github repositories synced to the project")
+			githubToken := fs.String("github-token", os.Getenv("GITHUB_TOKEN"), "github token for -github, with the repo or notifications scope")
+			githubURL := fs.String("github-api-url", "https://api.github.com", "github api url, for github enterprise server")
+			fs.Parse(args[2:])
+			s.showChanges = true
+			if args[0] == "project" {
+				if *github && *githubToken == "" {
+					return errors.New("-github requires -github-token or GITHUB_TOKEN")
+				}
+				project, err := s.resolveProject(ctx, args[1])
+				if err != nil {
+					return err
+				}
+				err = s.subscribeTo(ctx, "projects", project.ID, project.Name, *level)
+				if *github {
+					err = errors.Join(err, s.subscribeGitHub(ctx, newGitHubClient(*githubToken, *githubURL, s.client.transport.externalClient()), project.ID, *level))
+				}
+				return err
+			}
+			if *github {
+				return errors.New("-github requires a project, repositories are synced per project")
+			}
+			workspace, err := s.resolveWorkspace(ctx, *projectName, args[1])
+			if err != nil {
+				return err
+			}
+			return s.subscribeTo(ctx, "workspaces", workspace.ID, workspace.Name, *level)
+		},
+	}
+}
+
+// subscribeTo sets the subscription level of a project or workspace
+func (s *sweep) subscribeTo(ctx context.Context, object string, objectId int, name, level string) error {
+	if level == "" {
+		return errors.New("-level must not be empty")
+	}
+	current, err := s.client.userSettings(ctx, objectId, object, false)
+	if err != nil {
+		return err
+	}
+	if current.SubscriptionLevel == level {
+		s.printf("%s is already %s\n", name, level)
+		return nil
+	}
+	s.printf("%s:\n", name)
+	return s.setSubscriptionLevel(ctx, object, objectId, false, current, level)
+}
+
+var (
+	subscribeCommand = subscribeCommandFor("subscribe",
+		"subscribe workspace|project <slug> [-level everything] [-github] - change the subscription level of one workspace or project", "everything")
+	unsubscribeCommand = subscribeCommandFor("unsubscribe",
+		"unsubscribe workspace|project <slug> [-github] - mute one workspace or project, and its github repositories", "mute")
+)
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// summary tallies what a run actually changed, printed once every account is done
+type summary struct {
+	mu         sync.Mutex
+	started    time.Time
+	projects   map[int]bool
+	workspaces map[int]bool
+	// changes counts changed keys by preference type, direction and key
+	changes map[string]map[string]map[string]int
+}
+
+func newSummary() *summary {
+	return &summary{
+		started:    time.Now(),
+		projects:   make(map[int]bool),
+		workspaces: make(map[int]bool),
+		changes:    make(map[string]map[string]map[string]int),
+	}
+}
+
+// direction classifies a change as enabled, disabled, reset or set
+func direction(c preferenceChange) string {
+	switch c.After {
+	case true:
+		return "enabled"
+	case false:
+		return "disabled"
+	case nil:
+		return "reset"
+	}
+	return "set"
+}
+
+// record counts the changes of a successful write, sum may be nil
+func (sum *summary) record(object string, objectId int, prefType string, changes []preferenceChange) {
+	if sum == nil || len(changes) == 0 {
+		return
+	}
+	sum.mu.Lock()
+	defer sum.mu.Unlock()
+	switch object {
+	case "projects":
+		sum.projects[objectId] = true
+	case "workspaces":
+		sum.workspaces[objectId] = true
+	}
+	byDirection, ok := sum.changes[prefType]
+	if !ok {
+		byDirection = make(map[string]map[string]int)
+		sum.changes[prefType] = byDirection
+	}
+	for _, c := range changes {
+		d := direction(c)
+		if byDirection[d] == nil {
+			byDirection[d] = make(map[string]int)
+		}
+		byDirection[d][c.Key]++
+	}
+}
+
+// summaryReport is the rendered summary, also written as json with -summary-file
+type summaryReport struct {
+	Projects   int                                  `json:"projects_touched"`
+	Workspaces int                                  `json:"workspaces_touched"`
+	Changes    map[string]map[string]map[string]int `json:"changes"`
+	APICalls   uint64                               `json:"api_calls"`
+	Elapsed    string                               `json:"elapsed"`
+	Failures   int                                  `json:"failures"`
+}
+
+func (sum *summary) report(m *metrics, failures int) summaryReport {
+	sum.mu.Lock()
+	defer sum.mu.Unlock()
+	return summaryReport{
+		Projects:   len(sum.projects),
+		Workspaces: len(sum.workspaces),
+		Changes:    sum.changes,
+		APICalls:   m.total(),
+		Elapsed:    time.Since(sum.started).Round(time.Millisecond).String(),
+		Failures:   failures,
+	}
+}
+
+// touched reports whether the run changed anything
+func (r summaryReport) touched() bool {
+	return len(r.Changes) > 0
+}
+
+// render writes the summary as text, failures in red when color is set
+func (r summaryReport) render(w io.Writer, color bool) {
+	fmt.Fprintf(w, "\nsummary:\n")
+	fmt.Fprintf(w, "\tprojects touched:   %d\n", r.Projects)
+	fmt.Fprintf(w, "\tworkspaces touched: %d\n", r.Workspaces)
+	prefTypes := make([]string, 0, len(r.Changes))
+	for t := range r.Changes {
+		prefTypes = append(prefTypes, t)
+	}
+	sort.Strings(prefTypes)
+	for _, t := range prefTypes {
+		fmt.Fprintf(w, "\t%s:\n", t)
+		for _, d := range []string{"disabled", "enabled", "reset", "set"} {
+			keys := r.Changes[t][d]
+			if len(keys) == 0 {
+				continue
+			}
+			names := make([]string, 0, len(keys))
+			for k := range keys {
+				names = append(names, k)
+			}
+			sort.Strings(names)
+			counts := make([]string, len(names))
+			total := 0
+			for i, k := range names {
+				counts[i] = fmt.Sprintf("%s (%d)", k, keys[k])
+				total += keys[k]
+			}
+			fmt.Fprintf(w, "\t\t%d %s: %s\n", total, d, strings.Join(counts, ", "))
+		}
+	}
+	fmt.Fprintf(w, "\tapi calls:          %d\n", r.APICalls)
+	fmt.Fprintf(w, "\telapsed:            %s\n", r.Elapsed)
+	failures := fmt.Sprint(r.Failures)
+	if color && r.Failures > 0 {
+		failures = colorRed + failures + colorReset
+	}
+	fmt.Fprintf(w, "\tfailures:           %s\n", failures)
+}
+
+// writeFile writes the summary as json to path
+func (r summaryReport) writeFile(path string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0600)
+}
@@ -0,0 +1,671 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/graphaelli/zube-notifications/store"
+	"golang.org/x/sync/errgroup"
+)
+
+// action is the change requested for one kind of notification preference
+type action int
+
+const (
+	noAction action = iota
+	disableAction
+	enableAction
+	resetAction
+)
+
+func preferenceAction(disable, enable, defaults bool) action {
+	switch {
+	case disable:
+		return disableAction
+	case enable && defaults:
+		return resetAction
+	case enable:
+		return enableAction
+	}
+	return noAction
+}
+
+// sweep reports on and updates notification preferences across projects and their workspaces
+type sweep struct {
+	client *client
+	filter keyFilter
+	email  action
+	inApp  action
+	dryRun bool
+	// subscriptionLevel, when set, is applied to every project and workspace
+	subscriptionLevel string
+	// triageLevel, when set, is applied to every project's triage settings
+	triageLevel string
+	// labels, when set, restricts changes to workspaces with open cards carrying one of these label names
+	labels map[string]bool
+	// scopes limits changes to account, project and/or workspace preferences, nil means project and workspace
+	scopes map[string]bool
+	// triageComment, when set, is posted on each open triage card
+	triageComment string
+	// skipUnchanged avoids updates that would not change anything
+	skipUnchanged bool
+	// showChanges prints the keys changed by every update
+	showChanges bool
+	// concurrency bounds the number of workspaces processed at once
+	concurrency int
+	// config holds named preference profiles
+	config *config
+	// metrics, when set, counts applied changes and failures
+	metrics *metrics
+	// out receives the report, stdout when nil
+	out io.Writer
+	// report, when set, collects project and workspace summaries instead of printing them
+	report *report
+	format string
+	// reportOut receives reports rendered in format, stdout when nil
+	reportOut io.Writer
+	// outcome, when set, tallies changes and successes for the exit code
+	outcome *outcome
+	// auditLog, when set, records every mutation
+	auditLog *auditLog
+	// store, when set, keeps state across runs
+	store *store.Store
+	// verifier, when set, collects writes to check with verify
+	verifier *verifier
+	// summary, when set, tallies what was changed
+	summary *summary
+	// color highlights quiet and noisy objects in the text report
+	color bool
+	// quiet suppresses the per-object report, changes are still shown
+	quiet bool
+	// progress, when set, receives an event for every processed object
+	progress *progress
+	// actions, when set, annotates findings for github actions workflows
+	actions *actions
+	// planner, when set, collects preference updates instead of making them
+	planner *planner
+	// group, when set, limits projects and workspaces to those of a config group
+	group *workspaceGroup
+	// includeArchived processes archived workspaces, which are skipped otherwise
+	includeArchived bool
+	// estimate adds expected notifications per week, from recent workspace activity, to reports
+	estimate bool
+	// keyErr is why the private key or signer couldn't be loaded, for commands that diagnose it
+	keyErr error
+}
+
+// apply performs a on the prefType preferences of the object identified by objectId
+func (s *sweep) apply(ctx context.Context, object string, objectId int, prefType string, prefs UserPreference, a action) error {
+	prefId := preferenceId(prefs)
+	switch a {
+	case noAction:
+		return nil
+	case resetAction:
+		if s.dryRun {
+			s.printf("\t[dry-run] %s %d %s: reset to defaults\n", strings.TrimSuffix(object, "s"), objectId, prefType)
+			s.outcome.changed()
+			return nil
+		}
+		status, err := s.client.resetNotifications(ctx, objectId, object, prefId, prefType)
+		// the defaults zube falls back to are unknown, record what was replaced so it can be undone
+		var replaced []preferenceChange
+		for _, k := range sortedKeys(prefs) {
+			if _, isBool := prefs[k].(bool); isBool {
+				replaced = append(replaced, preferenceChange{Key: k, Before: prefs[k]})
+			}
+		}
+		if err := s.recordAudit(http.MethodDelete, object, objectId, prefType, replaced, status, err); err != nil {
+			return err
+		}
+		s.outcome.changed()
+		return nil
+	}
+
+	desired := copyPreferences(prefs)
+	var flipped bool
+	state := "disabled"
+	if a == disableAction {
+		flipped = disableAll(desired, s.filter)
+	} else {
+		flipped, state = enableAll(desired, s.filter), "enabled"
+	}
+	if !flipped {
+		// avoid a write, and an entry in zube's activity, that changes nothing
+		s.narrate("\t%s %d %s: already %s\n", strings.TrimSuffix(object, "s"), objectId, prefType, state)
+		return nil
+	}
+	return s.update(ctx, object, objectId, prefType, prefs, desired)
+}
+
+// conflictRetries bounds how often a write refused as stale is reapplied to freshly read preferences
+const conflictRetries = 3
+
+// update replaces the current prefType preferences of the object identified by objectId with desired
+func (s *sweep) update(ctx context.Context, object string, objectId int, prefType string, current, desired UserPreference) error {
+	desired = withVersion(desired, current)
+	changes := diffPreferences(current, desired)
+	if s.skipUnchanged && len(changes) == 0 {
+		return nil
+	}
+	if s.dryRun || s.showChanges {
+		prefix := ""
+		if s.dryRun {
+			prefix = "[dry-run] "
+		}
+		s.printf("\t%s%s %d %s: %d changes\n", prefix, strings.TrimSuffix(object, "s"), objectId, prefType, len(changes))
+		for _, c := range changes {
+			s.printf("\t\t%s\n", c)
+		}
+	}
+	if s.planner != nil {
+		s.planner.add(plannedChange{Object: object, ObjectID: objectId, PrefType: prefType, Before: current, After: desired, Changes: changes})
+		return nil
+	}
+	if s.dryRun {
+		if len(changes) > 0 {
+			s.outcome.changed()
+		}
+		return nil
+	}
+
+	var (
+		status int
+		err    error
+	)
+	for attempt := 0; ; attempt++ {
+		payload := new(bytes.Buffer)
+		if err := json.NewEncoder(payload).Encode(desired); err != nil {
+			return err
+		}
+		status, err = s.client.updateNotifications(ctx, objectId, object, preferenceId(current), prefType, payload)
+		if !errors.Is(err, ErrConflict) || attempt == conflictRetries {
+			break
+		}
+		// another session changed the preferences since they were read, reapply on top of its changes
+		fresh, ferr := s.client.notificationPreferences(ctx, objectId, object, prefType)
+		if ferr != nil {
+			err = errors.Join(err, ferr)
+			break
+		}
+		current, desired = fresh, withVersion(rebase(current, desired, fresh), fresh)
+		changes = diffPreferences(current, desired)
+		s.printf("\t%s %d %s: changed since read, reapplying %d changes\n", strings.TrimSuffix(object, "s"), objectId, prefType, len(changes))
+		if len(changes) == 0 {
+			return nil
+		}
+	}
+	if len(changes) == 0 {
+		// nothing to audit, undo would replay the entry for nothing
+		return err
+	}
+	if err := s.recordAudit(http.MethodPut, object, objectId, prefType, changes, status, err); err != nil {
+		return err
+	}
+	s.verifier.add(pendingWrite{object: object, objectId: objectId, prefType: prefType, desired: desired})
+	s.metrics.observeChange(prefType)
+	s.outcome.changed()
+	return nil
+}
+
+// setSubscriptionLevel changes the subscription level of the object identified by objectId
+// unless level is empty or already current
+func (s *sweep) setSubscriptionLevel(ctx context.Context, object string, objectId int, triage bool, current *UserSetting, level string) error {
+	if level == "" || current.SubscriptionLevel == level {
+		return nil
+	}
+	if s.dryRun || s.showChanges {
+		prefix := ""
+		if s.dryRun {
+			prefix = "[dry-run] "
+		}
+		s.printf("\t%s%s %d %s: subscription_level: %s -> %s\n", prefix, strings.TrimSuffix(object, "s"), objectId, userSettingsMethod(triage), current.SubscriptionLevel, level)
+	}
+	if s.dryRun {
+		s.outcome.changed()
+		return nil
+	}
+	status, err := s.client.updateUserSettings(ctx, objectId, object, current.ID, triage, level)
+	changes := []preferenceChange{{Key: "subscription_level", Before: current.SubscriptionLevel, After: level}}
+	if err := s.recordAudit(http.MethodPut, object, objectId, userSettingsMethod(triage), changes, status, err); err != nil {
+		return err
+	}
+	s.verifier.add(pendingWrite{object: object, objectId: objectId, prefType: userSettingsMethod(triage), triage: triage, level: level})
+	s.metrics.observeChange(userSettingsMethod(triage))
+	s.outcome.changed()
+	return nil
+}
+
+// run processes projects concurrently, continuing past failures.
+// Project reports are buffered and written in name order as soon as earlier projects are done.
+// The returned error joins the failures of individual projects and workspaces.
+func (s *sweep) run(ctx context.Context, projects []Project) error {
+	var errs []error
+	if s.inScope(accountScope) {
+		if err := s.accounts(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	projects = sortedByName(projects, func(p Project) string { return p.Name })
+	var (
+		mu      sync.Mutex
+		skipped int
+		next    int
+	)
+	results := make([]error, len(projects))
+	reports := make([]*bytes.Buffer, len(projects))
+	// flush writes finished reports that all earlier projects' reports were written before
+	flush := func(i int, report *bytes.Buffer) {
+		mu.Lock()
+		defer mu.Unlock()
+		reports[i] = report
+		for ; next < len(reports) && reports[next] != nil; next++ {
+			reports[next].WriteTo(s.writer())
+		}
+	}
+	// each project also processes up to concurrency workspaces, the rate limiter bounds the total request rate
+	var g errgroup.Group
+	g.SetLimit(s.concurrency)
+	for i, p := range projects {
+		i, project := i, p
+		g.Go(func() error {
+			report := &bytes.Buffer{}
+			if ctx.Err() != nil {
+				mu.Lock()
+				skipped++
+				mu.Unlock()
+				flush(i, report)
+				return nil
+			}
+			ps := *s
+			ps.out = report
+			results[i] = ps.project(ctx, project)
+			if results[i] == nil {
+				s.outcome.succeed()
+			}
+			flush(i, report)
+			return nil
+		})
+	}
+	g.Wait()
+	errs = append(errs, results...)
+	if skipped > 0 {
+		errs = append(errs, fmt.Errorf("interrupted before %d projects: %w", skipped, ctx.Err()))
+	}
+	return errors.Join(errs...)
+}
+
+// errNoSettings marks objects whose settings zube returned none of when first read,
+// e.g. projects the user was just added to, which are skipped rather than failed
+var errNoSettings = errors.New("no settings yet")
+
+// noSettings marks a not found error from reading an object's settings with errNoSettings,
+// leaving other errors alone
+func noSettings(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%w: %w", errNoSettings, err)
+	}
+	return err
+}
+
+// project processes project and its workspaces, returning the joined errors of each
+func (s *sweep) project(ctx context.Context, project Project) error {
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	var err error
+	if s.group.hasWholeProject(project) {
+		err = s.projectPreferences(ctx, project)
+		s.progress.done("project", project.ID, project.Name, "", err)
+	} else {
+		// only some workspaces of the project are in -group, its own preferences are left alone
+		s.narrate("\n*** %s group workspaces\n", project.Name)
+	}
+	if errors.Is(err, errNoSettings) {
+		// zube has no settings yet for projects the user was just added to
+		s.narrate("\n*** %s skipped: %v\n", project.Name, err)
+	} else if err != nil {
+		errs = append(errs, fmt.Errorf("project %s (%d): %w", project.Name, project.ID, err))
+	}
+	var labelIds map[int]bool
+	if len(s.labels) > 0 {
+		if labelIds, err = s.labelIds(ctx, project); err != nil {
+			return errors.Join(append(errs, fmt.Errorf("project %s (%d) labels: %w", project.Name, project.ID, err))...)
+		}
+	}
+
+	workspaces, err := s.client.ListWorkspaces(ctx, project.ID)
+	if err != nil {
+		return errors.Join(append(errs, fmt.Errorf("project %s (%d) workspaces: %w", project.Name, project.ID, err))...)
+	}
+
+	// report workspaces in name order regardless of which finishes first
+	workspaces = sortedByName(s.groupWorkspaces(project, workspaces), func(w Workspace) string { return w.Name })
+	if !s.includeArchived {
+		for _, w := range workspaces {
+			if w.IsArchived() {
+				s.narrate("\t%s archived, skipped\n", w.Name)
+			}
+		}
+		workspaces = s.activeWorkspaces(project, workspaces)
+	}
+	reports := make([]bytes.Buffer, len(workspaces))
+	var g errgroup.Group
+	g.SetLimit(s.concurrency)
+	for i, w := range workspaces {
+		workspace := w
+		ws := *s
+		ws.out = &reports[i]
+		g.Go(func() error {
+			if ctx.Err() != nil {
+				return nil
+			}
+			err := ws.workspace(ctx, project, workspace, labelIds)
+			s.progress.done("workspace", workspace.ID, workspace.Name, project.Name, err)
+			if errors.Is(err, errNoSettings) {
+				ws.narrate("\t%s skipped: %v\n", workspace.Name, err)
+			} else if err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("workspace %s/%s (%d): %w", project.Name, workspace.Name, workspace.ID, err))
+				mu.Unlock()
+			} else {
+				s.outcome.succeed()
+			}
+			return nil
+		})
+	}
+	g.Wait()
+	for i := range reports {
+		reports[i].WriteTo(s.writer())
+	}
+	return errors.Join(errs...)
+}
+
+// sortedByName returns a copy of items sorted by name
+func sortedByName[T any](items []T, name func(T) string) []T {
+	sorted := append([]T(nil), items...)
+	sort.SliceStable(sorted, func(i, j int) bool { return name(sorted[i]) < name(sorted[j]) })
+	return sorted
+}
+
+func (s *sweep) projectPreferences(ctx context.Context, project Project) error {
+	client := s.client
+	var (
+		projectEmailPrefs, projectInAppPrefs           UserPreference
+		projectUserSettings, projectTriageUserSettings *UserSetting
+	)
+	// the first failure, e.g. a project without settings yet, cancels the other requests
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() (err error) {
+		projectEmailPrefs, err = client.ProjectEmailPreferences(gctx, project.ID)
+		return err
+	})
+	g.Go(func() (err error) {
+		projectInAppPrefs, err = client.ProjectInAppPreferences(gctx, project.ID)
+		return err
+	})
+	g.Go(func() (err error) {
+		projectUserSettings, err = client.ProjectUserSettings(gctx, project.ID)
+		return err
+	})
+	g.Go(func() (err error) {
+		projectTriageUserSettings, err = client.ProjectTriageUserSettings(gctx, project.ID)
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return noSettings(err)
+	}
+	projectEmailNotifying := enabled(projectEmailPrefs)
+	projectInAppNotifying := enabled(projectInAppPrefs)
+
+	row := reportRow{
+		Project:                 project.Name,
+		ProjectID:               project.ID,
+		SubscriptionLevel:       projectUserSettings.SubscriptionLevel,
+		TriageSubscriptionLevel: projectTriageUserSettings.SubscriptionLevel,
+		EmailFrequency:          fmt.Sprint(projectEmailPrefs["email"]),
+		EmailEnabled:            len(projectEmailNotifying),
+		InAppEnabled:            len(projectInAppNotifying),
+		Email:                   projectEmailPrefs,
+		InApp:                   projectInAppPrefs,
+	}
+	s.actions.observe(row)
+	if s.report != nil {
+		s.report.add(row)
+	} else {
+		s.narrate("\n*** %s email: %s project: %s triage: %s, %s (email: %d in-app: %d)\n",
+			project.Name,
+			projectEmailPrefs["email"],
+			projectUserSettings.SubscriptionLevel,
+			projectTriageUserSettings.SubscriptionLevel,
+			s.notifying(len(projectEmailNotifying)+len(projectInAppNotifying), booleans(projectEmailPrefs, projectInAppPrefs)),
+			len(projectEmailNotifying),
+			len(projectInAppNotifying),
+		)
+	}
+	// sources only feed the webhook warning, failing to list them mustn't block the changes
+	var sourcesErr error
+	if sources, err := client.ListSources(ctx, project.ID); err != nil {
+		sourcesErr = fmt.Errorf("sources: %w", err)
+	} else {
+		for _, source := range sources {
+			if !source.WebhookVerified() {
+				s.narrate("\t! %s webhook not verified, github changes are not syncing\n", source.FullName)
+			}
+		}
+	}
+	if !s.inScope(projectScope) {
+		return sourcesErr
+	}
+	// label rules only adjust the email and in-app preferences of workspaces
+	email, inApp := s.email, s.inApp
+	if len(s.labels) > 0 {
+		email, inApp = noAction, noAction
+	}
+	// attempt all updates so a failing email update doesn't block in-app changes
+	return errors.Join(sourcesErr, s.concurrently(
+		func(s *sweep) error {
+			return s.apply(ctx, "projects", project.ID, emailPreferences, projectEmailPrefs, email)
+		},
+		func(s *sweep) error {
+			return s.apply(ctx, "projects", project.ID, inAppPreferences, projectInAppPrefs, inApp)
+		},
+		func(s *sweep) error {
+			return s.setSubscriptionLevel(ctx, "projects", project.ID, false, projectUserSettings, s.subscriptionLevel)
+		},
+		func(s *sweep) error {
+			return s.setSubscriptionLevel(ctx, "projects", project.ID, true, projectTriageUserSettings, s.triageLevel)
+		},
+		func(s *sweep) error { return s.commentOnTriage(ctx, project) },
+	))
+}
+
+// commentOnTriage posts triageComment on every card in the project's triage
+func (s *sweep) commentOnTriage(ctx context.Context, project Project) error {
+	if s.triageComment == "" {
+		return nil
+	}
+	cards, err := s.client.ListCards(ctx, CardFilter{ProjectID: project.ID, Status: "triage", State: "open"})
+	if err != nil {
+		return err
+	}
+	me, err := s.client.CurrentUser(ctx)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, card := range cards {
+		// repeated runs, e.g. watch or cron, comment once per card
+		commented, err := s.hasCommented(ctx, card.ID, me.ID, s.triageComment)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("card %d comments: %w", card.Number, err))
+			continue
+		}
+		if commented {
+			continue
+		}
+		if s.dryRun {
+			s.printf("\t[dry-run] card %d: comment %q\n", card.Number, s.triageComment)
+			continue
+		}
+		if _, err := s.client.CreateComment(ctx, card.ID, s.triageComment); err != nil {
+			errs = append(errs, fmt.Errorf("card %d: %w", card.Number, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// hasCommented reports whether userId already posted body on the card
+func (s *sweep) hasCommented(ctx context.Context, cardId, userId int, body string) (bool, error) {
+	comments, err := s.client.ListCardComments(ctx, cardId)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range comments {
+		if c.CreatorID == userId && strings.TrimSpace(c.Body) == strings.TrimSpace(body) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// workspace reports on and updates workspace preferences.
+// With label rules, only workspaces with open cards labeled with one of labelIds are updated.
+func (s *sweep) workspace(ctx context.Context, project Project, workspace Workspace, labelIds map[int]bool) error {
+	client := s.client
+	var (
+		workspaceEmailPrefs, workspaceInAppPrefs UserPreference
+		workspaceUserSettings                    *UserSetting
+	)
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() (err error) {
+		workspaceEmailPrefs, err = client.WorkspaceEmailPreferences(gctx, workspace.ID)
+		return err
+	})
+	g.Go(func() (err error) {
+		workspaceInAppPrefs, err = client.WorkspaceInAppPreferences(gctx, workspace.ID)
+		return err
+	})
+	g.Go(func() (err error) {
+		workspaceUserSettings, err = client.WorkspaceUserSettings(gctx, workspace.ID)
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return noSettings(err)
+	}
+	workspaceEmailNotifying := enabled(workspaceEmailPrefs)
+	workspaceInAppNotifying := enabled(workspaceInAppPrefs)
+
+	row := reportRow{
+		Project:           project.Name,
+		ProjectID:         project.ID,
+		Workspace:         workspace.Name,
+		WorkspaceID:       workspace.ID,
+		SubscriptionLevel: workspaceUserSettings.SubscriptionLevel,
+		EmailFrequency:    fmt.Sprint(workspaceEmailPrefs["email"]),
+		EmailEnabled:      len(workspaceEmailNotifying),
+		InAppEnabled:      len(workspaceInAppNotifying),
+		Email:             workspaceEmailPrefs,
+		InApp:             workspaceInAppPrefs,
+		Archived:          workspace.IsArchived(),
+		ArchiveMergedPrs:  workspace.ArchiveMergedPrs,
+	}
+	if s.estimate {
+		a, err := s.workspaceActivity(ctx, workspace.ID, time.Now().Add(-estimateWindow))
+		if err != nil {
+			return fmt.Errorf("while estimating notification volume: %w", err)
+		}
+		weekly := a.weekly(workspaceEmailPrefs, workspaceInAppPrefs)
+		row.WeeklyEstimate = &weekly
+	}
+	s.actions.observe(row)
+	if s.report != nil {
+		s.report.add(row)
+	} else {
+		s.narrate("\t%s email: %s project: %s triage: %s, %s (email: %d, in-app: %d)\n",
+			workspace.Name,
+			workspaceEmailPrefs["email"],
+			workspaceUserSettings.SubscriptionLevel,
+			workspaceUserSettings.SubscriptionLevel,
+			s.notifying(len(workspaceEmailNotifying)+len(workspaceInAppNotifying), booleans(workspaceEmailPrefs, workspaceInAppPrefs)),
+			len(workspaceEmailNotifying),
+			len(workspaceInAppNotifying),
+		)
+		if row.WeeklyEstimate != nil {
+			s.narrate("\t\t~%s notifications/week\n", row.estimateLabel())
+		}
+		if row.deadNotifying() {
+			s.narrate("\t\t%s\n", s.paint(colorYellow, "archived but still notifying"))
+		}
+	}
+
+	if !s.inScope(workspaceScope) {
+		return nil
+	}
+	if len(s.labels) > 0 {
+		labeled, err := s.hasLabeledCards(ctx, workspace, labelIds)
+		if err != nil || !labeled {
+			return err
+		}
+	}
+	return s.concurrently(
+		func(s *sweep) error {
+			return s.apply(ctx, "workspaces", workspace.ID, emailPreferences, workspaceEmailPrefs, s.email)
+		},
+		func(s *sweep) error {
+			return s.apply(ctx, "workspaces", workspace.ID, inAppPreferences, workspaceInAppPrefs, s.inApp)
+		},
+		func(s *sweep) error {
+			return s.setSubscriptionLevel(ctx, "workspaces", workspace.ID, false, workspaceUserSettings, s.subscriptionLevel)
+		},
+	)
+}
+
+// concurrently runs every fn at once, each with a copy of s writing to its own buffer.
+// Buffers are copied to s in argument order, so output reads as if fns ran one after another.
+// It returns the joined errors of every fn.
+func (s *sweep) concurrently(fns ...func(s *sweep) error) error {
+	bufs := make([]bytes.Buffer, len(fns))
+	errs := make([]error, len(fns))
+	var wg sync.WaitGroup
+	for i, fn := range fns {
+		i, fn := i, fn
+		cs := *s
+		cs.out = &bufs[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = fn(&cs)
+		}()
+	}
+	wg.Wait()
+	for i := range bufs {
+		s.writer().Write(bufs[i].Bytes())
+	}
+	return errors.Join(errs...)
+}
+
+// flattenErrors expands errors joined with errors.Join into their leaves
+func flattenErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+	var errs []error
+	for _, e := range joined.Unwrap() {
+		errs = append(errs, flattenErrors(e)...)
+	}
+	return errs
+}
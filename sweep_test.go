@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/graphaelli/zube-notifications/zubetest"
+)
+
+func TestSweepDisablesEmail(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	p := srv.AddProject("proj-a")
+	w := srv.AddWorkspace(p.ID, "web")
+
+	s := newTestSweep(srv)
+	s.email = disableAction
+	if err := defaultCommand.run(context.Background(), s, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, prefs := range []map[string]interface{}{
+		srv.Preferences("projects", p.ID, zubetest.EmailPreferences),
+		srv.Preferences("workspaces", w.ID, zubetest.EmailPreferences),
+	} {
+		for k := range zubetest.DefaultPreferences {
+			if b, ok := prefs[k].(bool); ok && b {
+				t.Errorf("email %s still enabled", k)
+			}
+		}
+	}
+	if in := srv.Preferences("workspaces", w.ID, zubetest.InAppPreferences); in["card_moved"] != true {
+		t.Errorf("in-app preferences changed: %v", in)
+	}
+	if got := s.outcome.exitCode(nil); got != exitChanged {
+		t.Errorf("exit code %d, want %d", got, exitChanged)
+	}
+
+	// a second run has nothing left to change
+	s = newTestSweep(srv)
+	s.email, s.skipUnchanged = disableAction, true
+	if err := defaultCommand.run(context.Background(), s, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.outcome.exitCode(nil); got != exitOK {
+		t.Errorf("exit code %d, want %d", got, exitOK)
+	}
+}
+
+func TestSweepEnablesInApp(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	p := srv.AddProject("proj-a")
+	w := srv.AddWorkspace(p.ID, "web")
+	srv.SetPreference("workspaces", w.ID, zubetest.InAppPreferences, "card_moved", false)
+
+	s := newTestSweep(srv)
+	s.inApp = enableAction
+	if err := defaultCommand.run(context.Background(), s, nil); err != nil {
+		t.Fatal(err)
+	}
+	if in := srv.Preferences("workspaces", w.ID, zubetest.InAppPreferences); in["card_moved"] != true {
+		t.Errorf("in-app card_moved not enabled: %v", in)
+	}
+	if email := srv.Preferences("workspaces", w.ID, zubetest.EmailPreferences); email["card_moved"] != true {
+		t.Errorf("email preferences changed: %v", email)
+	}
+}
+
+func TestUpdateRebasesConflict(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	p := srv.AddProject("proj-a")
+
+	s := newTestSweep(srv)
+	ctx := context.Background()
+	current, err := s.client.notificationPreferences(ctx, p.ID, "projects", emailPreferences)
+	if err != nil {
+		t.Fatal(err)
+	}
+	desired := copyPreferences(current)
+	desired["card_moved"] = false
+	// another session changes a different key after the read
+	srv.SetPreference("projects", p.ID, zubetest.EmailPreferences, "card_commented", false)
+
+	if err := s.update(ctx, "projects", p.ID, emailPreferences, current, desired); err != nil {
+		t.Fatal(err)
+	}
+	got := srv.Preferences("projects", p.ID, zubetest.EmailPreferences)
+	if got["card_moved"] != false {
+		t.Errorf("change not reapplied: %v", got)
+	}
+	if got["card_commented"] != false {
+		t.Errorf("other session's change overwritten: %v", got)
+	}
+	var puts int
+	for _, w := range srv.Writes() {
+		if w.Method == http.MethodPut {
+			puts++
+		}
+	}
+	if puts != 2 {
+		t.Errorf("%d writes, want the conflicting one and its rebase", puts)
+	}
+}
+
+func TestSweepDryRun(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	p := srv.AddProject("proj-a")
+	srv.AddWorkspace(p.ID, "web")
+
+	s := newTestSweep(srv)
+	s.email, s.dryRun = disableAction, true
+	if err := defaultCommand.run(context.Background(), s, nil); err != nil {
+		t.Fatal(err)
+	}
+	if writes := srv.Writes(); len(writes) > 0 {
+		t.Errorf("dry run wrote %v", writes)
+	}
+	if got := s.outcome.exitCode(nil); got != exitChanged {
+		t.Errorf("exit code %d, want %d", got, exitChanged)
+	}
+}
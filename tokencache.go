@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cachedToken is an access token persisted between runs
+type cachedToken struct {
+	AccessToken string    `json:"access_token"`
+	Expiry      time.Time `json:"expiry"`
+}
+
+// tokenStore persists access tokens between runs keyed by client id
+type tokenStore interface {
+	// load returns the cached token for clientId if it has not expired
+	load(clientId string) (cachedToken, bool)
+	store(clientId string, token cachedToken) error
+}
+
+// tokenCache stores access tokens on disk keyed by client id
+type tokenCache struct {
+	path string
+}
+
+func defaultTokenCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "zube", "token.json")
+}
+
+func (t *tokenCache) read() map[string]cachedToken {
+	tokens := make(map[string]cachedToken)
+	b, err := ioutil.ReadFile(t.path)
+	if err != nil {
+		return tokens
+	}
+	// a corrupt cache is as good as an empty one
+	json.Unmarshal(b, &tokens)
+	return tokens
+}
+
+// load returns the cached token for clientId if it has not expired
+func (t *tokenCache) load(clientId string) (cachedToken, bool) {
+	token, ok := t.read()[clientId]
+	if !ok || token.AccessToken == "" || !token.Expiry.After(time.Now()) {
+		return cachedToken{}, false
+	}
+	return token, true
+}
+
+// store saves token for clientId, pruning expired entries
+func (t *tokenCache) store(clientId string, token cachedToken) error {
+	tokens := t.read()
+	now := time.Now()
+	for id, tok := range tokens {
+		if !tok.Expiry.After(now) {
+			delete(tokens, id)
+		}
+	}
+	tokens[clientId] = token
+	b, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(t.path), ".token-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), t.path)
+}
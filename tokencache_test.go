@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/graphaelli/zube-notifications/zubetest"
+)
+
+func TestTokenCache(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	srv.AddProject("proj-a")
+	path := filepath.Join(t.TempDir(), "zube", "token.json")
+	var minted int
+	run := func() {
+		t.Helper()
+		c := newTestClient(srv, TokenCacheOption(path), MiddlewareOption(countRequests("users/tokens", &minted)))
+		if _, err := c.ListProjects(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run()
+	run()
+	if minted != 1 {
+		t.Errorf("minted %d access tokens, want the cached one reused", minted)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("token cache mode %o, want 600", mode)
+	}
+
+	srv.RevokeTokens()
+	run()
+	run()
+	if minted != 2 {
+		t.Errorf("minted %d access tokens, want one more after revocation", minted)
+	}
+}
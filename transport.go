@@ -0,0 +1,185 @@
+package main
+
+import (
+	"compress/gzip"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// maxIdleConnsPerHost keeps connections of concurrent project and workspace requests open for reuse,
+// http.DefaultTransport keeps only 2 and reconnects for the rest
+const maxIdleConnsPerHost = 64
+
+// drainLimit is how much of an unread response body is read to return its connection for reuse,
+// larger leftovers are cheaper to abandon along with the connection
+const drainLimit = 64 << 10
+
+// externalTimeout bounds requests outside of the api, such as to webhooks, signers and report collectors
+const externalTimeout = 30 * time.Second
+
+// transportConfig customizes the connection to the api
+type transportConfig struct {
+	proxy       *url.URL
+	rootCAs     *x509.CertPool
+	minVersion  uint16
+	noKeepAlive bool
+}
+
+// transports are shared by clients with the same transport settings, such as those of each
+// account in a run, so they share one connection pool
+var (
+	transportsMu sync.Mutex
+	transports   = make(map[transportConfig]*http.Transport)
+)
+
+// ProxyOption sends api requests through proxy instead of the one from HTTPS_PROXY
+func ProxyOption(proxy *url.URL) option {
+	return func(c *client) {
+		c.transport.proxy = proxy
+	}
+}
+
+// RootCAsOption verifies the api server certificate against pool
+func RootCAsOption(pool *x509.CertPool) option {
+	return func(c *client) {
+		c.transport.rootCAs = pool
+	}
+}
+
+// TLSMinVersionOption sets the minimum tls version accepted from the api server, e.g. tls.VersionTLS13
+func TLSMinVersionOption(version uint16) option {
+	return func(c *client) {
+		c.transport.minVersion = version
+	}
+}
+
+// KeepAliveOption disabled closes connections after every request instead of reusing them,
+// for proxies and load balancers that mishandle idle connections
+func KeepAliveOption(enabled bool) option {
+	return func(c *client) {
+		c.transport.noKeepAlive = !enabled
+	}
+}
+
+// httpClient returns a client with the configured transport settings, sharing the transport
+// of clients configured alike
+func (t transportConfig) httpClient() *http.Client {
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	transport, ok := transports[t]
+	if !ok {
+		transport = t.newTransport()
+		transports[t] = transport
+	}
+	return &http.Client{Transport: transport}
+}
+
+// externalClient returns a client for requests outside of the api with the same transport settings,
+// giving up after externalTimeout
+func (t transportConfig) externalClient() *http.Client {
+	c := t.httpClient()
+	c.Timeout = externalTimeout
+	return c
+}
+
+// newTransport clones http.DefaultTransport, keeping its http/2 support, with the configured settings
+func (t transportConfig) newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = maxIdleConnsPerHost
+	transport.DisableKeepAlives = t.noKeepAlive
+	if t.proxy != nil {
+		transport.Proxy = http.ProxyURL(t.proxy)
+	}
+	if t.rootCAs != nil || t.minVersion != 0 {
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:    t.rootCAs,
+			MinVersion: t.minVersion,
+		}
+	}
+	return transport
+}
+
+// closeBody drains what's left of a response body, up to drainLimit, and closes it,
+// letting the connection be reused
+func closeBody(rsp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(rsp.Body, drainLimit))
+	rsp.Body.Close()
+}
+
+// loadCAFile returns the system cert pool extended with the pem certificates in path
+func loadCAFile(path string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// parseTLSVersion converts versions like "1.2" to their crypto/tls constant
+func parseTLSVersion(v string) (uint16, error) {
+	switch v {
+	case "":
+		return 0, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unknown tls version %q, expected 1.0, 1.1, 1.2 or 1.3", v)
+}
+
+// gzipMiddleware requests gzip compressed responses and decompresses them. Unlike the compression
+// built into http.Transport this also works for transports passed with HttpClientOption.
+func gzipMiddleware(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("Accept-Encoding") != "" || req.Header.Get("Range") != "" {
+			return next.RoundTrip(req)
+		}
+		req = req.Clone(req.Context())
+		req.Header.Set("Accept-Encoding", "gzip")
+		rsp, err := next.RoundTrip(req)
+		if err != nil || rsp.Header.Get("Content-Encoding") != "gzip" || req.Method == http.MethodHead {
+			return rsp, err
+		}
+		zr, err := gzip.NewReader(rsp.Body)
+		if err != nil {
+			rsp.Body.Close()
+			return nil, fmt.Errorf("while decompressing %s response: %w", req.URL.Path, err)
+		}
+		rsp.Body = gzipBody{Reader: zr, body: rsp.Body}
+		rsp.Header.Del("Content-Encoding")
+		rsp.Header.Del("Content-Length")
+		rsp.ContentLength = -1
+		rsp.Uncompressed = true
+		return rsp, nil
+	})
+}
+
+// gzipBody closes the underlying response body along with the decompressor
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (b gzipBody) Close() error {
+	b.Reader.Close()
+	return b.body.Close()
+}
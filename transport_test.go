@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/graphaelli/zube-notifications/zubetest"
+)
+
+func TestConnectionReuse(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	for i := 0; i < 4; i++ {
+		p := srv.AddProject(fmt.Sprintf("project-%d", i))
+		for j := 0; j < 4; j++ {
+			srv.AddWorkspace(p.ID, fmt.Sprintf("workspace-%d", j))
+		}
+	}
+	m := newMetrics()
+	s := &sweep{
+		client:      newTestClient(srv, MiddlewareOption(m.middleware), ConcurrencyOption(4)),
+		email:       disableAction,
+		concurrency: 4,
+		out:         io.Discard,
+	}
+	if err := defaultCommand.run(context.Background(), s, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	m.writeTo(&out)
+	match := regexp.MustCompile(`zube_api_connections_total\{reused="true"\} (\d+)`).FindSubmatch(out.Bytes())
+	if match == nil {
+		t.Fatalf("no reused connections metric in\n%s", out.String())
+	}
+	if reused, _ := strconv.Atoi(string(match[1])); reused == 0 {
+		t.Errorf("no connections reused during a concurrent sweep\n%s", out.String())
+	}
+}
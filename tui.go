@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+var tuiCommand = &command{
+	name:  "tui",
+	usage: "tui - browse projects and workspaces and toggle individual preferences interactively",
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		fd := int(os.Stdin.Fd())
+		if !term.IsTerminal(fd) {
+			return errors.New("tui requires a terminal")
+		}
+		projects, err := s.targetProjects(ctx)
+		if err != nil {
+			return err
+		}
+		t := &tui{sweep: s, ctx: ctx}
+		for _, p := range sortedByName(projects, func(p Project) string { return p.Name }) {
+			t.roots = append(t.roots, &tuiObject{object: "projects", id: p.ID, name: p.Name, project: p})
+		}
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		defer term.Restore(fd, state)
+		return t.loop(bufio.NewReader(os.Stdin), os.Stdout, fd)
+	},
+}
+
+// tuiObject is a project or workspace in the tree, preferences are loaded when first expanded
+type tuiObject struct {
+	object   string
+	id       int
+	name     string
+	expanded bool
+	loaded   bool
+	// project is the zube project of project objects, for -group
+	project Project
+	// current holds the live preferences by type, desired the edited ones
+	current  map[string]UserPreference
+	desired  map[string]UserPreference
+	children []*tuiObject
+}
+
+func (o *tuiObject) modified() bool {
+	for prefType, d := range o.desired {
+		if len(diffPreferences(o.current[prefType], d)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// tuiLine is a row on screen, an object or one of its preference keys when key is set
+type tuiLine struct {
+	depth    int
+	obj      *tuiObject
+	prefType string
+	key      string
+}
+
+type tui struct {
+	sweep  *sweep
+	ctx    context.Context
+	roots  []*tuiObject
+	cursor int
+	top    int
+	status string
+}
+
+var tuiPrefTypes = []struct{ prefType, label string }{{emailPreferences, "email"}, {inAppPreferences, "in-app"}}
+
+// lines flattens the expanded parts of the tree
+func (t *tui) lines() []tuiLine {
+	var lines []tuiLine
+	var walk func(objs []*tuiObject, depth int)
+	walk = func(objs []*tuiObject, depth int) {
+		for _, o := range objs {
+			lines = append(lines, tuiLine{depth: depth, obj: o})
+			if !o.expanded {
+				continue
+			}
+			for _, pt := range tuiPrefTypes {
+				for _, k := range sortedKeys(o.desired[pt.prefType]) {
+					if _, isBool := o.desired[pt.prefType][k].(bool); isBool {
+						lines = append(lines, tuiLine{depth: depth + 1, obj: o, prefType: pt.prefType, key: k})
+					}
+				}
+			}
+			walk(o.children, depth+1)
+		}
+	}
+	walk(t.roots, 0)
+	return lines
+}
+
+// load fetches the preferences, and for projects the workspaces, of o
+func (t *tui) load(o *tuiObject) error {
+	client := t.sweep.client
+	o.current = make(map[string]UserPreference)
+	o.desired = make(map[string]UserPreference)
+	for _, pt := range tuiPrefTypes {
+		prefs, err := client.notificationPreferences(t.ctx, o.id, o.object, pt.prefType)
+		if err != nil {
+			return err
+		}
+		o.current[pt.prefType] = prefs
+		o.desired[pt.prefType] = copyPreferences(prefs)
+	}
+	if o.object == "projects" && !o.loaded {
+		workspaces, err := client.ListWorkspaces(t.ctx, o.id)
+		if err != nil {
+			return err
+		}
+		workspaces = t.sweep.groupWorkspaces(o.project, workspaces)
+		for _, w := range sortedByName(workspaces, func(w Workspace) string { return w.Name }) {
+			o.children = append(o.children, &tuiObject{object: "workspaces", id: w.ID, name: w.Name})
+		}
+	}
+	o.loaded = true
+	return nil
+}
+
+// save writes every edited object, reloading them afterwards
+func (t *tui) save() {
+	var (
+		errs    []error
+		changed int
+	)
+	out := t.sweep.out
+	// update prints its diff, keep it off the raw terminal
+	t.sweep.out = io.Discard
+	defer func() { t.sweep.out = out }()
+	var walk func(objs []*tuiObject)
+	walk = func(objs []*tuiObject) {
+		for _, o := range objs {
+			if o.loaded && o.modified() {
+				for _, pt := range tuiPrefTypes {
+					if len(diffPreferences(o.current[pt.prefType], o.desired[pt.prefType])) == 0 {
+						continue
+					}
+					if err := t.sweep.update(t.ctx, o.object, o.id, pt.prefType, o.current[pt.prefType], o.desired[pt.prefType]); err != nil {
+						errs = append(errs, fmt.Errorf("%s: %w", o.name, err))
+						continue
+					}
+					changed++
+				}
+				if err := t.load(o); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			walk(o.children)
+		}
+	}
+	walk(t.roots)
+	t.status = fmt.Sprintf("saved %d preference sets", changed)
+	if t.sweep.dryRun {
+		t.status = fmt.Sprintf("dry run, %d preference sets would be saved", changed)
+	}
+	if err := errors.Join(errs...); err != nil {
+		t.status = strings.ReplaceAll(err.Error(), "\n", "; ")
+	}
+}
+
+func (t *tui) render(w io.Writer, height int) {
+	lines := t.lines()
+	var b bytes.Buffer
+	b.WriteString("\x1b[H\x1b[2J")
+	b.WriteString("up/down move  enter expand  space toggle  s save  q quit\r\n\r\n")
+	rows := height - 4
+	if rows < 1 {
+		rows = 1
+	}
+	if t.cursor < t.top {
+		t.top = t.cursor
+	}
+	if t.cursor >= t.top+rows {
+		t.top = t.cursor - rows + 1
+	}
+	for i := t.top; i < len(lines) && i < t.top+rows; i++ {
+		l := lines[i]
+		cursor := "  "
+		if i == t.cursor {
+			cursor = "> "
+		}
+		indent := strings.Repeat("  ", l.depth)
+		if l.key == "" {
+			marker := "+"
+			if l.obj.expanded {
+				marker = "-"
+			}
+			mod := ""
+			if l.obj.loaded && l.obj.modified() {
+				mod = " *"
+			}
+			fmt.Fprintf(&b, "%s%s%s %s%s\r\n", cursor, indent, marker, l.obj.name, mod)
+			continue
+		}
+		check := " "
+		if v, _ := l.obj.desired[l.prefType][l.key].(bool); v {
+			check = "x"
+		}
+		label := "email"
+		if l.prefType == inAppPreferences {
+			label = "in-app"
+		}
+		mod := ""
+		if l.obj.desired[l.prefType][l.key] != l.obj.current[l.prefType][l.key] {
+			mod = " *"
+		}
+		fmt.Fprintf(&b, "%s%s[%s] %-6s %s%s\r\n", cursor, indent, check, label, l.key, mod)
+	}
+	fmt.Fprintf(&b, "\r\n%s", t.status)
+	w.Write(b.Bytes())
+}
+
+func (t *tui) loop(in *bufio.Reader, out io.Writer, fd int) error {
+	defer out.Write([]byte("\x1b[H\x1b[2J"))
+	for {
+		_, height, err := term.GetSize(fd)
+		if err != nil {
+			height = 24
+		}
+		t.render(out, height)
+		r, _, err := in.ReadRune()
+		if err != nil {
+			return err
+		}
+		lines := t.lines()
+		switch r {
+		case 'q', 3: // ctrl-c
+			if t.unsaved() && t.status != unsavedWarning {
+				t.status = unsavedWarning
+				continue
+			}
+			return nil
+		case 'k':
+			t.move(-1, len(lines))
+		case 'j':
+			t.move(1, len(lines))
+		case '\x1b':
+			// arrow keys are ESC [ A and ESC [ B
+			if b, _ := in.ReadByte(); b == '[' {
+				switch c, _ := in.ReadByte(); c {
+				case 'A':
+					t.move(-1, len(lines))
+				case 'B':
+					t.move(1, len(lines))
+				}
+			}
+		case '\r', ' ':
+			if t.cursor >= len(lines) {
+				continue
+			}
+			l := lines[t.cursor]
+			t.status = ""
+			if l.key != "" {
+				v, _ := l.obj.desired[l.prefType][l.key].(bool)
+				l.obj.desired[l.prefType][l.key] = !v
+				continue
+			}
+			if !l.obj.loaded {
+				t.status = "loading " + l.obj.name + "..."
+				t.render(out, height)
+				if err := t.load(l.obj); err != nil {
+					t.status = err.Error()
+					continue
+				}
+				t.status = ""
+			}
+			l.obj.expanded = !l.obj.expanded
+		case 's':
+			t.status = "saving..."
+			t.render(out, height)
+			t.save()
+		}
+		if t.ctx.Err() != nil {
+			return t.ctx.Err()
+		}
+	}
+}
+
+const unsavedWarning = "unsaved changes, press s to save or q again to discard them"
+
+// unsaved reports whether any loaded object has edits
+func (t *tui) unsaved() bool {
+	var walk func(objs []*tuiObject) bool
+	walk = func(objs []*tuiObject) bool {
+		for _, o := range objs {
+			if (o.loaded && o.modified()) || walk(o.children) {
+				return true
+			}
+		}
+		return false
+	}
+	return walk(t.roots)
+}
+
+func (t *tui) move(delta, n int) {
+	t.cursor += delta
+	if t.cursor >= n {
+		t.cursor = n - 1
+	}
+	if t.cursor < 0 {
+		t.cursor = 0
+	}
+}
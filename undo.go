@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var undoCommand = &command{
+	name:  "undo",
+	usage: "undo -last | -since <time> - revert changes recorded in the -audit-log",
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		fs := flag.NewFlagSet("undo", flag.ExitOnError)
+		last := fs.Bool("last", false, "revert every change of the most recent run")
+		since := fs.String("since", "", "revert changes made at or after this time, RFC 3339 or a duration like 2h")
+		fs.Parse(args)
+		if s.auditLog == nil {
+			return errors.New("undo requires -audit-log")
+		}
+		if *last == (*since != "") {
+			return errors.New("undo requires exactly one of -last or -since")
+		}
+		entries, err := readAuditLog(s.auditLog.path)
+		if err != nil {
+			return err
+		}
+		var selected []auditEntry
+		if *last {
+			selected = lastRun(entries, s.client.clientId)
+		} else {
+			t, err := parseSince(*since, time.Now())
+			if err != nil {
+				return err
+			}
+			for _, e := range entries {
+				if e.ClientID == s.client.clientId && !e.Time.Before(t) && undoable(e) {
+					selected = append(selected, e)
+				}
+			}
+		}
+		if len(selected) == 0 {
+			return errors.New("no changes to undo in " + s.auditLog.path)
+		}
+		s.showChanges = true
+		s.skipUnchanged = true
+		return s.undo(ctx, selected)
+	},
+}
+
+func readAuditLog(path string) ([]auditEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []auditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 4<<20)
+	for line := 1; scanner.Scan(); line++ {
+		var e auditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// lastRun returns the undoable entries of clientId's most recent run that changed anything
+func lastRun(entries []auditEntry, clientId string) []auditEntry {
+	run := ""
+	for _, e := range entries {
+		if e.ClientID == clientId && undoable(e) {
+			run = e.Run
+		}
+	}
+	var selected []auditEntry
+	for _, e := range entries {
+		if e.ClientID == clientId && e.Run == run && undoable(e) {
+			selected = append(selected, e)
+		}
+	}
+	return selected
+}
+
+// undoable reports whether e is a successful change undo can revert
+func undoable(e auditEntry) bool {
+	return e.Error == "" && e.Status/100 == 2 && len(e.Changes) > 0
+}
+
+// parseSince accepts a timestamp, or a duration or number of days before now
+func parseSince(v string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(v); err == nil {
+		return now.Add(-d), nil
+	}
+	if days, err := strconv.Atoi(strings.TrimSuffix(v, "d")); err == nil && strings.HasSuffix(v, "d") {
+		return now.AddDate(0, 0, -days), nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid -since %q, expected RFC 3339 time, duration or days like 7d", v)
+	}
+	return t, nil
+}
+
+// undo restores the before-values of successful entries, newest first so objects
+// changed repeatedly end up in their earliest recorded state
+func (s *sweep) undo(ctx context.Context, entries []auditEntry) error {
+	var errs []error
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if !undoable(e) {
+			continue
+		}
+		if ctx.Err() != nil {
+			return errors.Join(append(errs, ctx.Err())...)
+		}
+		if err := s.undoEntry(ctx, e); err != nil {
+			errs = append(errs, fmt.Errorf("%s %d %s: %w", e.Object, e.ObjectID, e.PrefType, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *sweep) undoEntry(ctx context.Context, e auditEntry) error {
+	switch e.PrefType {
+	case userSettingsMethod(false), userSettingsMethod(true):
+		for _, c := range e.Changes {
+			if level, ok := c.Before.(string); ok && c.Key == "subscription_level" {
+				return s.restoreSubscriptionLevel(ctx, e.Object, e.ObjectID, e.PrefType == userSettingsMethod(true), level)
+			}
+		}
+		return nil
+	case emailPreferences, inAppPreferences:
+		if e.Method != http.MethodPut && e.Method != http.MethodDelete {
+			return fmt.Errorf("unexpected method %s", e.Method)
+		}
+		current, err := s.client.notificationPreferences(ctx, e.ObjectID, e.Object, e.PrefType)
+		if err != nil {
+			return err
+		}
+		desired := copyPreferences(current)
+		for _, c := range e.Changes {
+			desired[c.Key] = c.Before
+		}
+		return s.update(ctx, e.Object, e.ObjectID, e.PrefType, current, desired)
+	case subscriptionMethod:
+		for _, c := range e.Changes {
+			if subscribed, ok := c.Before.(bool); ok && c.Key == "subscribed" {
+				return s.subscribe(ctx, e.Object, e.ObjectID, subscribed)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown preference type %q", e.PrefType)
+}
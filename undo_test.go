@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/graphaelli/zube-notifications/zubetest"
+)
+
+func TestLastRun(t *testing.T) {
+	change := []preferenceChange{{Key: "card_moved", Before: true, After: false}}
+	entries := []auditEntry{
+		{Run: "a", ClientID: "test", ObjectID: 1, Status: 200, Changes: change},
+		{Run: "a", ClientID: "test", ObjectID: 2, Status: 500, Error: "failed", Changes: change},
+		{Run: "b", ClientID: "other", ObjectID: 3, Status: 200, Changes: change},
+		// a write without changes, as older versions logged them
+		{Run: "c", ClientID: "test", ObjectID: 4, Status: 200},
+	}
+	got := lastRun(entries, "test")
+	if len(got) != 1 || got[0].ObjectID != 1 {
+		t.Errorf("last run %+v, want the successful change of run a", got)
+	}
+	if got := lastRun(entries[3:], "test"); len(got) != 0 {
+		t.Errorf("last run %+v, want nothing to undo", got)
+	}
+}
+
+func TestUndoLast(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	p := srv.AddProject("proj-a")
+	w := srv.AddWorkspace(p.ID, "web")
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	ctx := context.Background()
+	newSweep := func() *sweep {
+		t.Helper()
+		s := newTestSweep(srv)
+		var err error
+		if s.auditLog, err = openAuditLog(path); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.auditLog.Close() })
+		// runs are told apart by when they started
+		time.Sleep(time.Millisecond)
+		return s
+	}
+
+	s := newSweep()
+	s.email = disableAction
+	if err := defaultCommand.run(ctx, s, nil); err != nil {
+		t.Fatal(err)
+	}
+	s = newSweep()
+	s.inApp = disableAction
+	if err := defaultCommand.run(ctx, s, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := undoCommand.run(ctx, newSweep(), []string{"-last"}); err != nil {
+		t.Fatal(err)
+	}
+	for object, id := range map[string]int{"projects": p.ID, "workspaces": w.ID} {
+		if in := srv.Preferences(object, id, zubetest.InAppPreferences); in["card_moved"] != true {
+			t.Errorf("%s %d in-app not reverted: %v", object, id, in)
+		}
+		if email := srv.Preferences(object, id, zubetest.EmailPreferences); email["card_moved"] != false {
+			t.Errorf("%s %d email of the earlier run reverted: %v", object, id, email)
+		}
+	}
+
+	// the undo is itself the last run, undoing it disables in-app again
+	if err := undoCommand.run(ctx, newSweep(), []string{"-last"}); err != nil {
+		t.Fatal(err)
+	}
+	if in := srv.Preferences("projects", p.ID, zubetest.InAppPreferences); in["card_moved"] != false {
+		t.Errorf("undo not undone: %v", in)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// CurrentUser fetches the user the client's credentials belong to
+func (c *client) CurrentUser(ctx context.Context) (*User, error) {
+	var user User
+	if err := c.call(ctx, http.MethodGet, apiPath("users", "me"), nil, nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+var whoamiCommand = &command{
+	name:  "whoami",
+	usage: "print the authenticated user and their default notification settings",
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		user, err := s.client.CurrentUser(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("client id: %s\n", s.client.clientId)
+		fmt.Printf("user:      %s (%s) id %d\n", user.Name, user.Username, user.ID)
+		fmt.Printf("email:     %s\n", user.Email)
+		if user.TimeZone != "" {
+			fmt.Printf("time zone: %s\n", user.TimeZone)
+		}
+		if len(user.Preferences) > 0 {
+			fmt.Println("default notification settings:")
+			keys := make([]string, 0, len(user.Preferences))
+			for k := range user.Preferences {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				fmt.Printf("\t%s: %v\n", k, user.Preferences[k])
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// verifyRetries bounds how often a write that didn't stick is repeated
+const verifyRetries = 2
+
+// pendingWrite is a successful write to check once every write of a run is done
+type pendingWrite struct {
+	object   string
+	objectId int
+	// prefType is a preference type, or a user settings method for subscription levels
+	prefType string
+	desired  UserPreference
+	triage   bool
+	level    string
+}
+
+func (w pendingWrite) String() string {
+	return fmt.Sprintf("%s %d %s", strings.TrimSuffix(w.object, "s"), w.objectId, w.prefType)
+}
+
+// verifier collects the writes of a run, a nil verifier collects nothing
+type verifier struct {
+	mu     sync.Mutex
+	writes []pendingWrite
+}
+
+func (v *verifier) add(w pendingWrite) {
+	if v == nil {
+		return
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.writes = append(v.writes, w)
+}
+
+// verify re-fetches every object written since s.verifier was set, repeating writes that
+// don't match the desired state, and reports the objects zube refused to update
+func (s *sweep) verify(ctx context.Context) error {
+	if s.verifier == nil || s.dryRun {
+		return nil
+	}
+	writes := s.verifier.writes
+	// rewrites aren't verified again
+	vs := *s
+	vs.verifier = nil
+	vs.showChanges = false
+	var (
+		mu      sync.Mutex
+		retried int
+		refused []string
+		errs    []error
+	)
+	var g errgroup.Group
+	g.SetLimit(s.concurrency)
+	for _, w := range writes {
+		w := w
+		g.Go(func() error {
+			for attempt := 0; ; attempt++ {
+				mismatch, err := vs.mismatch(ctx, w, attempt < verifyRetries)
+				if err != nil {
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("verifying %s: %w", w, err))
+					mu.Unlock()
+					return nil
+				}
+				if mismatch == "" {
+					return nil
+				}
+				mu.Lock()
+				if attempt == verifyRetries {
+					refused = append(refused, fmt.Sprintf("%s: %s", w, mismatch))
+					errs = append(errs, fmt.Errorf("%s: zube refused to update %s", w, mismatch))
+				} else if attempt == 0 {
+					retried++
+				}
+				mu.Unlock()
+				if attempt == verifyRetries {
+					return nil
+				}
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(time.Duration(attempt+1) * time.Second):
+				}
+			}
+		})
+	}
+	g.Wait()
+	s.printf("verified %d writes, %d retried, %d refused\n", len(writes), retried, len(refused))
+	for _, r := range refused {
+		s.printf("\trefused %s\n", r)
+	}
+	return errors.Join(errs...)
+}
+
+// mismatch describes how the live state of w differs from the desired one, rewriting it when rewrite is set.
+// An empty description means they match.
+func (s *sweep) mismatch(ctx context.Context, w pendingWrite, rewrite bool) (string, error) {
+	if w.desired == nil {
+		current, err := s.client.userSettings(ctx, w.objectId, w.object, w.triage)
+		if err != nil {
+			return "", err
+		}
+		if current.SubscriptionLevel == w.level {
+			return "", nil
+		}
+		if rewrite {
+			err = s.setSubscriptionLevel(ctx, w.object, w.objectId, w.triage, current, w.level)
+		}
+		return fmt.Sprintf("subscription_level: %s, want %s", current.SubscriptionLevel, w.level), err
+	}
+	current, err := s.client.notificationPreferences(ctx, w.objectId, w.object, w.prefType)
+	if err != nil {
+		return "", err
+	}
+	var keys []string
+	for _, c := range diffPreferences(current, w.desired) {
+		if c.Key != "id" {
+			keys = append(keys, fmt.Sprintf("%s: %v, want %v", c.Key, c.Before, c.After))
+		}
+	}
+	if len(keys) == 0 {
+		return "", nil
+	}
+	if rewrite {
+		err = s.update(ctx, w.object, w.objectId, w.prefType, current, w.desired)
+	}
+	return strings.Join(keys, ", "), err
+}
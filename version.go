@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// version is set at build time with -ldflags "-X main.version=v1.2.3"
+var version = ""
+
+// buildVersion returns the injected version, or the module version for go install builds
+func buildVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+// userAgent identifies this tool in requests so Zube can trace automation traffic
+func userAgent() string {
+	return fmt.Sprintf("zube-notifications/%s (go/%s)", buildVersion(), strings.TrimPrefix(runtime.Version(), "go"))
+}
+
+var versionCommand = &command{
+	name:    "version",
+	usage:   "print the version and go runtime",
+	offline: true,
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		fmt.Printf("zube-notifications %s %s %s/%s\n", buildVersion(), runtime.Version(), runtime.GOOS, runtime.GOARCH)
+		return nil
+	},
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"time"
+)
+
+var watchCommand = &command{
+	name:  "watch",
+	usage: "watch [-interval 15m] [-profile file] [-onboard profile] - keep enforcing -E/-I/-enable-* changes or a profile",
+	run: func(ctx context.Context, s *sweep, args []string) error {
+		fs := flag.NewFlagSet("watch", flag.ExitOnError)
+		interval := fs.Duration("interval", 15*time.Minute, "time between enforcement runs")
+		profileFile := fs.String("profile", "", "path to yaml or json profile to enforce instead of the global flags")
+		scheduleExpr := fs.String("schedule", "", `mute windows, e.g. "mute 18:00-09:00 Mon-Fri; mute 00:00-24:00 Sat,Sun"`)
+		tz := fs.String("tz", "Local", "time zone the schedule is evaluated in, e.g. Europe/Berlin")
+		metricsAddr := fs.String("metrics-addr", "", "address to serve prometheus /metrics on, e.g. :9090")
+		onboardProfile := fs.String("onboard", "", "profile file or config preference profile to apply to workspaces created while watching")
+		fs.Parse(args)
+		// runs repeat forever, report what changed as it happens and exit 0 when stopped
+		s.report = nil
+		s.outcome = nil
+		if err := s.serveMetrics(ctx, *metricsAddr); err != nil {
+			return err
+		}
+
+		enforce := func(ctx context.Context) error {
+			projects, err := s.targetProjects(ctx)
+			if err != nil {
+				return err
+			}
+			return s.run(ctx, projects)
+		}
+		if *profileFile != "" {
+			p, err := s.loadProfile(*profileFile)
+			if err != nil {
+				return err
+			}
+			if err := s.validateProfileLive(ctx, p); err != nil {
+				return err
+			}
+			s.showChanges = true
+			enforce = func(ctx context.Context) error {
+				projects, err := s.targetProjects(ctx)
+				if err != nil {
+					return err
+				}
+				return s.applyProfile(ctx, p, projects, "")
+			}
+		}
+		if *scheduleExpr != "" {
+			if *profileFile != "" {
+				return errors.New("-schedule and -profile cannot be combined")
+			}
+			loc, err := time.LoadLocation(*tz)
+			if err != nil {
+				return err
+			}
+			sched, err := parseSchedule(*scheduleExpr, loc)
+			if err != nil {
+				return err
+			}
+			enforce = s.scheduled(sched, s.email, s.inApp)
+			intervalSet := false
+			fs.Visit(func(f *flag.Flag) { intervalSet = intervalSet || f.Name == "interval" })
+			if !intervalSet {
+				*interval = time.Minute
+			}
+		}
+		if *onboardProfile != "" {
+			p, err := s.loadProfile(*onboardProfile)
+			if err != nil {
+				return err
+			}
+			if err := s.validateProfileLive(ctx, p); err != nil {
+				return err
+			}
+			s.showChanges = true
+			enforce = s.onboarding(p, enforce)
+		}
+		// only write preferences that drifted from the desired state
+		s.skipUnchanged = true
+		return s.watch(ctx, *interval, enforce)
+	},
+}
+
+// serveMetrics exposes /metrics on addr until ctx is done, an empty addr disables it
+func (s *sweep) serveMetrics(ctx context.Context, addr string) error {
+	if addr == "" || s.metrics == nil {
+		return nil
+	}
+	return s.metrics.serve(ctx, addr)
+}
+
+// scheduled returns an enforcement func that disables notifications when sched enters a mute window
+// and enables them when it leaves one. Notification types with an action selected by the global
+// flags are scheduled, both when none are. Types selected with -enable-* -defaults are reset to
+// zube's defaults rather than fully enabled when unmuting.
+func (s *sweep) scheduled(sched *schedule, email, inApp action) func(context.Context) error {
+	if email == noAction && inApp == noAction {
+		email, inApp = enableAction, enableAction
+	}
+	mute := func(a action) action {
+		if a == noAction {
+			return noAction
+		}
+		return disableAction
+	}
+	unmute := func(a action) action {
+		if a == noAction || a == resetAction {
+			return a
+		}
+		return enableAction
+	}
+	var last *bool
+	return func(ctx context.Context) error {
+		muted := sched.muted(time.Now())
+		if last != nil && *last == muted {
+			return nil
+		}
+		if muted {
+			slog.Info("entering mute window, disabling notifications")
+			s.email, s.inApp = mute(email), mute(inApp)
+		} else {
+			slog.Info("outside mute window, enabling notifications")
+			s.email, s.inApp = unmute(email), unmute(inApp)
+		}
+		projects, err := s.targetProjects(ctx)
+		if err != nil {
+			return err
+		}
+		if err := s.run(ctx, projects); err != nil {
+			return err
+		}
+		last = &muted
+		return nil
+	}
+}
+
+// watch calls enforce every interval until ctx is done, logging rather than returning failures
+func (s *sweep) watch(ctx context.Context, interval time.Duration, enforce func(context.Context) error) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		started := time.Now()
+		if err := enforce(ctx); err != nil && ctx.Err() == nil {
+			s.metrics.observeErrors(len(flattenErrors(err)))
+			reportFailures(err)
+		}
+		s.client.logger.Debug("run finished", "took", time.Since(started).Round(time.Millisecond), "next", interval)
+		select {
+		case <-ctx.Done():
+			slog.Info("shutting down")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
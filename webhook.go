@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// signatureHeader carries the hex encoded HMAC-SHA256 of the request body
+const signatureHeader = "X-Zube-Signature"
+
+// webhookSink posts notifications as json to an arbitrary http endpoint
+type webhookSink struct {
+	url        string
+	header     http.Header
+	secret     []byte
+	httpClient *http.Client
+}
+
+// webhookPayload is the body sent by webhookSink
+type webhookPayload struct {
+	Notification Notification `json:"notification"`
+	DeliveredAt  time.Time    `json:"delivered_at"`
+}
+
+func (w *webhookSink) Send(ctx context.Context, n Notification) error {
+	payload, err := json.Marshal(webhookPayload{Notification: n, DeliveredAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	for k, vs := range w.header {
+		req.Header[k] = vs
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent())
+	if len(w.secret) > 0 {
+		req.Header.Set(signatureHeader, "sha256="+sign(w.secret, payload))
+	}
+	rsp, err := w.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer closeBody(rsp)
+	if rsp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook %s responded %s", w.url, rsp.Status)
+	}
+	return nil
+}
+
+// sign returns the hex encoded HMAC-SHA256 of body with secret
+func sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// headerFlags collects repeated "Name: value" flags
+type headerFlags http.Header
+
+func (h headerFlags) String() string {
+	var parts []string
+	for k, vs := range h {
+		for _, v := range vs {
+			parts = append(parts, k+": "+v)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (h headerFlags) Set(s string) error {
+	name, value, ok := strings.Cut(s, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf(`expected "Name: value", got %q`, s)
+	}
+	http.Header(h).Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+)
+
+// ListWorkspaces fetches every workspace in a project.
+// Unlike Project.Workspaces this is paginated and never truncated.
+func (c *client) ListWorkspaces(ctx context.Context, projectId int) ([]Workspace, error) {
+	return paginate[Workspace](ctx, c, apiPath("projects", projectId, "workspaces"), nil)
+}
+
+// WorkspacesIter iterates over the workspaces of a project, a page at a time
+func (c *client) WorkspacesIter(ctx context.Context, projectId int) *Iterator[Workspace] {
+	return iterate[Workspace](ctx, c, apiPath("projects", projectId, "workspaces"), nil)
+}
+
+// IsArchived reports whether the workspace was archived, no longer receiving activity
+func (w Workspace) IsArchived() bool {
+	return w.Archived || !w.ArchivedAt.IsZero()
+}
+
+// activeWorkspaces drops workspaces outside -group and archived ones unless -include-archived is set
+func (s *sweep) activeWorkspaces(project Project, workspaces []Workspace) []Workspace {
+	workspaces = s.groupWorkspaces(project, workspaces)
+	if s.includeArchived {
+		return workspaces
+	}
+	var active []Workspace
+	for _, w := range workspaces {
+		if !w.IsArchived() {
+			active = append(active, w)
+		}
+	}
+	return active
+}
+
+// ListAllWorkspaces fetches every workspace visible to the user across projects
+func (c *client) ListAllWorkspaces(ctx context.Context) ([]Workspace, error) {
+	return paginate[Workspace](ctx, c, "workspaces", nil)
+}
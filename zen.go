@@ -2,29 +2,68 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
 	"crypto/rsa"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"log"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"slices"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/graphaelli/zube-notifications/store"
+	"golang.org/x/time/rate"
 )
 
 type client struct {
-	clientId   string
-	key        *rsa.PrivateKey
+	clientId string
+	// signers sign refresh tokens, older keys to fall back to after the first
+	signers    []Signer
+	keyIndex   int
 	httpClient *http.Client
+	// transport configures the http client unless one is provided with HttpClientOption
+	transport transportConfig
+	// middleware wraps the http client transport
+	middleware []Middleware
+	// cache, when set, stores GET responses for revalidation
+	cache httpCache
 
+	// accessDuration is the lifetime of refresh tokens and so of the access tokens exchanged for them
 	accessDuration time.Duration
-	apiBaseUrl     string
-	debug          bool
+	// claims are added to every refresh token, issuer and times are always set by the client
+	claims     jwt.RegisteredClaims
+	apiBaseUrl string
+	// debug logs every request and response body
+	debug bool
+	// dump receives full requests and responses with credentials redacted
+	dump        io.Writer
+	logger      *slog.Logger
+	tokenCache  tokenStore
+	perPage     int
+	retry       RetryPolicy
+	limiter     *rate.Limiter
+	serverLimit serverLimit
+	// resolver maps project and workspace slugs to ids
+	resolver *resolver
+	// inflight holds a slot for every request in flight, bounding them across nested concurrency
+	inflight chan struct{}
+	// projectQuery holds the filters and field selection sent with ListProjects
+	projectQuery url.Values
+	// readOnly rejects every request but GETs and the token exchange
+	readOnly bool
 
 	accessMutex  sync.Mutex
 	accessExpiry time.Time
@@ -45,265 +84,757 @@ func DebugOption(enabled bool) option {
 	}
 }
 
-func NewClient(clientId string, key *rsa.PrivateKey, options ...option) *client {
+// ReadOnlyOption makes the client fail every request that could change anything with ErrReadOnly
+func ReadOnlyOption(enabled bool) option {
+	return func(c *client) {
+		c.readOnly = enabled
+	}
+}
+
+// FallbackKeysOption adds older keys to try, in order, when Zube rejects the newer ones.
+// This allows rotating to a new API key before the previous one is revoked.
+func FallbackKeysOption(keys ...crypto.Signer) option {
+	return func(c *client) {
+		for _, key := range keys {
+			c.signers = append(c.signers, KeySigner(key))
+		}
+	}
+}
+
+// TokenCacheOption persists access tokens to path so they are reused across runs
+func TokenCacheOption(path string) option {
+	return func(c *client) {
+		if path != "" {
+			c.tokenCache = &tokenCache{path: path}
+		}
+	}
+}
+
+// NewClient returns a client authenticating as clientId with an RSA, ECDSA or Ed25519 private key
+func NewClient(clientId string, key crypto.Signer, options ...option) *client {
 	c := &client{
 		clientId: clientId,
-		key:      key,
+		signers:  []Signer{KeySigner(key)},
 
 		accessDuration: 1 * time.Minute,
 		apiBaseUrl:     "https://zube.io/api/",
+		perPage:        defaultPerPage,
 	}
 	for _, o := range options {
 		o(c)
 	}
+	if c.logger == nil {
+		c.logger = slog.Default()
+	}
+	if c.resolver == nil {
+		c.resolver = newResolver("")
+	}
 	if c.httpClient == nil {
-		c.httpClient = http.DefaultClient
+		c.httpClient = c.transport.httpClient()
+	}
+	middleware := c.middleware
+	if c.cache != nil {
+		middleware = append([]Middleware{cacheMiddleware(c.cache, c.clientId)}, middleware...)
+	}
+	if c.debug {
+		// log what goes over the wire, after other middleware changed it
+		middleware = append(middleware[:len(middleware):len(middleware)], debugMiddleware(c.logger))
 	}
+	if c.dump != nil {
+		middleware = append(middleware[:len(middleware):len(middleware)], dumpMiddleware(c.dump))
+	}
+	// closest to the wire so every other middleware sees decompressed bodies
+	middleware = append(middleware[:len(middleware):len(middleware)], gzipMiddleware)
+	c.httpClient = wrapTransport(c.httpClient, middleware)
 	return c
 }
 
-func (c *client) refreshToken(iat, eat time.Time) (string, error) {
-	now := time.Now()
-	claims := &jwt.StandardClaims{
-		IssuedAt:  now.Unix(),
-		ExpiresAt: (now.Add(time.Minute)).Unix(),
-		Issuer:    c.clientId,
+// refreshToken signs a refresh token issued at iat and expiring at eat
+func (c *client) refreshToken(ctx context.Context, signer Signer, iat, eat time.Time) (string, error) {
+	claims := c.claims
+	claims.IssuedAt = jwt.NewNumericDate(iat)
+	claims.ExpiresAt = jwt.NewNumericDate(eat)
+	claims.Issuer = c.clientId
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, &claims)
+	token.Header["alg"] = signer.Algorithm()
+	signingInput, err := token.SigningString()
+	if err != nil {
+		return "", err
+	}
+	sig, err := signer.Sign(ctx, []byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// AccessDurationOption sets the lifetime of refresh tokens, and so how long access tokens are used before minting new ones
+func AccessDurationOption(d time.Duration) option {
+	return func(c *client) {
+		if d > 0 {
+			c.accessDuration = d
+		}
 	}
-	if err := claims.Valid(); err != nil {
-		log.Fatalf("invalid claims: %s", err)
+}
+
+// ClaimsOption adds registered claims, like an audience or subject required by enterprise deployments,
+// to every refresh token. Issuer, issued at and expiry are always set by the client.
+func ClaimsOption(claims jwt.RegisteredClaims) option {
+	return func(c *client) {
+		c.claims = claims
+	}
+}
+
+// BaseURLOption overrides the Zube API base url, e.g. for staging or self-hosted deployments
+func BaseURLOption(baseUrl string) option {
+	return func(c *client) {
+		if baseUrl != "" {
+			c.apiBaseUrl = baseUrl
+		}
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	return token.SignedString(c.key)
 }
 
-func (c *client) newRequest(method, api string, body io.Reader) (*http.Request, error) {
-	// TODO: urljoin
-	req, err := http.NewRequest(method, c.apiBaseUrl+api, body)
+// PerPageOption sets the page size requested from list endpoints
+func PerPageOption(perPage int) option {
+	return func(c *client) {
+		if perPage > 0 {
+			c.perPage = perPage
+		}
+	}
+}
+
+// apiPath joins escaped path segments, e.g. apiPath("projects", 1, "user_settings")
+func apiPath(segments ...interface{}) string {
+	escaped := make([]string, len(segments))
+	for i, s := range segments {
+		escaped[i] = url.PathEscape(fmt.Sprint(s))
+	}
+	return strings.Join(escaped, "/")
+}
+
+func (c *client) newRequest(ctx context.Context, method, api string, body io.Reader) (*http.Request, error) {
+	return c.newQueryRequest(ctx, method, api, nil, body)
+}
+
+// newQueryRequest builds a request for api, relative to the base url, with query added to its query string
+func (c *client) newQueryRequest(ctx context.Context, method, api string, query url.Values, body io.Reader) (*http.Request, error) {
+	base, err := url.Parse(c.apiBaseUrl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid api base url: %w", err)
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	ref, err := url.Parse(strings.TrimPrefix(api, "/"))
+	if err != nil {
+		return nil, err
+	}
+	u := base.ResolveReference(ref)
+	if len(query) > 0 {
+		q := u.Query()
+		for k, vs := range query {
+			q[k] = append(q[k], vs...)
+		}
+		u.RawQuery = q.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("X-Client-ID", c.clientId)
+	req.Header.Set("User-Agent", userAgent())
 	return req, nil
 }
 
-func (c *client) doRequest(req *http.Request) (*http.Response, error) {
-	if c.debug {
-		log.Printf("doing %s %s", req.Method, req.URL.String())
+// call sends in, when not nil, as the json body of a request for api and decodes the response into out
+func (c *client) call(ctx context.Context, method, api string, query url.Values, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
 	}
-	if req.Header.Get("Authorization") == "" {
-		c.accessMutex.Lock()
-		if c.accessToken == "" || c.accessExpiry.Before(time.Now()) {
-			now := time.Now()
-			later := now.Add(c.accessDuration)
+	req, err := c.newQueryRequest(ctx, method, api, query, body)
+	if err != nil {
+		return err
+	}
+	rsp, err := c.doRequest(req)
+	if err != nil {
+		return err
+	}
+	defer closeBody(rsp)
+	if out == nil {
+		return nil
+	}
+	if err := decodeResponse(rsp, api, out); err != nil {
+		return err
+	}
+	return nil
+}
 
-			accessToken, err := c.access(now, later)
-			if err != nil {
-				return nil, err
-			}
-			c.accessExpiry = later
-			c.accessToken = accessToken
+func (c *client) doRequest(req *http.Request) (*http.Response, error) {
+	if c.readOnly && req.Method != http.MethodGet && req.Method != http.MethodHead && !isTokenExchange(req) {
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL.Path, ErrReadOnly)
+	}
+	// requests carrying their own credentials, like the token exchange, are sent as is
+	authorize := req.Header.Get("Authorization") == ""
+	var accessToken string
+	if authorize {
+		var err error
+		if accessToken, err = c.token(req.Context()); err != nil {
+			return nil, err
 		}
-		c.accessMutex.Unlock()
-		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.accessToken))
+		req.Header.Set("Authorization", "Bearer "+accessToken)
 	}
-	rsp, err := c.httpClient.Do(req)
+	rsp, err := c.send(req)
 	if err != nil {
 		return rsp, err
 	}
-	if rsp.StatusCode == http.StatusBadRequest {
-		body, _ := ioutil.ReadAll(rsp.Body)
-		rsp.Body.Close()
-		return rsp, fmt.Errorf("bad request: %s", string(body))
+	// the access token was revoked before it expired, retry once with a fresh one
+	if authorize && rsp.StatusCode == http.StatusUnauthorized && (req.Body == nil || req.GetBody != nil) {
+		closeBody(rsp)
+		c.logger.Debug("access token rejected, refreshing")
+		c.invalidate(accessToken)
+		if accessToken, err = c.token(req.Context()); err != nil {
+			return nil, err
+		}
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		if rsp, err = c.send(req); err != nil {
+			return rsp, err
+		}
+	}
+	if rsp.StatusCode/100 != 2 {
+		return rsp, newAPIError(rsp)
 	}
+	return rsp, err
+}
 
-	// dump response bodies to stdout while preserving rsp.Body.Close
-	if c.debug {
-		rsp.Body = struct {
-			io.Reader
-			io.Closer
-		}{io.TeeReader(rsp.Body, log.Writer()), rsp.Body}
+// token returns the current access token, from the token cache or minting a new one once expired
+func (c *client) token(ctx context.Context) (string, error) {
+	c.accessMutex.Lock()
+	defer c.accessMutex.Unlock()
+	if c.accessToken == "" && c.tokenCache != nil {
+		if cached, ok := c.tokenCache.load(c.clientId); ok {
+			c.accessToken = cached.AccessToken
+			c.accessExpiry = cached.Expiry
+		}
+	}
+	if c.accessToken == "" || c.accessExpiry.Before(time.Now()) {
+		now := time.Now()
+		later := now.Add(c.accessDuration)
+
+		accessToken, err := c.access(ctx, now, later)
+		if err != nil {
+			return "", err
+		}
+		c.accessExpiry = later
+		c.accessToken = accessToken
+		if c.tokenCache != nil {
+			if err := c.tokenCache.store(c.clientId, cachedToken{AccessToken: accessToken, Expiry: later}); err != nil {
+				c.logger.Warn("failed to cache access token", "err", err)
+			}
+		}
+	}
+	return c.accessToken, nil
+}
+
+// invalidate forgets accessToken, unless a concurrent request already replaced it
+func (c *client) invalidate(accessToken string) {
+	c.accessMutex.Lock()
+	defer c.accessMutex.Unlock()
+	if c.accessToken != accessToken {
+		return
+	}
+	c.accessToken = ""
+	if c.tokenCache != nil {
+		if err := c.tokenCache.store(c.clientId, cachedToken{}); err != nil {
+			c.logger.Warn("failed to clear cached access token", "err", err)
+		}
 	}
-	return rsp, err
 }
 
-func (c *client) access(issueTime, expireTime time.Time) (string, error) {
-	refreshToken, err := c.refreshToken(issueTime, expireTime)
+var errUnauthorized = errors.New("unauthorized")
+
+// access exchanges a refresh token for an access token, starting with the last key
+// that worked and falling back to older keys while Zube responds 401.
+// Callers must hold accessMutex.
+func (c *client) access(ctx context.Context, issueTime, expireTime time.Time) (string, error) {
+	for i := c.keyIndex; ; i++ {
+		accessToken, err := c.accessWithKey(ctx, c.signers[i], issueTime, expireTime)
+		if errors.Is(err, errUnauthorized) && i+1 < len(c.signers) {
+			c.logger.Debug("key rejected, falling back to older key", "key", i, "fallback", i+1)
+			continue
+		}
+		if err == nil {
+			c.keyIndex = i
+		}
+		return accessToken, err
+	}
+}
+
+func (c *client) accessWithKey(ctx context.Context, signer Signer, issueTime, expireTime time.Time) (string, error) {
+	refreshToken, err := c.refreshToken(ctx, signer, issueTime, expireTime)
 	if err != nil {
 		return "", err
 	}
-	req, err := c.newRequest(http.MethodPost, "users/tokens", nil)
+	req, err := c.newRequest(ctx, http.MethodPost, "users/tokens", nil)
 	if err != nil {
 		return "", err
 	}
 	req.Header.Add("Authorization", "Bearer "+refreshToken)
 	rsp, err := c.doRequest(req)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("while requesting access token: %w", err)
 	}
-	defer rsp.Body.Close()
+	defer closeBody(rsp)
 	var accessTokenRsp struct {
 		AccessToken string `json:"access_token"`
 	}
-	if err := json.NewDecoder(rsp.Body).Decode(&accessTokenRsp); err != nil {
+	if err := decodeResponse(rsp, "access token", &accessTokenRsp); err != nil {
 		return "", err
 	}
 	return accessTokenRsp.AccessToken, nil
 }
 
-func enabled(m map[string]interface{}) []string {
-	var matches []string
-	for k, v := range m {
-		if b, ok := v.(bool); ok && b {
-			matches = append(matches, k)
-		}
-	}
-	return matches
-}
-
-func disableAll(m map[string]interface{}) {
-	for k, v := range m {
-		if b, ok := v.(bool); ok && b {
-			m[k] = false
-		}
-	}
-}
-
 func main() {
 	clientId := flag.String("c", os.Getenv("ZUBE_CLIENT_ID"), "zube client id")
-	privateKeyFile := flag.String("k", "zube_api_key.pem", "path to zube api key pem")
+	privateKeyFile := flag.String("k", "zube_api_key.pem", "path to zube api key pem, comma separated to rotate keys")
 	disableEmail := flag.Bool("E", false, "disable email notifications")
 	disableInApp := flag.Bool("I", false, "disable in-app notifications")
-	debug := flag.Bool("D", false, "enable debugging output")
+	enableEmail := flag.Bool("enable-email", false, "enable email notifications")
+	enableInApp := flag.Bool("enable-in-app", false, "enable in-app notifications")
+	defaults := flag.Bool("defaults", false, "when enabling, restore zube's default preferences instead of enabling everything")
+	only := flag.String("only", "", "comma separated preference keys to change, all keys when empty")
+	except := flag.String("except", "", "comma separated preference keys to leave unchanged")
+	subscriptionLevel := flag.String("subscription", "", "set project and workspace subscription level, e.g. mute, participating or everything")
+	triageLevel := flag.String("triage", "", "set project triage subscription level, e.g. mute to silence triage notifications")
+	triageComment := flag.String("triage-comment", "", "comment to post on open triage cards, e.g. \"notifications muted until monday\"")
+	ifLabel := flag.String("if-label", "", "comma separated label names, only change workspaces with open cards carrying one of them")
+	scope := flag.String("scope", "project,workspace", "comma separated preference levels to change: account, project, workspace")
+	format := flag.String("format", textFormat, "report format: "+strings.Join(reportFormats, ", "))
+	dryRun := flag.Bool("dry-run", false, "print preference changes without making them")
+	retries := flag.Int("retries", 3, "maximum attempts for requests failing with transient errors")
+	concurrency := flag.Int("concurrency", 4, "maximum number of api requests in flight, shared by the projects and workspaces processed concurrently")
+	accountId := flag.Int("account-id", 0, "only act on projects of this zube account id, filtered by the api")
+	projectFields := flag.String("project-fields", "", "comma separated project fields the api lists, e.g. id,name,slug, all by default, others are left empty")
+	perPage := flag.Int("per-page", defaultPerPage, "page size requested from list endpoints")
+	rps := flag.Float64("rate", 5, "maximum requests per second, 0 for unlimited")
+	apiURL := flag.String("api-url", "", "zube api base url, e.g. for self-hosted or staging deployments")
+	proxy := flag.String("proxy", "", "http proxy url for api requests, HTTPS_PROXY by default")
+	caFile := flag.String("ca-file", "", "pem bundle of additional certificate authorities trusted for the api server")
+	noKeepAlive := flag.Bool("no-keepalive", false, "open a new connection for every api request, for proxies that mishandle idle connections")
+	tlsMin := flag.String("tls-min", "", "minimum tls version for the api server, e.g. 1.2 or 1.3")
+	cacheMode := flag.String("cache", "", "cache api responses and revalidate them with etags: memory, disk or a directory, off when empty")
+	auditPath := flag.String("audit-log", "", "append a json line for every preference change to this file")
+	statePath := flag.String("state-db", defaultStatePath(), "path to the state database kept by forward, dnd and watch -onboard, empty to disable")
+	recordDir := flag.String("record", "", "directory to save api responses to as replayable fixtures, after any already there, tokens are not recorded")
+	replayDir := flag.String("replay", "", "directory of fixtures saved with -record to answer api requests from, without network access")
+	signerSpec := flag.String("signer", os.Getenv("ZUBE_SIGNER"), "sign refresh tokens outside this process instead of with -k: ssh-agent[:key], vault:<mount>/<key>, awskms:<key> or gcpkms:<key version>")
+	useKeyring := flag.Bool("keyring", false, "read the private key and cache access tokens in the os keyring, see the keyring command")
+	tokenCachePath := flag.String("token-cache", defaultTokenCachePath(), "path to access token cache, empty to disable")
+	tokenLifetime := flag.Duration("token-lifetime", time.Minute, "lifetime of refresh tokens and the access tokens exchanged for them")
+	jwtAudience := flag.String("jwt-audience", "", "comma separated audience claim of refresh tokens, for deployments that require one")
+	jwtSubject := flag.String("jwt-subject", "", "subject claim of refresh tokens, for deployments that require one")
+	configPath := flag.String("config", defaultConfigPath(), "path to config file")
+	account := flag.String("account", os.Getenv("ZUBE_ACCOUNT"), "named credentials from the config file, or all to run once per account")
+	usersPath := flag.String("users", "", "act for each user with a directory of <client id>.pem keys, or a yaml file mapping user names to client_id and key")
+	profileName := flag.String("profile", os.Getenv("ZUBE_PROFILE"), "named settings profile from the config file, such as staging")
+	logLevel := flag.String("log-level", "info", "minimum level of log messages: debug, info, warn or error")
+	debug := flag.Bool("D", false, "enable debugging output, same as -log-level debug")
+	summaryPath := flag.String("summary-file", "", "also write the summary of changes made by the run to this file as json")
+	quiet := flag.Bool("quiet", false, "suppress the per-project and per-workspace report, changes, summaries and errors are still shown")
+	progressFormat := flag.String("progress", "", "stream an event per processed object to stderr, only json is supported")
+	estimate := flag.Bool("estimate", false, "estimate notifications per week of each workspace from its last 30 days of card and comment activity")
+	groupNames := flag.String("group", "", "comma separated workspace groups from the config file, only acting on their projects and workspaces")
+	includeArchived := flag.Bool("include-archived", false, "also process archived workspaces, which are skipped by default")
+	noColor := flag.Bool("no-color", false, "disable colors, which are used when stdout is a terminal")
+	readOnly := flag.Bool("read-only", false, "fail every api request that could change anything, for reporting and audits")
+	output := flag.String("output", "", "publish the report to a file, s3://<bucket>/<key> or an http(s) collector instead of stdout")
+	debugDump := flag.String("debug-dump", "", "append full requests and responses, credentials redacted, to this file")
 
+	flag.Usage = usage
 	flag.Parse()
-	if len(flag.Args()) > 1 {
-		*clientId = flag.Arg(0)
+	if *debug {
+		*logLevel = "debug"
+	}
+	level, err := setupLogging(*logLevel)
+	if err != nil {
+		fatal(err.Error())
 	}
-	if *clientId == "" {
-		log.Fatal("client id required, set ZUBE_CLIENT_ID or provide as first argument")
+	if cmd, args := lookupCommand(flag.Args()); cmd == versionCommand {
+		// needs neither config nor credentials
+		versionCommand.run(context.Background(), nil, args)
+		return
 	}
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
 
-	privateKey, err := ioutil.ReadFile(*privateKeyFile)
+	cfg, err := loadConfig(*configPath, explicit["config"])
 	if err != nil {
-		log.Fatal(err)
+		fatal(err.Error())
+	}
+	conf, err := cfg.resolve(*profileName)
+	if err != nil {
+		fatal(err.Error())
+	}
+	if !explicit["c"] && os.Getenv("ZUBE_CLIENT_ID") == "" && conf.ClientID != "" {
+		*clientId = conf.ClientID
+	}
+	if !explicit["k"] && conf.Key != "" {
+		*privateKeyFile = expandHome(conf.Key)
+	}
+	if !explicit["concurrency"] && conf.Concurrency > 0 {
+		*concurrency = conf.Concurrency
+	}
+	if !explicit["api-url"] {
+		*apiURL = conf.APIURL
+	}
+
+	cmd, args := lookupCommand(flag.Args())
+	if cmd == nil && len(flag.Args()) > 1 {
+		*clientId = flag.Arg(0)
+	}
+	if (*disableEmail && *enableEmail) || (*disableInApp && *enableInApp) {
+		fatal("cannot both enable and disable the same notifications")
+	}
+	if *concurrency < 1 {
+		fatal("-concurrency must be at least 1")
+	}
+	if *defaults && (*only != "" || *except != "") {
+		fatal("-defaults resets every preference key, it cannot be combined with -only or -except")
+	}
+
+	if !slices.Contains(reportFormats, *format) {
+		fatal(fmt.Sprintf("unknown -format %q, expected one of %s", *format, strings.Join(reportFormats, ", ")))
+	}
+	for sc := range splitKeys(*scope) {
+		if sc != accountScope && sc != projectScope && sc != workspaceScope {
+			fatal(fmt.Sprintf("unknown -scope %q, expected account, project or workspace", sc))
+		}
+	}
+
+	flagCreds := credentials{
+		clientId: *clientId,
+		keyFiles: strings.Split(*privateKeyFile, ","),
+		apiURL:   *apiURL,
+	}
+	var creds []credentials
+	if *usersPath != "" {
+		if *account != "" {
+			fatal("-users and -account cannot be combined")
+		}
+		creds, err = userCredentials(expandHome(*usersPath), flagCreds)
+	} else {
+		creds, err = cfg.credentials(*account, flagCreds)
 	}
-	key, err := jwt.ParseRSAPrivateKeyFromPEM(privateKey)
 	if err != nil {
-		log.Fatal(err)
+		fatal(err.Error())
+	}
+	if explicit["api-url"] {
+		// the flag also overrides api_url of config accounts and users
+		for i := range creds {
+			creds[i].apiURL = *apiURL
+		}
 	}
-	client := NewClient(*clientId, key, DebugOption(*debug))
-	projects, err := client.ListProjects()
+
+	// the transport settings apply to the api and to webhooks, signers and report collectors alike
+	tc := transportConfig{noKeepAlive: *noKeepAlive}
+	if *proxy != "" {
+		u, err := url.Parse(*proxy)
+		if err != nil {
+			fatal(fmt.Sprintf("invalid -proxy: %s", err))
+		}
+		tc.proxy = u
+	}
+	if *caFile != "" {
+		pool, err := loadCAFile(expandHome(*caFile))
+		if err != nil {
+			fatal(err.Error())
+		}
+		tc.rootCAs = pool
+	}
+	minVersion, err := parseTLSVersion(*tlsMin)
 	if err != nil {
-		log.Fatal(err)
+		fatal(err.Error())
 	}
-	for _, project := range projects {
-		projectEmailPrefs, err := client.ProjectEmailPreferences(project.ID)
+	tc.minVersion = minVersion
+	transport := []option{ProxyOption(tc.proxy), RootCAsOption(tc.rootCAs), TLSMinVersionOption(tc.minVersion), KeepAliveOption(!tc.noKeepAlive)}
+	switch *cacheMode {
+	case "":
+	case "memory":
+		transport = append(transport, CacheOption(newMemoryCache()))
+	default:
+		dir := *cacheMode
+		if dir == "disk" {
+			dir = defaultHTTPCacheDir()
+		}
+		cache, err := newDiskCache(expandHome(dir))
 		if err != nil {
-			log.Fatal(err)
+			fatal(err.Error())
 		}
-		projectInAppPrefs, err := client.ProjectInAppPreferences(project.ID)
+		transport = append(transport, CacheOption(cache))
+	}
+	if *recordDir != "" && *replayDir != "" {
+		fatal("-record and -replay cannot be combined")
+	}
+	if *recordDir != "" {
+		record, err := recordMiddleware(*recordDir)
 		if err != nil {
-			log.Fatal(err)
+			fatal(err.Error())
 		}
-		projectUserSettings, err := client.ProjectUserSettings(project.ID)
+		transport = append(transport, MiddlewareOption(record))
+	}
+	if *replayDir != "" {
+		replay, err := replayMiddleware(*replayDir)
 		if err != nil {
-			log.Fatal(err)
+			fatal(err.Error())
 		}
-		projectTriageUserSettings, err := client.ProjectTriageUserSettings(project.ID)
+		transport = append(transport, MiddlewareOption(replay))
+	}
+
+	if *debugDump != "" {
+		f, err := os.OpenFile(expandHome(*debugDump), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
 		if err != nil {
-			log.Fatal(err)
-		}
-		projectEmailNotifying := enabled(projectEmailPrefs)
-		projectInAppNotifying := enabled(projectInAppPrefs)
-
-		fmt.Printf("\n*** %s email: %s project: %s triage: %s, notifying: %d (email: %d in-app: %d)\n",
-			project.Name,
-			projectEmailPrefs["email"],
-			projectUserSettings.SubscriptionLevel,
-			projectTriageUserSettings.SubscriptionLevel,
-			len(projectEmailNotifying)+len(projectInAppNotifying),
-			len(projectEmailNotifying),
-			len(projectInAppNotifying),
+			fatal(err.Error())
+		}
+		defer f.Close()
+		transport = append(transport, DebugDumpOption(f))
+	}
+
+	// stop issuing requests on ctrl-c, in-flight requests are cancelled
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	base := sweep{
+		filter: newKeyFilter(*only, *except),
+		email:  preferenceAction(*disableEmail, *enableEmail, *defaults),
+		inApp:  preferenceAction(*disableInApp, *enableInApp, *defaults),
+		dryRun: *dryRun,
+
+		subscriptionLevel: *subscriptionLevel,
+		triageLevel:       *triageLevel,
+		triageComment:     *triageComment,
+		labels:            splitKeys(*ifLabel),
+		scopes:            splitKeys(*scope),
+		concurrency:       *concurrency,
+		config:            cfg,
+		metrics:           newMetrics(),
+		outcome:           &outcome{},
+		summary:           newSummary(),
+		color:             !*noColor && *output == "" && useColor(os.Stdout),
+		quiet:             *quiet,
+		includeArchived:   *includeArchived,
+		estimate:          *estimate,
+	}
+	if *groupNames != "" {
+		if base.group, err = cfg.group(*groupNames); err != nil {
+			fatal(err.Error())
+		}
+	}
+	if *format == textFormat {
+		base.actions = newActions(os.Stdout)
+	} else {
+		base.actions = newActions(os.Stderr)
+	}
+	switch *progressFormat {
+	case "":
+	case jsonFormat:
+		base.progress = newProgress(os.Stderr)
+	default:
+		fatal(fmt.Sprintf("unknown -progress %q, expected json", *progressFormat))
+	}
+	if *statePath != "" && *replayDir == "" {
+		base.store = store.New(expandHome(*statePath))
+	}
+	if *auditPath != "" {
+		if base.auditLog, err = openAuditLog(expandHome(*auditPath)); err != nil {
+			fatal(err.Error())
+		}
+	}
+	var reportOut reportSink
+	if *output != "" {
+		if reportOut, err = parseReportSink(*output, tc.externalClient()); err != nil {
+			fatal(err.Error())
+		}
+		base.reportOut = &bytes.Buffer{}
+		if *format == textFormat {
+			// the text report is the narration
+			base.out = base.reportOut
+		}
+	}
+	if *format != textFormat {
+		// keep stdout parseable, progress and changes go to stderr
+		base.report = &report{}
+		base.format = *format
+		base.out = os.Stderr
+	}
+	if cmd == nil {
+		cmd = defaultCommand
+	}
+	// replayed token exchanges accept any signature, so recorded runs don't need the signer
+	externalSigner := *signerSpec != "" && !cmd.offline && *replayDir == ""
+	claims := jwt.RegisteredClaims{Subject: *jwtSubject}
+	if *jwtAudience != "" {
+		claims.Audience = strings.Split(*jwtAudience, ",")
+	}
+	// the external signer is shared by every credential and closed once they're done
+	var (
+		signer    Signer
+		signerErr error
+	)
+	if externalSigner {
+		if signer, signerErr = parseSigner(*signerSpec, tc.externalClient()); signerErr != nil && !cmd.keyOptional {
+			fatal(signerErr.Error())
+		}
+	}
+	var errs []error
+	for _, cred := range creds {
+		if ctx.Err() != nil {
+			break
+		}
+		if cred.clientId == "" && *replayDir != "" {
+			cred.clientId = "replay"
+		}
+		if cred.clientId == "" {
+			fatal("client id required, set ZUBE_CLIENT_ID or provide as first argument")
+		}
+		if cred.name != "" && len(creds) > 1 {
+			fmt.Printf("\n=== %s ===\n", cred.heading())
+		}
+		var (
+			keys   []crypto.Signer
+			keyErr error
 		)
-		if *disableEmail {
-			disableAll(projectEmailPrefs)
-			projectPayloadEmail := new(bytes.Buffer)
-			if err := json.NewEncoder(projectPayloadEmail).Encode(projectEmailPrefs); err != nil {
-				log.Fatal(err)
+		// keyFailed records err for commands diagnosing it, reporting whether to skip the credentials
+		keyFailed := func(err error) bool {
+			if !cmd.keyOptional {
+				errs = append(errs, cred.label(err))
+				return true
 			}
-			id := int(projectEmailPrefs["id"].(float64))
-			if err := client.DisableProjectEmailNotifications(project.ID, id, projectPayloadEmail); err != nil {
-				log.Fatal(err)
+			keys, keyErr = []crypto.Signer{nil}, err
+			return false
+		}
+		switch {
+		case cmd.offline, externalSigner:
+			// commands that don't call the api, and external signers, run without a key
+			keys = []crypto.Signer{nil}
+		case *replayDir != "":
+			// replayed token exchanges accept any signature
+			key, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				fatal(err.Error())
 			}
+			keys = []crypto.Signer{key}
+		case *useKeyring && !explicit["k"] && cred.kind == "":
+			key, err := keyringKey(cred.clientId)
+			if err != nil && keyFailed(err) {
+				continue
+			}
+			keys = []crypto.Signer{key}
+		default:
+			var keyFiles []string
+			for _, f := range cred.keyFiles {
+				keyFiles = append(keyFiles, expandHome(f))
+			}
+			if keys, err = loadKeys(keyFiles); err != nil && keyFailed(err) {
+				continue
+			}
+		}
+		cache := TokenCacheOption(*tokenCachePath)
+		if *useKeyring {
+			cache = KeyringTokenCacheOption()
+		}
+		resolverCache := ResolverCacheOption(defaultResolverCachePath(cred.clientId))
+		if *replayDir != "" {
+			cache = TokenCacheOption("")
+			resolverCache = ResolverCacheOption("")
+		}
+		s := base
+		if base.report != nil {
+			s.report = &report{}
 		}
-		if *disableInApp {
-			disableAll(projectInAppPrefs)
-			projectPayloadInApp := new(bytes.Buffer)
-			if err := json.NewEncoder(projectPayloadInApp).Encode(projectInAppPrefs); err != nil {
-				log.Fatal(err)
+		options := append([]option{FallbackKeysOption(keys[1:]...),
+			DebugOption(level <= slog.LevelDebug), cache,
+			RetryOption(RetryPolicy{MaxAttempts: *retries, BaseDelay: 500 * time.Millisecond, MaxDelay: 30 * time.Second, Jitter: 0.2}),
+			RateLimitOption(*rps, 1), ConcurrencyOption(*concurrency), PerPageOption(*perPage), BaseURLOption(cred.apiURL),
+			MiddlewareOption(base.metrics.middleware), resolverCache, ReadOnlyOption(*readOnly),
+			AccessDurationOption(*tokenLifetime), ClaimsOption(claims)}, transport...)
+		if *accountId != 0 {
+			options = append(options, ProjectFilterOption("account_id", *accountId))
+		}
+		if *projectFields != "" {
+			options = append(options, ProjectFieldsOption(strings.Split(*projectFields, ",")...))
+		}
+		if externalSigner {
+			if signerErr != nil {
+				keyErr = signerErr
+			} else {
+				options = append(options, SignerOption(signer))
+			}
+		}
+		s.client = NewClient(cred.clientId, keys[0], options...)
+		s.keyErr = keyErr
+		if !cmd.offline && !cmd.keyOptional && cmd != dndCommand && *replayDir == "" {
+			if err := s.expireDND(ctx); err != nil {
+				errs = append(errs, cred.label(err))
 			}
-			id := int(projectInAppPrefs["id"].(float64))
-			if err := client.DisableProjectInAppNotifications(project.ID, id, projectPayloadInApp); err != nil {
-				log.Fatal(err)
+		}
+		if err := cmd.run(ctx, &s, args); err != nil {
+			for _, e := range flattenErrors(err) {
+				errs = append(errs, cred.label(e))
 			}
 		}
+	}
+	if closer, ok := signer.(io.Closer); ok {
+		closer.Close()
+	}
+	if reportOut != nil {
+		if perr := reportOut.Publish(ctx, base.reportOut.(*bytes.Buffer).Bytes(), reportContentType(*format)); perr != nil {
+			errs = append(errs, perr)
+		}
+	}
+	err = errors.Join(errs...)
+	sum := base.summary.report(base.metrics, len(flattenErrors(err)))
+	if sum.touched() {
+		// stdout stays parseable for machine readable formats
+		out := os.Stdout
+		if *format != textFormat {
+			out = os.Stderr
+		}
+		sum.render(out, out == os.Stdout && base.color)
+	}
+	if *summaryPath != "" {
+		if serr := sum.writeFile(expandHome(*summaryPath)); serr != nil {
+			err = errors.Join(err, serr)
+		}
+	}
+	if aerr := base.actions.finish(sum, flattenErrors(err)); aerr != nil {
+		err = errors.Join(err, aerr)
+	}
+	if err != nil {
+		reportFailures(err)
+	}
+	os.Exit(base.outcome.exitCode(err))
+}
 
-		var wg sync.WaitGroup
-		wg.Add(len(project.Workspaces))
-		for _, w := range project.Workspaces {
-			go func(workspace Workspace) {
-				defer wg.Done()
-				workspaceEmailPrefs, err := client.WorkspaceEmailPreferences(workspace.ID)
-				if err != nil {
-					log.Fatal(err)
-				}
-				workspaceInAppPrefs, err := client.WorkspaceInAppPreferences(workspace.ID)
-				if err != nil {
-					log.Fatal(err)
-				}
-				workspaceUserSettings, err := client.WorkspaceUserSettings(workspace.ID)
-				if err != nil {
-					log.Fatal(err)
-				}
-				workspaceEmailNotifying := enabled(workspaceEmailPrefs)
-				workspaceInAppNotifying := enabled(workspaceInAppPrefs)
-
-				fmt.Printf("\t%s email: %s project: %s triage: %s, notifying: %d (email: %d, in-app: %d)\n",
-					workspace.Name,
-					workspaceEmailPrefs["email"],
-					workspaceUserSettings.SubscriptionLevel,
-					workspaceUserSettings.SubscriptionLevel,
-					len(workspaceEmailNotifying)+len(workspaceInAppNotifying),
-					len(workspaceEmailNotifying),
-					len(workspaceInAppNotifying),
-				)
-
-				if *disableEmail {
-					disableAll(workspaceEmailPrefs)
-					payloadEmail := new(bytes.Buffer)
-					if err := json.NewEncoder(payloadEmail).Encode(workspaceEmailPrefs); err != nil {
-						log.Fatal(err)
-					}
-					id := int(workspaceEmailPrefs["id"].(float64))
-					if err := client.DisableWorkspaceEmailNotifications(workspace.ID, id, payloadEmail); err != nil {
-						log.Fatal(err)
-					}
-				}
-				if *disableInApp {
-					disableAll(workspaceInAppPrefs)
-					payloadInApp := new(bytes.Buffer)
-					if err := json.NewEncoder(payloadInApp).Encode(workspaceInAppPrefs); err != nil {
-						log.Fatal(err)
-					}
-					id := int(workspaceInAppPrefs["id"].(float64))
-					if err := client.DisableWorkspaceInAppNotifications(workspace.ID, id, payloadInApp); err != nil {
-						log.Fatal(err)
-					}
-				}
-			}(w)
-		}
-		wg.Wait()
+// reportFailures prints each error joined into err
+func reportFailures(err error) {
+	failures := flattenErrors(err)
+	if len(failures) == 1 {
+		slog.Error(failures[0].Error())
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\n%d failures:\n", len(failures))
+	for _, f := range failures {
+		fmt.Fprintf(os.Stderr, "\t%s\n", f)
 	}
 }
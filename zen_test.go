@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/graphaelli/zube-notifications/zubetest"
+)
+
+// testKey is shared by the tests, generating an rsa key is slow
+var testKey = func() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// newTestClient returns a client of srv, options are applied after the base url
+func newTestClient(srv *zubetest.Server, options ...option) *client {
+	return NewClient("test", testKey, append([]option{BaseURLOption(srv.APIURL())}, options...)...)
+}
+
+// newTestSweep returns a sweep of srv that discards its report and tracks its outcome
+func newTestSweep(srv *zubetest.Server, options ...option) *sweep {
+	return &sweep{client: newTestClient(srv, options...), concurrency: 2, out: io.Discard, outcome: &outcome{}}
+}
+
+// countRequests counts the requests whose path ends with suffix
+func countRequests(suffix string, n *int) Middleware {
+	var mu sync.Mutex
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if strings.HasSuffix(req.URL.Path, suffix) {
+				mu.Lock()
+				*n++
+				mu.Unlock()
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// countWrites counts the requests srv received with method
+func countWrites(srv *zubetest.Server, method string) int {
+	var n int
+	for _, w := range srv.Writes() {
+		if w.Method == method {
+			n++
+		}
+	}
+	return n
+}
+
+func TestReadOnlyOption(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	p := srv.AddProject("proj-a")
+	srv.AddWorkspace(p.ID, "web")
+
+	s := newTestSweep(srv, ReadOnlyOption(true))
+	s.email = disableAction
+	err := defaultCommand.run(context.Background(), s, nil)
+	if !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("got %v, want %v", err, ErrReadOnly)
+	}
+	if writes := srv.Writes(); len(writes) > 0 {
+		t.Errorf("read-only client wrote %v", writes)
+	}
+	if email := srv.Preferences("projects", p.ID, zubetest.EmailPreferences); email["card_moved"] != true {
+		t.Errorf("preferences changed: %v", email)
+	}
+}
+
+func TestReauthenticatesRevokedToken(t *testing.T) {
+	srv := zubetest.NewServer()
+	defer srv.Close()
+	srv.AddProject("proj-a")
+	var exchanges int
+	c := newTestClient(srv, MiddlewareOption(countRequests("/users/tokens", &exchanges)))
+	ctx := context.Background()
+
+	if _, err := c.ListProjects(ctx); err != nil {
+		t.Fatal(err)
+	}
+	srv.RevokeTokens()
+	if _, err := c.ListProjects(ctx); err != nil {
+		t.Fatalf("revoked token not refreshed: %v", err)
+	}
+	if exchanges != 2 {
+		t.Errorf("%d token exchanges, want 2", exchanges)
+	}
+}
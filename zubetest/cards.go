@@ -0,0 +1,227 @@
+package zubetest
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// CurrentUser is the user every access token belongs to
+var CurrentUser = User{ID: 1, Username: "zube", Name: "Zube Test"}
+
+type User struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+}
+
+type Label struct {
+	ID        int    `json:"id"`
+	ProjectID int    `json:"project_id"`
+	Name      string `json:"name"`
+}
+
+type Card struct {
+	ID           int       `json:"id"`
+	AccountID    int       `json:"account_id"`
+	ProjectID    int       `json:"project_id"`
+	WorkspaceID  int       `json:"workspace_id"`
+	Number       int       `json:"number"`
+	Title        string    `json:"title"`
+	Body         string    `json:"body"`
+	State        string    `json:"state"`
+	Status       string    `json:"status"`
+	CategoryName string    `json:"category_name"`
+	AssigneeIDs  []int     `json:"assignee_ids"`
+	LabelIDs     []int     `json:"label_ids"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type Comment struct {
+	ID        int    `json:"id"`
+	CardID    int    `json:"card_id"`
+	CreatorID int    `json:"creator_id"`
+	Body      string `json:"body"`
+}
+
+// Comments returns the comments posted on the card
+func (s *Server) Comments(cardId int) []Comment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var comments []Comment
+	for _, c := range s.comments {
+		if c.CardID == cardId {
+			comments = append(comments, c)
+		}
+	}
+	return comments
+}
+
+// AddLabel creates a label in project
+func (s *Server) AddLabel(projectId int, name string) Label {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l := Label{ID: s.id(), ProjectID: projectId, Name: name}
+	s.labels = append(s.labels, l)
+	return l
+}
+
+// AddCard creates card, assigning its id and number, and returns the stored card
+func (s *Server) AddCard(card Card) Card {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addCard(card)
+}
+
+func (s *Server) addCard(card Card) Card {
+	card.ID = s.id()
+	card.AccountID = CurrentAccount.ID
+	card.Number = len(s.cards) + 1
+	if card.State == "" {
+		card.State = "open"
+	}
+	if card.Status == "" {
+		card.Status = "triage"
+		if card.WorkspaceID != 0 {
+			card.Status = "backlog"
+		}
+	}
+	if card.CreatedAt.IsZero() {
+		card.CreatedAt = time.Now().UTC()
+	}
+	if card.UpdatedAt.IsZero() {
+		card.UpdatedAt = card.CreatedAt
+	}
+	s.cards = append(s.cards, &card)
+	return card
+}
+
+// Muted reports whether the current user unsubscribed from card id
+func (s *Server) Muted(id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.muted[id]
+}
+
+// Card returns a copy of the card with id, if any
+func (s *Server) Card(id int) (Card, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c := s.card(id); c != nil {
+		return *c, true
+	}
+	return Card{}, false
+}
+
+func (s *Server) card(id int) *Card {
+	for _, c := range s.cards {
+		if c.ID == id {
+			return c
+		}
+	}
+	return nil
+}
+
+// handleCards serves cards, cards/:id, cards/:id/comments and cards/:id/move
+func (s *Server) handleCards(w http.ResponseWriter, r *http.Request, segments []string, raw []byte) {
+	if len(segments) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			q := r.URL.Query()
+			var cards []Card
+			for _, c := range s.cards {
+				if match(q.Get("where[project_id]"), c.ProjectID) &&
+					match(q.Get("where[workspace_id]"), c.WorkspaceID) &&
+					(q.Get("where[state]") == "" || q.Get("where[state]") == c.State) &&
+					(q.Get("where[status]") == "" || q.Get("where[status]") == c.Status) {
+					cards = append(cards, *c)
+				}
+			}
+			if q.Get("order[by]") == "updated_at" {
+				desc := q.Get("order[direction]") == "desc"
+				sort.SliceStable(cards, func(i, j int) bool {
+					if desc {
+						return cards[i].UpdatedAt.After(cards[j].UpdatedAt)
+					}
+					return cards[i].UpdatedAt.Before(cards[j].UpdatedAt)
+				})
+			}
+			writeList(w, r, cards)
+		case http.MethodPost:
+			var card Card
+			json.Unmarshal(raw, &card)
+			writeJSON(w, r, s.addCard(card))
+		default:
+			writeError(w, http.StatusNotFound, "not found")
+		}
+		return
+	}
+	id, _ := strconv.Atoi(segments[1])
+	card := s.card(id)
+	if card == nil {
+		writeError(w, http.StatusNotFound, "card not found")
+		return
+	}
+	switch {
+	case len(segments) == 2 && r.Method == http.MethodGet:
+		writeJSON(w, r, card)
+	case len(segments) == 2 && r.Method == http.MethodPut:
+		// decode over the existing card, so only fields present change
+		json.Unmarshal(raw, card)
+		card.UpdatedAt = time.Now().UTC()
+		writeJSON(w, r, card)
+	case len(segments) == 3 && segments[2] == "comments" && r.Method == http.MethodGet:
+		var comments []Comment
+		for _, c := range s.comments {
+			if c.CardID == card.ID {
+				comments = append(comments, c)
+			}
+		}
+		writeList(w, r, comments)
+	case len(segments) == 3 && segments[2] == "comments" && r.Method == http.MethodPost:
+		comment := Comment{ID: s.id(), CardID: card.ID, CreatorID: CurrentUser.ID}
+		json.Unmarshal(raw, &comment)
+		s.comments = append(s.comments, comment)
+		writeJSON(w, r, comment)
+	case len(segments) == 3 && segments[2] == "subscription" && r.Method == http.MethodGet:
+		writeJSON(w, r, map[string]bool{"subscribed": !s.muted[card.ID]})
+	case len(segments) == 3 && segments[2] == "subscription" && r.Method == http.MethodPut:
+		var sub struct {
+			Subscribed bool `json:"subscribed"`
+		}
+		json.Unmarshal(raw, &sub)
+		s.muted[card.ID] = !sub.Subscribed
+		writeJSON(w, r, sub)
+	case len(segments) == 3 && segments[2] == "move" && r.Method == http.MethodPut:
+		var move struct {
+			Destination struct {
+				Type        string `json:"type"`
+				Name        string `json:"name"`
+				WorkspaceID int    `json:"workspace_id"`
+			} `json:"destination"`
+		}
+		json.Unmarshal(raw, &move)
+		switch d := move.Destination; d.Type {
+		case "archive":
+			card.Status = "archived"
+		case "triage":
+			card.Status, card.CategoryName = "triage", ""
+		default:
+			card.Status, card.CategoryName = "in_progress", d.Name
+			if d.WorkspaceID != 0 {
+				card.WorkspaceID = d.WorkspaceID
+			}
+		}
+		card.UpdatedAt = time.Now().UTC()
+		writeJSON(w, r, card)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func match(want string, id int) bool {
+	return want == "" || want == strconv.Itoa(id)
+}
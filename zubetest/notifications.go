@@ -0,0 +1,84 @@
+package zubetest
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type Notification struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`
+	ProjectID   int       `json:"project_id"`
+	WorkspaceID int       `json:"workspace_id"`
+	CardID      int       `json:"card_id"`
+	Event       string    `json:"event"`
+	Title       string    `json:"title"`
+	Read        bool      `json:"read"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// AddNotification adds n to the current user's feed, CreatedAt defaults to now
+func (s *Server) AddNotification(n Notification) Notification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n.ID, n.UserID = s.id(), CurrentUser.ID
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now().UTC()
+	}
+	s.notifications = append(s.notifications, &n)
+	return n
+}
+
+// Notification returns the notification with id
+func (s *Server) Notification(id int) (Notification, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, n := range s.notifications {
+		if n.ID == id {
+			return *n, true
+		}
+	}
+	return Notification{}, false
+}
+
+// handleNotifications serves notifications, newest first, notifications/:id and notifications/mark_all_read
+func (s *Server) handleNotifications(w http.ResponseWriter, r *http.Request, segments []string, raw []byte) {
+	switch {
+	case len(segments) == 1 && r.Method == http.MethodGet:
+		unreadOnly := r.URL.Query().Get("where[read]") == "false"
+		var notifications []Notification
+		for _, n := range s.notifications {
+			if !unreadOnly || !n.Read {
+				notifications = append(notifications, *n)
+			}
+		}
+		sort.SliceStable(notifications, func(i, j int) bool {
+			return notifications[i].CreatedAt.After(notifications[j].CreatedAt)
+		})
+		writeList(w, r, notifications)
+	case len(segments) == 2 && segments[1] == "mark_all_read" && r.Method == http.MethodPut:
+		for _, n := range s.notifications {
+			n.Read = true
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case len(segments) == 2 && r.Method == http.MethodPut:
+		id, _ := strconv.Atoi(segments[1])
+		for _, n := range s.notifications {
+			if n.ID == id {
+				var update struct {
+					Read bool `json:"read"`
+				}
+				json.Unmarshal(raw, &update)
+				n.Read = update.Read
+				writeJSON(w, r, n)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "notification not found")
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
@@ -0,0 +1,489 @@
+// Package zubetest provides an in-memory fake of the Zube api, serving projects, workspaces,
+// notification preferences, user settings, labels, cards and notifications so clients can be exercised without zube.io.
+package zubetest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	EmailPreferences   = "user_email_preferences"
+	InAppPreferences   = "user_in_app_preferences"
+	UserSettings       = "user_settings"
+	TriageUserSettings = "triage_user_settings"
+)
+
+// DefaultPreferences are the notification preferences of the account and new projects and workspaces
+var DefaultPreferences = map[string]interface{}{
+	"email":              "default",
+	"card_assigned":      true,
+	"card_commented":     true,
+	"card_mentioned":     true,
+	"card_moved":         true,
+	"card_closed":        true,
+	"triage_card_opened": true,
+}
+
+// DefaultSubscriptionLevel is the subscription level of new projects and workspaces
+const DefaultSubscriptionLevel = "participating"
+
+type Account struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// CurrentAccount is the account projects are created in, it has default preferences of its own
+var CurrentAccount = Account{ID: 1, Name: "Zube Test", Slug: "zube-test"}
+
+type Project struct {
+	ID        int    `json:"id"`
+	AccountID int    `json:"account_id"`
+	Name      string `json:"name"`
+	Slug      string `json:"slug"`
+	Private   bool   `json:"private"`
+}
+
+type Workspace struct {
+	ID        int    `json:"id"`
+	ProjectID int    `json:"project_id"`
+	Name      string `json:"name"`
+	Slug      string `json:"slug"`
+	Archived  bool   `json:"archived"`
+}
+
+// Request is a write received by the server
+type Request struct {
+	Method string
+	Path   string
+	Body   map[string]interface{}
+}
+
+type objectKey struct {
+	object string
+	id     int
+	method string
+}
+
+type setting struct {
+	id    int
+	level string
+}
+
+// Server is a fake Zube api. Any refresh token is exchanged for an access token,
+// which must be presented on every other request.
+type Server struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	nextID     int
+	tokens     map[string]bool
+	projects   []Project
+	workspaces []Workspace
+	prefs      map[objectKey]map[string]interface{}
+	settings   map[objectKey]*setting
+	labels     []Label
+	cards      []*Card
+	comments   []Comment
+	muted      map[int]bool
+	// notifications is the current user's in-app feed
+	notifications []*Notification
+	writes        []Request
+}
+
+// NewServer starts a fake api server, callers should Close it when done
+func NewServer() *Server {
+	s := &Server{
+		nextID:   1,
+		tokens:   make(map[string]bool),
+		prefs:    make(map[objectKey]map[string]interface{}),
+		settings: make(map[objectKey]*setting),
+		muted:    make(map[int]bool),
+	}
+	s.add("accounts", CurrentAccount.ID, false)
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// APIURL is the api base url to configure clients with
+func (s *Server) APIURL() string {
+	return s.URL + "/api/"
+}
+
+func (s *Server) id() int {
+	id := s.nextID
+	s.nextID++
+	return id
+}
+
+// add creates default preferences and settings for a new object
+func (s *Server) add(object string, id int, triage bool) {
+	for _, prefType := range []string{EmailPreferences, InAppPreferences} {
+		p := defaults(s.id())
+		s.touch(p)
+		s.prefs[objectKey{object, id, prefType}] = p
+	}
+	s.settings[objectKey{object, id, UserSettings}] = &setting{id: s.id(), level: DefaultSubscriptionLevel}
+	if triage {
+		s.settings[objectKey{object, id, TriageUserSettings}] = &setting{id: s.id(), level: DefaultSubscriptionLevel}
+	}
+}
+
+// epoch is when revisions start, each change to preferences moves updated_at a second later
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// touch sets a new updated_at revision on p
+func (s *Server) touch(p map[string]interface{}) {
+	p["updated_at"] = epoch.Add(time.Duration(s.id()) * time.Second).Format(time.RFC3339)
+}
+
+func defaults(id int) map[string]interface{} {
+	p := make(map[string]interface{}, len(DefaultPreferences)+1)
+	for k, v := range DefaultPreferences {
+		p[k] = v
+	}
+	p["id"] = id
+	return p
+}
+
+// AddProject creates a project with default preferences
+func (s *Server) AddProject(name string) Project {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := Project{ID: s.id(), AccountID: CurrentAccount.ID, Name: name, Slug: slug(name)}
+	s.projects = append(s.projects, p)
+	s.add("projects", p.ID, true)
+	return p
+}
+
+// AddWorkspace creates a workspace in project with default preferences
+func (s *Server) AddWorkspace(projectId int, name string) Workspace {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w := Workspace{ID: s.id(), ProjectID: projectId, Name: name, Slug: slug(name)}
+	s.workspaces = append(s.workspaces, w)
+	s.add("workspaces", w.ID, false)
+	return w
+}
+
+func slug(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, " ", "-"))
+}
+
+// MoveProject places the project in another account, and makes it private or public
+func (s *Server) MoveProject(id, accountId int, private bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.projects {
+		if s.projects[i].ID == id {
+			s.projects[i].AccountID, s.projects[i].Private = accountId, private
+		}
+	}
+}
+
+// ArchiveWorkspace marks the workspace archived
+func (s *Server) ArchiveWorkspace(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.workspaces {
+		if s.workspaces[i].ID == id {
+			s.workspaces[i].Archived = true
+		}
+	}
+}
+
+// Preferences returns a copy of the prefType preferences of object ("projects" or "workspaces") id
+func (s *Server) Preferences(object string, id int, prefType string) map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.prefs[objectKey{object, id, prefType}]
+	if !ok {
+		return nil
+	}
+	c := make(map[string]interface{}, len(p))
+	for k, v := range p {
+		c[k] = v
+	}
+	return c
+}
+
+// SetPreference changes a single preference key
+func (s *Server) SetPreference(object string, id int, prefType, key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.prefs[objectKey{object, id, prefType}]; ok {
+		p[key] = value
+		s.touch(p)
+	}
+}
+
+// SubscriptionLevel returns the user_settings or triage_user_settings level of object id
+func (s *Server) SubscriptionLevel(object string, id int, method string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st, ok := s.settings[objectKey{object, id, method}]; ok {
+		return st.level
+	}
+	return ""
+}
+
+// Writes returns the PUT, POST and DELETE requests received so far, excluding token exchanges
+func (s *Server) Writes() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.writes...)
+}
+
+// RevokeTokens invalidates every access token issued so far
+func (s *Server) RevokeTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = make(map[string]bool)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/"), "/")
+	auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if auth == "" {
+		writeError(w, http.StatusUnauthorized, "missing authorization")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if path == "users/tokens" && r.Method == http.MethodPost {
+		token := fmt.Sprintf("access-%d", s.id())
+		s.tokens[token] = true
+		writeJSON(w, r, map[string]string{"access_token": token})
+		return
+	}
+	if !s.tokens[auth] {
+		writeError(w, http.StatusUnauthorized, "invalid access token")
+		return
+	}
+
+	var (
+		body map[string]interface{}
+		raw  []byte
+	)
+	if r.Method != http.MethodGet {
+		if r.Body != nil {
+			raw, _ = io.ReadAll(r.Body)
+			json.Unmarshal(raw, &body)
+		}
+		s.writes = append(s.writes, Request{Method: r.Method, Path: path, Body: body})
+	}
+
+	segments := strings.Split(path, "/")
+	switch {
+	case path == "projects" && r.Method == http.MethodGet:
+		writeList(w, r, selectFields(r, filterProjects(r, s.projects)))
+	case path == "workspaces" && r.Method == http.MethodGet:
+		writeList(w, r, s.workspaces)
+	case len(segments) == 2 && segments[0] == "projects" && r.Method == http.MethodGet:
+		for _, p := range s.projects {
+			if strconv.Itoa(p.ID) == segments[1] {
+				writeJSON(w, r, p)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "project not found")
+	case len(segments) == 2 && segments[0] == "workspaces" && r.Method == http.MethodGet:
+		for _, ws := range s.workspaces {
+			if strconv.Itoa(ws.ID) == segments[1] {
+				writeJSON(w, r, ws)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "workspace not found")
+	case len(segments) == 3 && segments[0] == "projects" && segments[2] == "workspaces" && r.Method == http.MethodGet:
+		var ws []Workspace
+		for _, w := range s.workspaces {
+			if strconv.Itoa(w.ProjectID) == segments[1] {
+				ws = append(ws, w)
+			}
+		}
+		writeList(w, r, ws)
+	case len(segments) == 3 && segments[0] == "projects" && segments[2] == "sources" && r.Method == http.MethodGet:
+		writeList(w, r, []struct{}{})
+	case len(segments) == 3 && segments[0] == "projects" && segments[2] == "labels" && r.Method == http.MethodGet:
+		var labels []Label
+		for _, l := range s.labels {
+			if strconv.Itoa(l.ProjectID) == segments[1] {
+				labels = append(labels, l)
+			}
+		}
+		writeList(w, r, labels)
+	case path == "users/me" && r.Method == http.MethodGet:
+		writeJSON(w, r, CurrentUser)
+	case path == "accounts" && r.Method == http.MethodGet:
+		writeList(w, r, []interface{}{CurrentAccount})
+	case len(segments) == 2 && segments[0] == "accounts" && segments[1] == strconv.Itoa(CurrentAccount.ID) && r.Method == http.MethodGet:
+		writeJSON(w, r, CurrentAccount)
+	case len(segments) == 3 && segments[0] == "accounts" && segments[2] == "members" && r.Method == http.MethodGet:
+		writeList(w, r, []map[string]interface{}{{"user_id": CurrentUser.ID, "user": CurrentUser}})
+	case segments[0] == "cards":
+		s.handleCards(w, r, segments, raw)
+	case segments[0] == "notifications":
+		s.handleNotifications(w, r, segments, raw)
+	case len(segments) >= 3 && (segments[0] == "accounts" || segments[0] == "projects" || segments[0] == "workspaces"):
+		s.handleObject(w, r, segments, body)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// handleObject serves preferences and user settings of an account, project or workspace
+func (s *Server) handleObject(w http.ResponseWriter, r *http.Request, segments []string, body map[string]interface{}) {
+	id, err := strconv.Atoi(segments[1])
+	if err != nil {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	key := objectKey{segments[0], id, segments[2]}
+	if p, ok := s.prefs[key]; ok {
+		switch {
+		case len(segments) == 3 && r.Method == http.MethodGet:
+			writeJSON(w, r, map[string]interface{}{"data": []interface{}{p}})
+		case len(segments) == 4 && r.Method == http.MethodPut && segments[3] == fmt.Sprint(p["id"]):
+			// writes carrying a stale revision are refused like zube's optimistic locking
+			if v, ok := body["updated_at"]; ok && v != p["updated_at"] {
+				writeError(w, http.StatusConflict, "preferences changed since read")
+				return
+			}
+			for k, v := range body {
+				if k != "id" {
+					p[k] = v
+				}
+			}
+			s.touch(p)
+			writeJSON(w, r, p)
+		case len(segments) == 4 && r.Method == http.MethodDelete && segments[3] == fmt.Sprint(p["id"]):
+			s.prefs[key] = defaults(s.id())
+			s.touch(s.prefs[key])
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeError(w, http.StatusNotFound, "not found")
+		}
+		return
+	}
+	if st, ok := s.settings[key]; ok {
+		switch {
+		case len(segments) == 3 && r.Method == http.MethodGet:
+			writeJSON(w, r, map[string]interface{}{"data": []interface{}{map[string]interface{}{
+				"id":                 st.id,
+				"user_id":            CurrentUser.ID,
+				"subscription_level": st.level,
+			}}})
+		case len(segments) == 4 && r.Method == http.MethodPut && segments[3] == strconv.Itoa(st.id):
+			if level, ok := body["subscription_level"].(string); ok {
+				st.level = level
+			}
+			writeJSON(w, r, map[string]interface{}{"id": st.id, "subscription_level": st.level})
+		default:
+			writeError(w, http.StatusNotFound, "not found")
+		}
+		return
+	}
+	writeError(w, http.StatusNotFound, "not found")
+}
+
+// filterProjects applies the where[account_id] and where[private] filters of the request
+func filterProjects(r *http.Request, projects []Project) []Project {
+	q := r.URL.Query()
+	var filtered []Project
+	for _, p := range projects {
+		if v := q.Get("where[account_id]"); v != "" && v != strconv.Itoa(p.AccountID) {
+			continue
+		}
+		if v := q.Get("where[private]"); v != "" && v != strconv.FormatBool(p.Private) {
+			continue
+		}
+		filtered = append(filtered, p)
+	}
+	return filtered
+}
+
+// selectFields reduces items to the fields requested with select[], all of them when none are
+func selectFields[T any](r *http.Request, items []T) []map[string]interface{} {
+	fields := r.URL.Query()["select[]"]
+	selected := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		b, _ := json.Marshal(item)
+		var all map[string]interface{}
+		json.Unmarshal(b, &all)
+		if len(fields) == 0 {
+			selected[i] = all
+			continue
+		}
+		selected[i] = make(map[string]interface{}, len(fields))
+		for _, f := range fields {
+			if v, ok := all[f]; ok {
+				selected[i][f] = v
+			}
+		}
+	}
+	return selected
+}
+
+// writeList writes the requested page of items in zube's list envelope
+func writeList[T any](w http.ResponseWriter, r *http.Request, items []T) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage < 1 {
+		perPage = 30
+	}
+	start, end := (page-1)*perPage, page*perPage
+	if start > len(items) {
+		start = len(items)
+	}
+	if end > len(items) {
+		end = len(items)
+	}
+	writeJSON(w, r, map[string]interface{}{
+		"pagination": map[string]int{
+			"page":        page,
+			"per_page":    perPage,
+			"total":       len(items),
+			"total_pages": (len(items) + perPage - 1) / perPage,
+		},
+		"data": items[start:end],
+	})
+}
+
+// writeJSON writes v with an etag of its content, answering matching If-None-Match with 304
+func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sum := sha256.Sum256(b)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	w.Header().Set("ETag", etag)
+	if r.Method == http.MethodGet && r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}